@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	_ "time/tzdata" // schedules resolve IANA zones, the lambda image has no zoneinfo
 
 	"github.com/Fleexa-Graduation-Project/Backend/internal/api/handlers"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
@@ -11,8 +12,17 @@ import (
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/notify"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/rooms"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/schedules"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/stream"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/weather"
 	
 	"github.com/aws/aws-sdk-go-v2/config"
 
@@ -57,7 +67,81 @@ if err != nil {
 		log.Error("Failed to initialize CommandStore", "error", err)
 		panic(err)
 	}
+	scheduleStore, err := schedules.NewScheduleStore()
+	if err != nil {
+		log.Error("Failed to initialize ScheduleStore", "error", err)
+		panic(err)
+	}
+	ruleStore, err := automation.NewRuleStore()
+	if err != nil {
+		log.Error("Failed to initialize RuleStore", "error", err)
+		panic(err)
+	}
+	thresholdStore, err := alerts.NewThresholdStore()
+	if err != nil {
+		log.Error("Failed to initialize ThresholdStore", "error", err)
+		panic(err)
+	}
+	tokenStore, err := notify.NewTokenStore()
+	if err != nil {
+		log.Error("Failed to initialize TokenStore", "error", err)
+		panic(err)
+	}
+	preferenceStore, err := notify.NewPreferenceStore()
+	if err != nil {
+		log.Error("Failed to initialize PreferenceStore", "error", err)
+		panic(err)
+	}
+	homeStore, err := homes.NewHomeStore()
+	if err != nil {
+		log.Error("Failed to initialize HomeStore", "error", err)
+		panic(err)
+	}
+	quarantineStore, err := devices.NewQuarantineStore()
+	if err != nil {
+		log.Error("Failed to initialize QuarantineStore", "error", err)
+		panic(err)
+	}
+	roomStore, err := rooms.NewRoomStore()
+	if err != nil {
+		log.Error("Failed to initialize RoomStore", "error", err)
+		panic(err)
+	}
+	auditStore, err := audit.NewAuditStore()
+	if err != nil {
+		log.Error("Failed to initialize AuditStore", "error", err)
+		panic(err)
+	}
+	weatherProvider, err := weather.NewProviderFromEnv()
+	if err != nil {
+		log.Error("Failed to initialize weather provider", "error", err)
+		panic(err)
+	}
+	chartReader, err := iot.NewS3Client(cfg)
+	if err != nil {
+		log.Error("Failed to initialize chart reader", "error", err)
+		panic(err)
+	}
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Error("Failed to initialize token verifier", "error", err)
+		panic(err)
+	}
+
+	// alerts acked and commands sent from here, plus the events relayed by the lambdas, reach /stream
+	hub := stream.NewHub()
+	streamedAlerts := &stream.PublishingAlertStore{AlertStore: alertStore, Logger: log, Publisher: hub}
+	streamedCommands := &stream.PublishingCommandStore{CommandStore: commandStore, Logger: log, Publisher: hub}
+
+	// who did what, kept apart from the 30 day command history
+	recorder := audit.NewRecorder(auditStore, homeStore)
+
 	iotPublisher := iot.NewPublisher(cfg)
+	dispatcher := &commands.Dispatcher{
+		Publisher: iotPublisher,
+		Store:     streamedCommands,
+		Audit:     recorder,
+	}
 
 //initializing the device holder
 	deviceHandler := &handlers.DeviceHandler{
@@ -65,24 +149,152 @@ if err != nil {
 		TelemetryStore: telemetryStore,
 		AlertStore:     alertStore,
 		CommandStore:   commandStore,
-		IoTPublisher:   iotPublisher,
+		Dispatcher:     dispatcher,
+		S3Fetcher:      chartReader,
+		Homes:          homeStore,
+		Audit:          recorder,
+		Weather:        weatherProvider,
+	}
+	registryHandler := &handlers.RegistryHandler{
+		Registry:       stateStore,
+		StateStore:     stateStore,
+		TelemetryStore: telemetryStore,
+		AlertStore:     alertStore,
+		CommandStore:   commandStore,
+		Schedules:      scheduleStore,
+		Rules:          ruleStore,
+		Thresholds:     thresholdStore,
+		Quarantine:     quarantineStore,
+		Homes:          homeStore,
+		Rooms:          roomStore,
+		Audit:          recorder,
+	}
+	roomHandler := &handlers.RoomHandler{
+		Rooms:          roomStore,
+		Registry:       stateStore,
+		StateStore:     stateStore,
+		TelemetryStore: telemetryStore,
+		Homes:          homeStore,
+		Dispatcher:     dispatcher,
+		Audit:          recorder,
+	}
+	scheduleHandler := &handlers.ScheduleHandler{
+		StateStore: stateStore,
+		Schedules:  scheduleStore,
+		Homes:      homeStore,
+		Audit:      recorder,
+	}
+	alertHandler := &handlers.AlertHandler{
+		AlertStore: streamedAlerts,
+		Homes:      homeStore,
+		Audit:      recorder,
+	}
+	thresholdHandler := &handlers.ThresholdHandler{
+		StateStore: stateStore,
+		Thresholds: thresholdStore,
+		Homes:      homeStore,
+		Audit:      recorder,
+	}
+	automationHandler := &handlers.AutomationHandler{
+		StateStore: stateStore,
+		Rules:      ruleStore,
+		Homes:      homeStore,
+		Audit:      recorder,
+	}
+	notificationHandler := &handlers.NotificationHandler{
+		Tokens:      tokenStore,
+		Preferences: preferenceStore,
+	}
+	homeHandler := &handlers.HomeHandler{
+		StateStore: stateStore,
+		Homes:      homeStore,
+		Audit:      recorder,
+	}
+	auditHandler := &handlers.AuditHandler{
+		Events: auditStore,
+		Homes:  homeStore,
+	}
+	streamHandler := &handlers.StreamHandler{
+		Hub:         hub,
+		Homes:       homeStore,
+		RelaySecret: os.Getenv("STREAM_RELAY_SECRET"),
 	}
 
 	router := gin.Default()
+	router.Use(audit.RequestID())
 
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 
-	//grouping routes
-	v1 := router.Group("/api/v1")
+	// called by the lambdas only, every event is HMAC signed with STREAM_RELAY_SECRET
+	router.POST("/internal/events", streamHandler.ReceiveRelay)
+
+	//grouping routes, every one of them needs a bearer token
+	v1 := router.Group("/api/v1", auth.Middleware(verifier), audit.Logins(recorder))
 	{
 		v1.GET("/devices", deviceHandler.GetDevices)
 		v1.GET("/devices/:id", deviceHandler.GetDeviceByID)
+		v1.POST("/devices", registryHandler.RegisterDevice)
+		v1.PATCH("/devices/:id", registryHandler.UpdateDevice)
+		v1.DELETE("/devices/:id", registryHandler.DecommissionDevice)
 		v1.GET("/devices/:id/telemetry", deviceHandler.GetDeviceTelemetry)
+		v1.GET("/devices/:id/telemetry/raw", deviceHandler.GetDeviceTelemetryRaw)
+		v1.GET("/devices/:id/telemetry/aggregate", deviceHandler.GetDeviceTelemetryAggregate)
 		v1.GET("/devices/:id/alerts", deviceHandler.GetDeviceAlerts)
 		v1.GET("/system/overview", deviceHandler.GetSystemOverview)
 		v1.POST("/devices/:id/commands", deviceHandler.SendCommand)
+		v1.GET("/devices/:id/commands", deviceHandler.GetDeviceCommands)
+		v1.GET("/commands/:request_id", deviceHandler.GetCommand)
+
+		v1.GET("/devices/:id/schedules", scheduleHandler.ListSchedules)
+		v1.POST("/devices/:id/schedules", scheduleHandler.CreateSchedule)
+		v1.GET("/devices/:id/schedules/:schedule_id", scheduleHandler.GetSchedule)
+		v1.PUT("/devices/:id/schedules/:schedule_id", scheduleHandler.UpdateSchedule)
+		v1.DELETE("/devices/:id/schedules/:schedule_id", scheduleHandler.DeleteSchedule)
+
+		v1.GET("/alerts", alertHandler.ListAlerts)
+		v1.POST("/alerts/:device_id/:timestamp/ack", alertHandler.AcknowledgeAlert)
+		v1.POST("/alerts/:device_id/:timestamp/resolve", alertHandler.ResolveAlert)
+
+		v1.GET("/devices/:id/thresholds", thresholdHandler.ListThresholds)
+		v1.POST("/devices/:id/thresholds", thresholdHandler.CreateThreshold)
+		v1.PUT("/devices/:id/thresholds/:threshold_id", thresholdHandler.UpdateThreshold)
+		v1.DELETE("/devices/:id/thresholds/:threshold_id", thresholdHandler.DeleteThreshold)
+
+		v1.GET("/automations", automationHandler.ListRules)
+		v1.POST("/automations", automationHandler.CreateRule)
+		v1.GET("/automations/:rule_id", automationHandler.GetRule)
+		v1.PUT("/automations/:rule_id", automationHandler.UpdateRule)
+		v1.DELETE("/automations/:rule_id", automationHandler.DeleteRule)
+		v1.GET("/automations/:rule_id/history", automationHandler.GetRuleHistory)
+
+		v1.GET("/users/:user_id/push-tokens", notificationHandler.ListPushTokens)
+		v1.POST("/users/:user_id/push-tokens", notificationHandler.RegisterPushToken)
+		v1.DELETE("/users/:user_id/push-tokens/:token", notificationHandler.DeletePushToken)
+		v1.GET("/users/:user_id/notification-preferences", notificationHandler.GetPreferences)
+		v1.PUT("/users/:user_id/notification-preferences", notificationHandler.UpdatePreferences)
+
+		v1.GET("/stream", streamHandler.Stream)
+
+		v1.GET("/homes", homeHandler.ListHomes)
+		v1.POST("/homes", homeHandler.CreateHome)
+		v1.GET("/homes/:home_id", homeHandler.GetHome)
+		v1.PUT("/homes/:home_id", homeHandler.UpdateHome)
+		v1.POST("/homes/:home_id/devices", homeHandler.AddDevice)
+		v1.DELETE("/homes/:home_id/devices/:device_id", homeHandler.RemoveDevice)
+		v1.POST("/homes/:home_id/members", homeHandler.AddMember)
+		v1.PUT("/homes/:home_id/members/:user_id", homeHandler.UpdateMember)
+		v1.DELETE("/homes/:home_id/members/:user_id", homeHandler.RemoveMember)
+
+		v1.GET("/rooms", roomHandler.ListRooms)
+		v1.POST("/rooms", roomHandler.CreateRoom)
+		v1.GET("/rooms/:room_id", roomHandler.GetRoom)
+		v1.PUT("/rooms/:room_id", roomHandler.UpdateRoom)
+		v1.DELETE("/rooms/:room_id", roomHandler.DeleteRoom)
+		v1.POST("/rooms/:room_id/commands", roomHandler.SendRoomCommand)
+
+		v1.GET("/audit", auditHandler.ListEvents)
 	}
 	
 
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	_ "time/tzdata" // days are cut in each home's zone, the lambda image has no zoneinfo
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/rollup"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
+)
+
+var (
+	log *slog.Logger
+	job *rollup.Job
+)
+
+func init() {
+
+	log = logger.InitLogger()
+	log.Info("chart rollup -> cold Start...")
+
+	if err := db.NewDynamoDBClient(context.Background()); err != nil {
+		log.Error("failed to initialize DynamoDB", "error", err)
+		panic(err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(fmt.Errorf("failed to load aws config for s3: %w", err))
+	}
+
+	stateStore, err := devices.NewStateStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init device state store: %w", err))
+	}
+
+	telemetryStore, err := telemetry.NewTelemetryStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init telemetry store: %w", err))
+	}
+
+	homeStore, err := homes.NewHomeStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init home store: %w", err))
+	}
+
+	charts, err := iot.NewS3Client(cfg)
+	if err != nil {
+		panic(fmt.Errorf("failed to init chart bucket: %w", err))
+	}
+
+	job = &rollup.Job{
+		Logger:     log,
+		StateStore: stateStore,
+		Telemetry:  telemetryStore,
+		Charts:     charts,
+		Homes:      homeStore,
+	}
+
+	log.Info("chart rollup -> Cold Start Completed. Stores Ready.")
+}
+
+// invoked once a day, shortly after midnight UTC, by an EventBridge rule. days end at the
+// midnight of each home's zone, so west of UTC a day waits for the next run. a missed day
+// is caught up by the next run as long as its readings haven't expired
+func handleTick(ctx context.Context) error {
+	return job.Run(ctx, time.Now())
+}
+
+func main() {
+	lambda.Start(handleTick)
+}
@@ -4,21 +4,35 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	_ "time/tzdata" // quiet hours and home zones are IANA names, the lambda image has no zoneinfo
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/ingestion"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/notify"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/stream"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/weather"
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
 )
 
 var (
 	log            *slog.Logger
-	telemetryStore *telemetry.TelemetryStore
-	alertStore     *alerts.AlertStore
-	stateStore     *devices.StateStore
+	telemetryStore telemetry.TelemetryStore
+	alertStore     alerts.AlertStore
+	stateStore     devices.StateStore
+	commandStore   commands.CommandStore
+	quarantine     devices.QuarantineStore
+	automations    *automation.Engine
+	thresholds     *alerts.Monitor
 )
 
 func init() {
@@ -38,16 +52,94 @@ func init() {
 		panic(fmt.Errorf("failed to init telemetry store: %w", err))
 	}
 
-	alertStore, err = alerts.NewAlertStore()
+	dynamoAlertStore, err := alerts.NewAlertStore()
 	if err != nil {
 		panic(fmt.Errorf("failed to init alert store: %w", err))
 	}
 
+	notifier, err := notify.NewNotifier(log)
+	if err != nil {
+		panic(fmt.Errorf("failed to init notifier: %w", err))
+	}
+
+	// every alert saved from here on (device alerts, thresholds) is pushed to the users
+	alertStore = &notify.NotifyingAlertStore{AlertStore: dynamoAlertStore, Notifier: notifier}
+
 	stateStore, err = devices.NewStateStore()
 	if err != nil {
 		panic(fmt.Errorf("failed to init device state store: %w", err))
 	}
 
+	quarantine, err = devices.NewQuarantineStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init quarantine store: %w", err))
+	}
+
+	commandStore, err = commands.NewCommandStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init command store: %w", err))
+	}
+
+	auditStore, err := audit.NewAuditStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init audit store: %w", err))
+	}
+
+	homeStore, err := homes.NewHomeStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init home store: %w", err))
+	}
+
+	relay, err := stream.NewRelayFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to init stream relay: %w", err))
+	}
+	if relay != nil {
+		// state changes, alerts and command acks are forwarded to the api's /stream subscribers
+		stateStore = &stream.PublishingStateStore{StateStore: stateStore, Logger: log, Publisher: relay}
+		alertStore = &stream.PublishingAlertStore{AlertStore: alertStore, Logger: log, Publisher: relay}
+		commandStore = &stream.PublishingCommandStore{CommandStore: commandStore, Logger: log, Publisher: relay}
+	}
+
+	thresholdStore, err := alerts.NewThresholdStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init threshold store: %w", err))
+	}
+
+	thresholds = &alerts.Monitor{
+		Logger:     log,
+		Thresholds: thresholdStore,
+		Alerts:     alertStore,
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(fmt.Errorf("failed to load aws config for iot: %w", err))
+	}
+
+	ruleStore, err := automation.NewRuleStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init automation rule store: %w", err))
+	}
+
+	weatherProvider, err := weather.NewProviderFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to init weather provider: %w", err))
+	}
+
+	automations = &automation.Engine{
+		Logger:     log,
+		Rules:      ruleStore,
+		StateStore: stateStore,
+		Homes:      homeStore,
+		Weather:    weatherProvider,
+		Dispatcher: &commands.Dispatcher{
+			Publisher: iot.NewPublisher(cfg),
+			Store:     commandStore,
+			Audit:     audit.NewRecorder(auditStore, homeStore),
+		},
+	}
+
 	log.Info("iot ingestion -> Cold Start Completed. Stores Ready.")
 
 }
@@ -59,6 +151,10 @@ func main() {
 		TelemetryStore: telemetryStore,
 		AlertStore:     alertStore,
 		StateStore:     stateStore,
+		CommandStore:   commandStore,
+		Quarantine:     quarantine,
+		Automations:    automations,
+		Thresholds:     thresholds,
 	}
 
 	lambda.Start(service.HandleRequest)
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	_ "time/tzdata" // quiet hours are read in the user's IANA zone, the lambda image has no zoneinfo
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/notify"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/presence"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/stream"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
+)
+
+var (
+	log     *slog.Logger
+	sweeper *presence.Sweeper
+)
+
+func init() {
+
+	log = logger.InitLogger()
+	log.Info("offline sweeper -> cold Start...")
+
+	if err := db.NewDynamoDBClient(context.Background()); err != nil {
+		log.Error("failed to initialize DynamoDB", "error", err)
+		panic(err)
+	}
+
+	stateStore, err := devices.NewStateStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init device state store: %w", err))
+	}
+
+	alertStore, err := alerts.NewAlertStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init alert store: %w", err))
+	}
+
+	thresholdStore, err := alerts.NewThresholdStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init threshold store: %w", err))
+	}
+
+	notifier, err := notify.NewNotifier(log)
+	if err != nil {
+		panic(fmt.Errorf("failed to init notifier: %w", err))
+	}
+
+	var sweptStates devices.StateStore = stateStore
+	var sweptAlerts alerts.AlertStore = &notify.NotifyingAlertStore{AlertStore: alertStore, Notifier: notifier}
+
+	relay, err := stream.NewRelayFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to init stream relay: %w", err))
+	}
+	if relay != nil {
+		sweptStates = &stream.PublishingStateStore{StateStore: sweptStates, Logger: log, Publisher: relay}
+		sweptAlerts = &stream.PublishingAlertStore{AlertStore: sweptAlerts, Logger: log, Publisher: relay}
+	}
+
+	sweeper = &presence.Sweeper{
+		Logger:     log,
+		StateStore: sweptStates,
+		AlertStore: sweptAlerts,
+		Thresholds: &alerts.Monitor{
+			Logger:     log,
+			Thresholds: thresholdStore,
+			Alerts:     sweptAlerts,
+		},
+	}
+
+	log.Info("offline sweeper -> Cold Start Completed. Stores Ready.")
+}
+
+// invoked every minute by an EventBridge rule
+func handleTick(ctx context.Context) error {
+	return sweeper.Sweep(ctx, time.Now())
+}
+
+func main() {
+	lambda.Start(handleTick)
+}
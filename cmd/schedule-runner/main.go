@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	_ "time/tzdata" // schedules resolve IANA zones, the lambda image has no zoneinfo
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/schedules"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/stream"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
+)
+
+var (
+	log    *slog.Logger
+	runner *schedules.Runner
+)
+
+func init() {
+
+	log = logger.InitLogger()
+	log.Info("schedule runner -> cold Start...")
+
+	if err := db.NewDynamoDBClient(context.Background()); err != nil {
+		log.Error("failed to initialize DynamoDB", "error", err)
+		panic(err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(fmt.Errorf("failed to load aws config for iot: %w", err))
+	}
+
+	scheduleStore, err := schedules.NewScheduleStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init schedule store: %w", err))
+	}
+
+	stateStore, err := devices.NewStateStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init device state store: %w", err))
+	}
+
+	var commandStore commands.CommandStore
+	commandStore, err = commands.NewCommandStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init command store: %w", err))
+	}
+
+	auditStore, err := audit.NewAuditStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init audit store: %w", err))
+	}
+
+	homeStore, err := homes.NewHomeStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to init home store: %w", err))
+	}
+
+	relay, err := stream.NewRelayFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to init stream relay: %w", err))
+	}
+	if relay != nil {
+		commandStore = &stream.PublishingCommandStore{CommandStore: commandStore, Logger: log, Publisher: relay}
+	}
+
+	runner = &schedules.Runner{
+		Logger:     log,
+		Schedules:  scheduleStore,
+		StateStore: stateStore,
+		Homes:      homeStore,
+		Dispatcher: &commands.Dispatcher{
+			Publisher: iot.NewPublisher(cfg),
+			Store:     commandStore,
+			Audit:     audit.NewRecorder(auditStore, homeStore),
+		},
+	}
+
+	log.Info("schedule runner -> Cold Start Completed. Stores Ready.")
+}
+
+// invoked every minute by an EventBridge rule
+func handleTick(ctx context.Context) error {
+	return runner.RunDue(ctx, time.Now())
+}
+
+func main() {
+	lambda.Start(handleTick)
+}
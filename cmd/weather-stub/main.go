@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/weather"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/logger"
+)
+
+// local stand-in for the weather api, point WEATHER_API_URL at it (http://localhost:8090).
+// WEATHER_STUB_TEMP and WEATHER_STUB_HUMIDITY set the weather it reports
+func main() {
+	log := logger.InitLogger()
+
+	temperature := envFloat("WEATHER_STUB_TEMP", 36)
+	humidity := envFloat("WEATHER_STUB_HUMIDITY", 40)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8090"
+	}
+
+	log.Info("weather stub listening", "port", port, "temperature", temperature, "humidity", humidity)
+	if err := http.ListenAndServe(":"+port, weather.StubHandler(temperature, humidity)); err != nil {
+		log.Error("weather stub stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func envFloat(name string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	StatusOpen         = "OPEN"         // raised, nobody looked at it yet
+	StatusAcknowledged = "ACKNOWLEDGED" // someone is handling it
+	StatusResolved     = "RESOLVED"     // handled, or the device recovered
+
+	ResolvedByAuto = "auto"
+
+	// alerts of one device raised in the same second move to the next free second, at most this far
+	maxRaiseAttempts = 10
+)
+
+var (
+	ErrAlertNotFound          = errors.New("alert not found")
+	ErrInvalidAlertTransition = errors.New("invalid alert status transition")
+)
+
+// alerts saved before statuses existed have none and count as open
+func StatusOf(alert models.Alert) string {
+	if alert.Status == "" {
+		return StatusOpen
+	}
+	return alert.Status
+}
+
+func IsAlertStatus(status string) bool {
+	switch status {
+	case StatusOpen, StatusAcknowledged, StatusResolved:
+		return true
+	default:
+		return false
+	}
+}
+
+// threshold alerts are resolved by their own threshold once the metric is back within limits
+func IsThresholdAlert(alert models.Alert) bool {
+	status, _ := alert.Payload["status"].(string)
+	return status == StatusThresholdBreached
+}
+
+// alerts published by the device itself carry their severity in the payload (validated on ingestion),
+// the ones raised by thresholds or the offline sweeper don't
+func RaisedByDevice(alert models.Alert) bool {
+	_, ok := alert.Payload["severity"]
+	return ok
+}
+
+// RaiseAlert saves a new alert. an alert of the device already saved at the same second (a threshold
+// and a device alert, the sweeper...) moves it to the next free second, the saved alert is returned.
+// ErrDuplicateAlert when the same alert is already there, a redelivered message or a retried run
+func RaiseAlert(ctx context.Context, store AlertStore, alert models.Alert) (models.Alert, error) {
+	for range maxRaiseAttempts {
+		err := store.SaveAlert(ctx, alert)
+		var duplicate *DuplicateAlertError
+		if !errors.As(err, &duplicate) {
+			return alert, err
+		}
+		if SameAlert(duplicate.Existing, alert) {
+			return duplicate.Existing, err
+		}
+		alert.Timestamp++
+	}
+	return alert, fmt.Errorf("%w: no free second for %s after %d attempts", ErrDuplicateAlert, alert.DeviceID, maxRaiseAttempts)
+}
+
+// alerts are the same when raised by the same source for the same reason: the same threshold,
+// the same sweeper status or the same device status
+func SameAlert(a models.Alert, b models.Alert) bool {
+	return a.Type == b.Type &&
+		RaisedByDevice(a) == RaisedByDevice(b) &&
+		a.Payload["status"] == b.Payload["status"] &&
+		a.Payload["threshold_id"] == b.Payload["threshold_id"]
+}
+
+// resolves the unresolved alerts of a device that clears matches, returns how many were resolved
+func ResolveMatching(ctx context.Context, store AlertStore, deviceID string, at int64, clears func(models.Alert) bool) (int, error) {
+	unresolved, err := store.GetAlertsByDeviceStatus(ctx, deviceID, []string{StatusOpen, StatusAcknowledged}, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved := 0
+	for _, alert := range unresolved {
+		if !clears(alert) {
+			continue
+		}
+		_, err := store.ResolveAlert(ctx, deviceID, alert.Timestamp, ResolvedByAuto, at)
+		if errors.Is(err, ErrAlertNotFound) || errors.Is(err, ErrInvalidAlertTransition) {
+			continue // resolved or expired in the meantime
+		}
+		if err != nil {
+			return resolved, err
+		}
+		resolved++
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,138 @@
+package alerts
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// Monitor raises alerts from telemetry when a reading crosses one of the device's thresholds
+type Monitor struct {
+	Logger     *slog.Logger
+	Thresholds ThresholdStore
+	Alerts     AlertStore
+}
+
+// evaluates the readings of one device oldest first, so a batch keeps its breach timing
+func (monitor *Monitor) Check(ctx context.Context, deviceID string, deviceType string, readings []models.Telemetry) error {
+	thresholdList, err := monitor.Thresholds.GetThresholds(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if len(thresholdList) == 0 {
+		return nil
+	}
+
+	readings = slices.Clone(readings)
+	slices.SortFunc(readings, func(a, b models.Telemetry) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+
+	for i := range thresholdList {
+		threshold := &thresholdList[i]
+		if !threshold.Enabled {
+			continue
+		}
+		for _, reading := range readings {
+			if err := monitor.checkReading(ctx, threshold, deviceType, reading); err != nil {
+				// one broken threshold must not block the others
+				monitor.Logger.Error("threshold evaluation failed", "device_id", deviceID, "threshold_id", threshold.ThresholdID, "error", err)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckPending raises the alerts of breaches that lasted ForSeconds with no new reading, a door reporting
+// only on change stays UNLOCKED in its last state. the sweeper calls it every minute with the live state
+func (monitor *Monitor) CheckPending(ctx context.Context, state models.DeviceState, at int64) error {
+	thresholdList, err := monitor.Thresholds.GetThresholds(ctx, state.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	reading := models.Telemetry{
+		DeviceID:  state.DeviceID,
+		Timestamp: at,
+		Type:      state.Type,
+		Payload:   state.Payload,
+	}
+	for i := range thresholdList {
+		threshold := &thresholdList[i]
+		if !threshold.Enabled || threshold.BreachSince == 0 || threshold.LastAlertAt >= threshold.BreachSince {
+			continue // nothing pending: no breach, or it already alerted
+		}
+		if at-threshold.BreachSince < threshold.ForSeconds {
+			continue
+		}
+		if err := monitor.checkReading(ctx, threshold, state.Type, reading); err != nil {
+			monitor.Logger.Error("pending threshold evaluation failed", "device_id", state.DeviceID, "threshold_id", threshold.ThresholdID, "error", err)
+		}
+	}
+	return nil
+}
+
+// updates threshold in place so the next reading of the batch sees the new breach state
+func (monitor *Monitor) checkReading(ctx context.Context, threshold *models.Threshold, deviceType string, reading models.Telemetry) error {
+	decision, ok := EvaluateThreshold(*threshold, reading.Payload, reading.Timestamp)
+	if !ok {
+		return nil
+	}
+
+	if decision.BreachSince != threshold.BreachSince {
+		if err := monitor.Thresholds.SetBreachSince(ctx, threshold.DeviceID, threshold.ThresholdID, decision.BreachSince); err != nil {
+			return err
+		}
+		if decision.BreachSince == 0 {
+			monitor.resolveBreach(ctx, *threshold, reading.Timestamp)
+		}
+		threshold.BreachSince = decision.BreachSince
+	}
+	if !decision.Raise {
+		return nil
+	}
+
+	claimed, err := monitor.Thresholds.ClaimAlert(ctx, threshold.DeviceID, threshold.ThresholdID, threshold.LastAlertAt, reading.Timestamp)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		monitor.Logger.Info("threshold alert already raised by another invocation", "device_id", threshold.DeviceID, "threshold_id", threshold.ThresholdID)
+		return nil
+	}
+	threshold.LastAlertAt = reading.Timestamp
+
+	alert := thresholdAlert(*threshold, deviceType, decision.Observed, reading.Timestamp)
+	if _, err := RaiseAlert(ctx, monitor.Alerts, alert); errors.Is(err, ErrDuplicateAlert) {
+		return nil // already saved by a retried run
+	} else if err != nil {
+		return err
+	}
+
+	monitor.Logger.Info("threshold alert raised", "device_id", threshold.DeviceID, "threshold_id", threshold.ThresholdID, "metric", threshold.Metric, "severity", threshold.Severity)
+	return nil
+}
+
+// the breach is over, the unresolved alerts of the threshold are resolved. they are found by threshold id,
+// an alert may have moved to a later second than LastAlertAt when another alert had its key
+func (monitor *Monitor) resolveBreach(ctx context.Context, threshold models.Threshold, at int64) {
+	if threshold.LastAlertAt == 0 || threshold.LastAlertAt < threshold.BreachSince {
+		return // the breach ended before alerting
+	}
+
+	resolved, err := ResolveMatching(ctx, monitor.Alerts, threshold.DeviceID, at, func(alert models.Alert) bool {
+		return IsThresholdAlert(alert) && alert.Payload["threshold_id"] == threshold.ThresholdID
+	})
+	switch {
+	case err != nil:
+		monitor.Logger.Warn("failed to resolve threshold alert", "device_id", threshold.DeviceID, "threshold_id", threshold.ThresholdID, "error", err)
+	case resolved > 0:
+		monitor.Logger.Info("threshold alert resolved", "device_id", threshold.DeviceID, "threshold_id", threshold.ThresholdID)
+	}
+}
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestRaiseAlertCollision(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAlertStore()
+	device := models.Alert{DeviceID: "gas-1", Timestamp: 1000, Type: "gas-sensor", Payload: map[string]interface{}{"severity": "CRITICAL", "status": "DANGER"}}
+	offline := models.Alert{DeviceID: "gas-1", Timestamp: 1000, Type: "gas-sensor", Payload: map[string]interface{}{"status": "DEVICE_OFFLINE"}}
+
+	if _, err := RaiseAlert(ctx, store, device); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := RaiseAlert(ctx, store, offline)
+	if err != nil || saved.Timestamp != 1001 {
+		t.Fatalf("second alert of the second saved at %d (%v), want 1001", saved.Timestamp, err)
+	}
+
+	// the same alert again is a redelivery, not a new alert
+	if _, err := RaiseAlert(ctx, store, device); !errors.Is(err, ErrDuplicateAlert) {
+		t.Errorf("redelivered alert = %v, want ErrDuplicateAlert", err)
+	}
+	if all, _ := store.GetAlertsByDevice(ctx, "gas-1", 0); len(all) != 2 {
+		t.Errorf("saved %d alerts, want 2", len(all))
+	}
+}
+
+// two thresholds firing on the same reading, next to a device alert of the same second,
+// each get their own alert and each is resolved by its own threshold
+func TestMonitorThresholdsSameSecond(t *testing.T) {
+	ctx := context.Background()
+	alertStore := NewMemoryAlertStore()
+	thresholdStore := NewMemoryThresholdStore()
+	for _, threshold := range []models.Threshold{
+		{DeviceID: "ac-1", ThresholdID: "hot", Metric: "temp", Operator: OperatorAbove, Value: 30, Severity: "MEDIUM", Enabled: true},
+		{DeviceID: "ac-1", ThresholdID: "very-hot", Metric: "temp", Operator: OperatorAbove, Value: 35, Severity: "CRITICAL", Enabled: true},
+	} {
+		if err := thresholdStore.SaveThreshold(ctx, threshold); err != nil {
+			t.Fatal(err)
+		}
+	}
+	monitor := &Monitor{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Thresholds: thresholdStore, Alerts: alertStore}
+
+	deviceAlert := models.Alert{DeviceID: "ac-1", Timestamp: 1000, Type: "ac-actuator", Payload: map[string]interface{}{"severity": "LOW", "status": "FILTER"}}
+	if err := alertStore.SaveAlert(ctx, deviceAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	hot := models.Telemetry{DeviceID: "ac-1", Timestamp: 1000, Type: "ac-actuator", Payload: map[string]interface{}{"temp": 40.0}}
+	if err := monitor.Check(ctx, "ac-1", "ac-actuator", []models.Telemetry{hot}); err != nil {
+		t.Fatal(err)
+	}
+	open, _ := alertStore.GetAlertsByDeviceStatus(ctx, "ac-1", []string{StatusOpen}, 0)
+	if len(open) != 3 {
+		t.Fatalf("got %d open alerts, want the device alert and one per threshold", len(open))
+	}
+
+	// back under 35 only: the very-hot alert is resolved, the others stay open
+	warm := models.Telemetry{DeviceID: "ac-1", Timestamp: 1100, Type: "ac-actuator", Payload: map[string]interface{}{"temp": 32.0}}
+	if err := monitor.Check(ctx, "ac-1", "ac-actuator", []models.Telemetry{warm}); err != nil {
+		t.Fatal(err)
+	}
+	resolved, _ := alertStore.GetAlertsByDeviceStatus(ctx, "ac-1", []string{StatusResolved}, 0)
+	if len(resolved) != 1 || resolved[0].Payload["threshold_id"] != "very-hot" {
+		t.Errorf("resolved %+v, want the very-hot alert only", resolved)
+	}
+}
@@ -3,8 +3,10 @@ package alerts
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,12 +18,47 @@ import (
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
 )
 
-type AlertStore struct {
+const (
+	alertTTL    = 30 * 24 * time.Hour
+	statusIndex = "StatusIndex"
+)
+
+// ErrDuplicateAlert means an alert with the same device and timestamp is already saved, it is never
+// overwritten so a redelivered message can't reopen an acknowledged or resolved alert
+var ErrDuplicateAlert = errors.New("alert already saved")
+
+// DuplicateAlertError is returned by SaveAlert when the key is taken, Existing is the alert saved there
+type DuplicateAlertError struct {
+	Existing models.Alert
+}
+
+func (e *DuplicateAlertError) Error() string {
+	return fmt.Sprintf("%v: %s at %d", ErrDuplicateAlert, e.Existing.DeviceID, e.Existing.Timestamp)
+}
+
+func (e *DuplicateAlertError) Is(target error) bool {
+	return target == ErrDuplicateAlert
+}
+
+// AlertStore keeps the alerts raised by devices (device_id + timestamp)
+type AlertStore interface {
+	SaveAlert(ctx context.Context, alert models.Alert) error
+	GetAlertsBySeverity(ctx context.Context, severity string, limit int32) ([]models.Alert, error)
+	GetAlertsByDevice(ctx context.Context, deviceID string, limit int32) ([]models.Alert, error)
+	GetAlertsByDeviceSince(ctx context.Context, deviceID string, since int64) ([]models.Alert, error)
+	GetAlertsByStatus(ctx context.Context, status string, limit int32) ([]models.Alert, error)
+	GetAlertsByDeviceStatus(ctx context.Context, deviceID string, statuses []string, limit int32) ([]models.Alert, error)
+	AcknowledgeAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error)
+	ResolveAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error)
+	DeleteDeviceAlerts(ctx context.Context, deviceID string) (int, error)
+}
+
+type DynamoAlertStore struct {
 	Client    *dynamodb.Client
 	TableName string
 }
 
-func NewAlertStore() (*AlertStore, error) {
+func NewAlertStore() (*DynamoAlertStore, error) {
 	tableName := os.Getenv("DYNAMODB_ALERTS_TABLE")
 	if tableName == "" {
 		return nil, fmt.Errorf("DYNAMODB_ALERTS_TABLE environment variable is not set")
@@ -31,15 +68,18 @@ func NewAlertStore() (*AlertStore, error) {
 		return nil, fmt.Errorf("dynamodb client is not initialized")
 	}
 
-	return &AlertStore{
+	return &DynamoAlertStore{
 		Client:    db.Client,
 		TableName: tableName,
 	}, nil
 }
 
-func (store *AlertStore) SaveAlert(ctx context.Context, alert models.Alert) error {
+func (store *DynamoAlertStore) SaveAlert(ctx context.Context, alert models.Alert) error {
 	if alert.ExpiresAt == 0 {
-		alert.ExpiresAt = time.Now().Add(30 * 24 * time.Hour).Unix()
+		alert.ExpiresAt = time.Now().Add(alertTTL).Unix()
+	}
+	if alert.Status == "" {
+		alert.Status = StatusOpen
 	}
 
 	item, err := attributevalue.MarshalMap(alert)
@@ -48,12 +88,23 @@ func (store *AlertStore) SaveAlert(ctx context.Context, alert models.Alert) erro
 	}
 
 	input := &dynamodb.PutItemInput{
-		TableName: aws.String(store.TableName),
-		Item:      item,
+		TableName:           aws.String(store.TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(device_id)"),
+
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
 	_, err = store.Client.PutItem(ctx, input)
 	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			duplicate := &DuplicateAlertError{Existing: models.Alert{DeviceID: alert.DeviceID, Timestamp: alert.Timestamp}}
+			if err := attributevalue.UnmarshalMap(conditionErr.Item, &duplicate.Existing); err != nil {
+				return fmt.Errorf("failed to unmarshal existing alert: %w", err)
+			}
+			return duplicate
+		}
 		return fmt.Errorf("failed to store alert in dynamodb: %w", err)
 	}
 
@@ -61,7 +112,7 @@ func (store *AlertStore) SaveAlert(ctx context.Context, alert models.Alert) erro
 }
 
 
-func (store *AlertStore) GetAlertsBySeverity(ctx context.Context, severity string, limit int32,
+func (store *DynamoAlertStore) GetAlertsBySeverity(ctx context.Context, severity string, limit int32,
 	) ([]models.Alert, error) {
 
 	const defaultLimit int32 = 20
@@ -102,7 +153,7 @@ func (store *AlertStore) GetAlertsBySeverity(ctx context.Context, severity strin
 }
 
 //return recent alerts for a specific device
-func (store *AlertStore) GetAlertsByDevice(ctx context.Context, deviceID string, limit int32) ([]models.Alert, error) {
+func (store *DynamoAlertStore) GetAlertsByDevice(ctx context.Context, deviceID string, limit int32) ([]models.Alert, error) {
     const defaultLimit int32 = 20
     if limit <= 0 {
         limit = defaultLimit
@@ -132,29 +183,186 @@ func (store *AlertStore) GetAlertsByDevice(ctx context.Context, deviceID string,
 }
 
 
-//retrieve all alerts in the whole system (system overview part)
-func (store *AlertStore) GetAllAlerts(ctx context.Context, since int64) ([]models.Alert, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(store.TableName),
-		FilterExpression: aws.String("#ts >= :since"),
+// every alert of a device raised since a unix time, newest first (system overview part)
+func (store *DynamoAlertStore) GetAlertsByDeviceSince(ctx context.Context, deviceID string, since int64) ([]models.Alert, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		KeyConditionExpression: aws.String("device_id = :id AND #ts >= :since"),
 		ExpressionAttributeNames: map[string]string{
 			"#ts": "timestamp",
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":    &types.AttributeValueMemberS{Value: deviceID},
 			":since": &types.AttributeValueMemberN{Value: fmt.Sprint(since)},
 		},
+		ScanIndexForward: aws.Bool(false), // newest first
 	}
 
-	res, err := store.Client.Scan(ctx, input)
+	alertList := []models.Alert{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query alerts for device %s: %w", deviceID, err)
+		}
+
+		var items []models.Alert
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal alerts for device %s: %w", deviceID, err)
+		}
+		alertList = append(alertList, items...)
+	}
+
+	return alertList, nil
+}
+
+// newest alerts in a given status from the StatusIndex GSI (status + timestamp)
+func (store *DynamoAlertStore) GetAlertsByStatus(ctx context.Context, status string, limit int32) ([]models.Alert, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	res, err := store.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		IndexName:              aws.String(statusIndex),
+		KeyConditionExpression: aws.String("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+		ScanIndexForward: aws.Bool(false), // newest first
+		Limit:            aws.Int32(limit),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan all alerts: %w", err)
+		return nil, fmt.Errorf("failed to query alerts by status: %w", err)
 	}
 
-	var alerts []models.Alert
-	if err = attributevalue.UnmarshalListOfMaps(res.Items, &alerts);
-	  err != nil {
+	alertList := []models.Alert{}
+	if err = attributevalue.UnmarshalListOfMaps(res.Items, &alertList); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal alerts: %w", err)
 	}
 
-	return alerts, nil
+	return alertList, nil
+}
+
+// newest alerts of a device in one of the statuses, limit <= 0 returns all of them.
+// alerts saved before statuses existed match OPEN
+func (store *DynamoAlertStore) GetAlertsByDeviceStatus(ctx context.Context, deviceID string, statuses []string, limit int32) ([]models.Alert, error) {
+	values := map[string]types.AttributeValue{
+		":id": &types.AttributeValueMemberS{Value: deviceID},
+	}
+	conditions := []string{}
+	for i, status := range statuses {
+		name := fmt.Sprintf(":status%d", i)
+		values[name] = &types.AttributeValueMemberS{Value: status}
+		conditions = append(conditions, "#status = "+name)
+		if status == StatusOpen {
+			conditions = append(conditions, "attribute_not_exists(#status)")
+		}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		KeyConditionExpression: aws.String("device_id = :id"),
+		FilterExpression:       aws.String(strings.Join(conditions, " OR ")),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false), // newest first
+	}
+
+	// the filter runs after the page is read, so keep paging until limit matches are found
+	alertList := []models.Alert{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query alerts for device %s: %w", deviceID, err)
+		}
+
+		var items []models.Alert
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal alerts for device %s: %w", deviceID, err)
+		}
+		alertList = append(alertList, items...)
+
+		if limit > 0 && len(alertList) >= int(limit) {
+			return alertList[:limit], nil
+		}
+	}
+
+	return alertList, nil
+}
+
+// OPEN -> ACKNOWLEDGED
+func (store *DynamoAlertStore) AcknowledgeAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	return store.updateStatus(ctx, deviceID, timestamp,
+		"attribute_not_exists(#status) OR #status = :open",
+		"SET #status = :status, acknowledged_by = :by, acknowledged_at = :at",
+		StatusAcknowledged, by, at)
+}
+
+// OPEN or ACKNOWLEDGED -> RESOLVED
+func (store *DynamoAlertStore) ResolveAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	return store.updateStatus(ctx, deviceID, timestamp,
+		"attribute_not_exists(#status) OR #status IN (:open, :acknowledged)",
+		"SET #status = :status, resolved_by = :by, resolved_at = :at",
+		StatusResolved, by, at)
+}
+
+func (store *DynamoAlertStore) updateStatus(ctx context.Context, deviceID string, timestamp int64, allowedFrom string, updateExpr string, status string, by string, at int64) (*models.Alert, error) {
+	values := map[string]types.AttributeValue{
+		":status": &types.AttributeValueMemberS{Value: status},
+		":by":     &types.AttributeValueMemberS{Value: by},
+		":at":     &types.AttributeValueMemberN{Value: fmt.Sprint(at)},
+		":open":   &types.AttributeValueMemberS{Value: StatusOpen},
+	}
+	if status == StatusResolved {
+		values[":acknowledged"] = &types.AttributeValueMemberS{Value: StatusAcknowledged}
+	}
+
+	res, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+			"timestamp": &types.AttributeValueMemberN{Value: fmt.Sprint(timestamp)},
+		},
+		ConditionExpression: aws.String("attribute_exists(device_id) AND (" + allowedFrom + ")"),
+		UpdateExpression:    aws.String(updateExpr),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues:           values,
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			if len(conditionErr.Item) == 0 {
+				return nil, fmt.Errorf("%w: %s at %d", ErrAlertNotFound, deviceID, timestamp)
+			}
+			return nil, fmt.Errorf("%w: %s at %d -> %s", ErrInvalidAlertTransition, deviceID, timestamp, status)
+		}
+		return nil, fmt.Errorf("failed to update alert status: %w", err)
+	}
+
+	var alert models.Alert
+	if err = attributevalue.UnmarshalMap(res.Attributes, &alert); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// drops every alert of a decommissioned device, whatever its status
+func (store *DynamoAlertStore) DeleteDeviceAlerts(ctx context.Context, deviceID string) (int, error) {
+	deleted, err := db.DeletePartition(ctx, store.Client, store.TableName, "device_id", "timestamp", deviceID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete alerts of device %s: %w", deviceID, err)
+	}
+	return deleted, nil
 }
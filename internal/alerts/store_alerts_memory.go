@@ -0,0 +1,197 @@
+package alerts
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const defaultQueryLimit = 20
+
+type alertKey struct {
+	DeviceID  string
+	Timestamp int64
+}
+
+// MemoryAlertStore is an in-process AlertStore used for tests and local demos
+type MemoryAlertStore struct {
+	mu     sync.Mutex
+	alerts map[alertKey]models.Alert
+}
+
+func NewMemoryAlertStore() *MemoryAlertStore {
+	return &MemoryAlertStore{
+		alerts: make(map[alertKey]models.Alert),
+	}
+}
+
+func (store *MemoryAlertStore) SaveAlert(ctx context.Context, alert models.Alert) error {
+	if alert.ExpiresAt == 0 {
+		alert.ExpiresAt = time.Now().Add(alertTTL).Unix()
+	}
+	if alert.Status == "" {
+		alert.Status = StatusOpen
+	}
+	alert.Payload = maps.Clone(alert.Payload)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := alertKey{DeviceID: alert.DeviceID, Timestamp: alert.Timestamp}
+	if existing, exists := store.alerts[key]; exists && (existing.ExpiresAt == 0 || existing.ExpiresAt > time.Now().Unix()) {
+		existing.Payload = maps.Clone(existing.Payload)
+		return &DuplicateAlertError{Existing: existing}
+	}
+	store.alerts[key] = alert
+	return nil
+}
+
+func (store *MemoryAlertStore) GetAlertsBySeverity(ctx context.Context, severity string, limit int32) ([]models.Alert, error) {
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	alertList := store.collect(func(alert models.Alert) bool {
+		return alert.Severity == severity
+	})
+
+	return truncate(alertList, limit), nil
+}
+
+func (store *MemoryAlertStore) GetAlertsByDevice(ctx context.Context, deviceID string, limit int32) ([]models.Alert, error) {
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	alertList := store.collect(func(alert models.Alert) bool {
+		return alert.DeviceID == deviceID
+	})
+
+	return truncate(alertList, limit), nil
+}
+
+func (store *MemoryAlertStore) GetAlertsByDeviceSince(ctx context.Context, deviceID string, since int64) ([]models.Alert, error) {
+	return store.collect(func(alert models.Alert) bool {
+		return alert.DeviceID == deviceID && alert.Timestamp >= since
+	}), nil
+}
+
+func (store *MemoryAlertStore) GetAlertsByStatus(ctx context.Context, status string, limit int32) ([]models.Alert, error) {
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	alertList := store.collect(func(alert models.Alert) bool {
+		return alert.Status == status
+	})
+
+	return truncate(alertList, limit), nil
+}
+
+func (store *MemoryAlertStore) GetAlertsByDeviceStatus(ctx context.Context, deviceID string, statuses []string, limit int32) ([]models.Alert, error) {
+	alertList := store.collect(func(alert models.Alert) bool {
+		return alert.DeviceID == deviceID && slices.Contains(statuses, StatusOf(alert))
+	})
+
+	if limit > 0 {
+		alertList = truncate(alertList, limit)
+	}
+	return alertList, nil
+}
+
+func (store *MemoryAlertStore) AcknowledgeAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	return store.updateStatus(deviceID, timestamp, StatusAcknowledged, func(alert *models.Alert) bool {
+		if StatusOf(*alert) != StatusOpen {
+			return false
+		}
+		alert.AcknowledgedBy = by
+		alert.AcknowledgedAt = at
+		return true
+	})
+}
+
+func (store *MemoryAlertStore) ResolveAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	return store.updateStatus(deviceID, timestamp, StatusResolved, func(alert *models.Alert) bool {
+		if StatusOf(*alert) == StatusResolved {
+			return false
+		}
+		alert.ResolvedBy = by
+		alert.ResolvedAt = at
+		return true
+	})
+}
+
+// apply returns false when the alert can't move to status, same errors as the conditional update
+func (store *MemoryAlertStore) updateStatus(deviceID string, timestamp int64, status string, apply func(*models.Alert) bool) (*models.Alert, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := alertKey{DeviceID: deviceID, Timestamp: timestamp}
+	alert, exists := store.alerts[key]
+	if !exists || (alert.ExpiresAt > 0 && alert.ExpiresAt <= time.Now().Unix()) {
+		return nil, fmt.Errorf("%w: %s at %d", ErrAlertNotFound, deviceID, timestamp)
+	}
+	if !apply(&alert) {
+		return nil, fmt.Errorf("%w: %s at %d -> %s", ErrInvalidAlertTransition, deviceID, timestamp, status)
+	}
+
+	alert.Status = status
+	store.alerts[key] = alert
+
+	alert.Payload = maps.Clone(alert.Payload)
+	return &alert, nil
+}
+
+// returns matching alerts newest first, dropping expired ones like the table TTL
+func (store *MemoryAlertStore) collect(match func(models.Alert) bool) []models.Alert {
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	alertList := make([]models.Alert, 0)
+	for key, alert := range store.alerts {
+		if alert.ExpiresAt > 0 && alert.ExpiresAt <= now {
+			delete(store.alerts, key)
+			continue
+		}
+		if !match(alert) {
+			continue
+		}
+		alert.Payload = maps.Clone(alert.Payload)
+		alertList = append(alertList, alert)
+	}
+
+	slices.SortFunc(alertList, func(a, b models.Alert) int {
+		return cmp.Compare(b.Timestamp, a.Timestamp)
+	})
+
+	return alertList
+}
+
+func truncate(alertList []models.Alert, limit int32) []models.Alert {
+	if len(alertList) > int(limit) {
+		return alertList[:limit]
+	}
+	return alertList
+}
+
+func (store *MemoryAlertStore) DeleteDeviceAlerts(ctx context.Context, deviceID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := 0
+	for key := range store.alerts {
+		if key.DeviceID == deviceID {
+			delete(store.alerts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,31 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// a redelivered alert must not reopen the one already handled
+func TestSaveAlertDuplicate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAlertStore()
+	alert := models.Alert{DeviceID: "gas-1", Timestamp: 1000, Type: "gas-sensor", Severity: "CRITICAL"}
+
+	if err := store.SaveAlert(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ResolveAlert(ctx, "gas-1", 1000, "user-1", 1100); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveAlert(ctx, alert); !errors.Is(err, ErrDuplicateAlert) {
+		t.Fatalf("second SaveAlert = %v, want ErrDuplicateAlert", err)
+	}
+
+	saved, _ := store.GetAlertsByDevice(ctx, "gas-1", 0)
+	if len(saved) != 1 || saved[0].Status != StatusResolved {
+		t.Errorf("alerts = %+v, want the resolved one", saved)
+	}
+}
@@ -0,0 +1,183 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// ThresholdStore keeps the alert thresholds of every device (device_id + threshold_id) and their breach tracking
+type ThresholdStore interface {
+	SaveThreshold(ctx context.Context, threshold models.Threshold) error
+	GetThreshold(ctx context.Context, deviceID string, thresholdID string) (*models.Threshold, error)
+	GetThresholds(ctx context.Context, deviceID string) ([]models.Threshold, error)
+	DeleteThreshold(ctx context.Context, deviceID string, thresholdID string) error
+	SetBreachSince(ctx context.Context, deviceID string, thresholdID string, since int64) error
+	ClaimAlert(ctx context.Context, deviceID string, thresholdID string, previousAlertAt int64, alertAt int64) (bool, error)
+	DeleteDeviceThresholds(ctx context.Context, deviceID string) (int, error)
+}
+
+type DynamoThresholdStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewThresholdStore() (*DynamoThresholdStore, error) {
+	tableName := os.Getenv("DYNAMODB_THRESHOLDS_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_THRESHOLDS_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoThresholdStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+func thresholdKey(deviceID string, thresholdID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"device_id":    &types.AttributeValueMemberS{Value: deviceID},
+		"threshold_id": &types.AttributeValueMemberS{Value: thresholdID},
+	}
+}
+
+func (store *DynamoThresholdStore) SaveThreshold(ctx context.Context, threshold models.Threshold) error {
+	item, err := attributevalue.MarshalMap(threshold)
+	if err != nil {
+		return fmt.Errorf("failed to marshal threshold: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store threshold in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoThresholdStore) GetThreshold(ctx context.Context, deviceID string, thresholdID string) (*models.Threshold, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key:       thresholdKey(deviceID, thresholdID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get threshold %s of device %s: %w", thresholdID, deviceID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var threshold models.Threshold
+	if err = attributevalue.UnmarshalMap(result.Item, &threshold); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal threshold %s: %w", thresholdID, err)
+	}
+
+	return &threshold, nil
+}
+
+func (store *DynamoThresholdStore) GetThresholds(ctx context.Context, deviceID string) ([]models.Threshold, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		KeyConditionExpression: aws.String("device_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	}
+
+	thresholdList := []models.Threshold{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query thresholds for device %s: %w", deviceID, err)
+		}
+
+		var items []models.Threshold
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal thresholds for device %s: %w", deviceID, err)
+		}
+		thresholdList = append(thresholdList, items...)
+	}
+
+	return thresholdList, nil
+}
+
+func (store *DynamoThresholdStore) DeleteThreshold(ctx context.Context, deviceID string, thresholdID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key:       thresholdKey(deviceID, thresholdID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete threshold %s of device %s: %w", thresholdID, deviceID, err)
+	}
+	return nil
+}
+
+// breach bookkeeping, a threshold deleted in the meantime is left deleted
+func (store *DynamoThresholdStore) SetBreachSince(ctx context.Context, deviceID string, thresholdID string, since int64) error {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(store.TableName),
+		Key:                 thresholdKey(deviceID, thresholdID),
+		ConditionExpression: aws.String("attribute_exists(threshold_id)"),
+		UpdateExpression:    aws.String("SET breach_since = :since"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":since": &types.AttributeValueMemberN{Value: fmt.Sprint(since)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to update threshold %s of device %s: %w", thresholdID, deviceID, err)
+	}
+	return nil
+}
+
+// conditional on last_alert_at so a message redelivered to two invocations raises a single alert
+func (store *DynamoThresholdStore) ClaimAlert(ctx context.Context, deviceID string, thresholdID string, previousAlertAt int64, alertAt int64) (bool, error) {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(store.TableName),
+		Key:                 thresholdKey(deviceID, thresholdID),
+		ConditionExpression: aws.String("last_alert_at = :previous"),
+		UpdateExpression:    aws.String("SET last_alert_at = :alert_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":previous": &types.AttributeValueMemberN{Value: fmt.Sprint(previousAlertAt)},
+			":alert_at": &types.AttributeValueMemberN{Value: fmt.Sprint(alertAt)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim alert of threshold %s: %w", thresholdID, err)
+	}
+	return true, nil
+}
+
+// drops every threshold of a purged device
+func (store *DynamoThresholdStore) DeleteDeviceThresholds(ctx context.Context, deviceID string) (int, error) {
+	deleted, err := db.DeletePartition(ctx, store.Client, store.TableName, "device_id", "threshold_id", deviceID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete thresholds of device %s: %w", deviceID, err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type thresholdKeyPair struct {
+	DeviceID    string
+	ThresholdID string
+}
+
+// MemoryThresholdStore is an in-process ThresholdStore used for tests and local demos
+type MemoryThresholdStore struct {
+	mu         sync.Mutex
+	thresholds map[thresholdKeyPair]models.Threshold
+}
+
+func NewMemoryThresholdStore() *MemoryThresholdStore {
+	return &MemoryThresholdStore{
+		thresholds: make(map[thresholdKeyPair]models.Threshold),
+	}
+}
+
+func (store *MemoryThresholdStore) SaveThreshold(ctx context.Context, threshold models.Threshold) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.thresholds[thresholdKeyPair{threshold.DeviceID, threshold.ThresholdID}] = threshold
+	return nil
+}
+
+func (store *MemoryThresholdStore) GetThreshold(ctx context.Context, deviceID string, thresholdID string) (*models.Threshold, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	threshold, exists := store.thresholds[thresholdKeyPair{deviceID, thresholdID}]
+	if !exists {
+		return nil, nil
+	}
+	return &threshold, nil
+}
+
+// ordered by threshold_id like the table sort key
+func (store *MemoryThresholdStore) GetThresholds(ctx context.Context, deviceID string) ([]models.Threshold, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	thresholdList := make([]models.Threshold, 0)
+	for key, threshold := range store.thresholds {
+		if key.DeviceID == deviceID {
+			thresholdList = append(thresholdList, threshold)
+		}
+	}
+
+	slices.SortFunc(thresholdList, func(a, b models.Threshold) int {
+		return cmp.Compare(a.ThresholdID, b.ThresholdID)
+	})
+	return thresholdList, nil
+}
+
+func (store *MemoryThresholdStore) DeleteThreshold(ctx context.Context, deviceID string, thresholdID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.thresholds, thresholdKeyPair{deviceID, thresholdID})
+	return nil
+}
+
+func (store *MemoryThresholdStore) SetBreachSince(ctx context.Context, deviceID string, thresholdID string, since int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := thresholdKeyPair{deviceID, thresholdID}
+	threshold, exists := store.thresholds[key]
+	if !exists {
+		return nil
+	}
+	threshold.BreachSince = since
+	store.thresholds[key] = threshold
+	return nil
+}
+
+func (store *MemoryThresholdStore) ClaimAlert(ctx context.Context, deviceID string, thresholdID string, previousAlertAt int64, alertAt int64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := thresholdKeyPair{deviceID, thresholdID}
+	threshold, exists := store.thresholds[key]
+	if !exists || threshold.LastAlertAt != previousAlertAt {
+		return false, nil
+	}
+	threshold.LastAlertAt = alertAt
+	store.thresholds[key] = threshold
+	return true, nil
+}
+
+func (store *MemoryThresholdStore) DeleteDeviceThresholds(ctx context.Context, deviceID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := 0
+	for key := range store.thresholds {
+		if key.DeviceID == deviceID {
+			delete(store.thresholds, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
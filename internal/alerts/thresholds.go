@@ -0,0 +1,209 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	OperatorAbove  = "ABOVE"
+	OperatorBelow  = "BELOW"
+	OperatorEquals = "EQUALS"
+
+	// alert payload status of a threshold alert
+	StatusThresholdBreached = "THRESHOLD_BREACHED"
+
+	DefaultThresholdCooldownSeconds = 600
+)
+
+var (
+	ErrInvalidThreshold = errors.New("invalid threshold")
+
+	severities = []string{"LOW", "MEDIUM", "CRITICAL"}
+)
+
+// thresholds a device gets until its own are configured, same limits as the door insights
+var defaultThresholds = map[string][]models.Threshold{
+	"door-actuator": {
+		{Metric: "lock_state", Operator: OperatorEquals, State: "UNLOCKED", ForSeconds: 7 * 60, Severity: "MEDIUM"},
+		{Metric: "lock_state", Operator: OperatorEquals, State: "UNLOCKED", ForSeconds: 15 * 60, Severity: "CRITICAL"},
+	},
+}
+
+// copies of the default thresholds of a device type, ready to be saved for deviceID
+func DefaultThresholds(deviceType string, deviceID string, now int64) []models.Threshold {
+	defaults := defaultThresholds[deviceType]
+
+	thresholdList := make([]models.Threshold, 0, len(defaults))
+	for i, threshold := range defaults {
+		threshold.DeviceID = deviceID
+		threshold.ThresholdID = fmt.Sprintf("default-%d", i+1)
+		threshold.CooldownSeconds = DefaultThresholdCooldownSeconds
+		threshold.Enabled = true
+		threshold.CreatedAt = now
+		threshold.UpdatedAt = now
+		thresholdList = append(thresholdList, threshold)
+	}
+	return thresholdList
+}
+
+// SeedThresholds saves the defaults of the device type once, when the device joins a home. a device
+// that already has thresholds keeps them, and one whose thresholds were all deleted stays without
+func SeedThresholds(ctx context.Context, store ThresholdStore, deviceID string, deviceType string, now int64) (int, error) {
+	existing, err := store.GetThresholds(ctx, deviceID)
+	if err != nil || len(existing) > 0 {
+		return 0, err
+	}
+
+	defaults := DefaultThresholds(deviceType, deviceID, now)
+	for i, threshold := range defaults {
+		if err := store.SaveThreshold(ctx, threshold); err != nil {
+			return i, err
+		}
+	}
+	return len(defaults), nil
+}
+
+// checks the fields a threshold needs to be evaluated and fills the defaults
+func PrepareThreshold(threshold *models.Threshold) error {
+	if threshold.Metric == "" {
+		return fmt.Errorf("%w: metric is required", ErrInvalidThreshold)
+	}
+
+	switch threshold.Operator {
+	case OperatorAbove, OperatorBelow:
+		if threshold.Hysteresis < 0 {
+			return fmt.Errorf("%w: hysteresis must not be negative", ErrInvalidThreshold)
+		}
+		threshold.State = ""
+	case OperatorEquals:
+		if threshold.State == "" {
+			return fmt.Errorf("%w: state is required for EQUALS", ErrInvalidThreshold)
+		}
+		threshold.Value = 0
+		threshold.Hysteresis = 0
+	default:
+		return fmt.Errorf("%w: operator must be ABOVE, BELOW or EQUALS", ErrInvalidThreshold)
+	}
+
+	if threshold.ForSeconds < 0 || threshold.CooldownSeconds < 0 {
+		return fmt.Errorf("%w: for_seconds and cooldown_seconds must not be negative", ErrInvalidThreshold)
+	}
+	if threshold.Severity == "" {
+		threshold.Severity = "MEDIUM"
+	}
+	if !slices.Contains(severities, threshold.Severity) {
+		return fmt.Errorf("%w: severity must be LOW, MEDIUM or CRITICAL", ErrInvalidThreshold)
+	}
+	if threshold.CooldownSeconds == 0 {
+		threshold.CooldownSeconds = DefaultThresholdCooldownSeconds
+	}
+
+	return nil
+}
+
+// BreachDecision is what a threshold does with a new reading
+type BreachDecision struct {
+	Raise       bool
+	BreachSince int64       // new breach start, 0 when the reading is back within limits
+	Observed    interface{} // the metric value that was read
+}
+
+// the breach must last ForSeconds before alerting, a threshold alerts once per breach and
+// never sooner than CooldownSeconds after its previous alert, so a flapping sensor raises one alert.
+// ok is false when the reading doesn't carry the metric, the breach is then left as it is
+func EvaluateThreshold(threshold models.Threshold, payload map[string]interface{}, at int64) (decision BreachDecision, ok bool) {
+	observed, exists := payload[threshold.Metric]
+	if !exists {
+		return BreachDecision{BreachSince: threshold.BreachSince}, false
+	}
+	decision.Observed = observed
+
+	breaching, ok := isBreaching(threshold, observed)
+	if !ok {
+		return BreachDecision{BreachSince: threshold.BreachSince}, false
+	}
+	if !breaching {
+		return decision, true
+	}
+
+	since := threshold.BreachSince
+	if since == 0 || since > at {
+		since = at
+	}
+	decision.BreachSince = since
+
+	if at-since < threshold.ForSeconds {
+		return decision, true
+	}
+	if threshold.LastAlertAt >= since {
+		return decision, true // already alerted for this breach
+	}
+
+	cooldown := threshold.CooldownSeconds
+	if cooldown == 0 {
+		cooldown = DefaultThresholdCooldownSeconds
+	}
+	if threshold.LastAlertAt > 0 && at-threshold.LastAlertAt < cooldown {
+		return decision, true
+	}
+
+	decision.Raise = true
+	return decision, true
+}
+
+// an ongoing numeric breach only ends once the value is back past the hysteresis margin
+func isBreaching(threshold models.Threshold, observed interface{}) (bool, bool) {
+	if threshold.Operator == OperatorEquals {
+		return fmt.Sprint(observed) == threshold.State, true
+	}
+
+	value, ok := observed.(float64)
+	if !ok {
+		return false, false
+	}
+
+	inBreach := threshold.BreachSince > 0
+	switch threshold.Operator {
+	case OperatorAbove:
+		if inBreach {
+			return value > threshold.Value-threshold.Hysteresis, true
+		}
+		return value > threshold.Value, true
+	case OperatorBelow:
+		if inBreach {
+			return value < threshold.Value+threshold.Hysteresis, true
+		}
+		return value < threshold.Value, true
+	}
+	return false, false
+}
+
+// the alert record written for a raised threshold, same shape as a device alert plus the limit
+func thresholdAlert(threshold models.Threshold, deviceType string, observed interface{}, at int64) models.Alert {
+	payload := map[string]interface{}{
+		"status":       StatusThresholdBreached,
+		"threshold_id": threshold.ThresholdID,
+		"metric":       threshold.Metric,
+		"operator":     threshold.Operator,
+		"observed":     observed,
+		"for_seconds":  threshold.ForSeconds,
+	}
+	if threshold.Operator == OperatorEquals {
+		payload["limit"] = threshold.State
+	} else {
+		payload["limit"] = threshold.Value
+	}
+
+	return models.Alert{
+		DeviceID:  threshold.DeviceID,
+		Timestamp: at,
+		Type:      deviceType,
+		Severity:  threshold.Severity,
+		Payload:   payload,
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// users only manage their own push tokens and preferences
+func sameUser(context *gin.Context) bool {
+	if context.Param("user_id") != auth.UserID(context) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own notifications"})
+		return false
+	}
+	return true
+}
+
+// devices of every home the caller belongs to, false when the response is already written
+func callerDevices(context *gin.Context, store homes.HomeStore) (map[string]bool, bool) {
+	userID := auth.UserID(context)
+
+	deviceIDs, err := homes.DeviceIDs(context.Request.Context(), store, userID)
+	if err != nil {
+		slog.Error("failed to resolve user devices", "user_id", userID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return deviceIDs, true
+}
+
+// the caller must own the home: other members get 403 with forbiddenMsg, anyone outside it 404
+func requireHomeOwner(context *gin.Context, store homes.HomeStore, homeID string, forbiddenMsg string) (*models.Home, bool) {
+	userID := auth.UserID(context)
+
+	member, err := homes.ActiveMember(context.Request.Context(), store, homeID, userID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home"})
+		return nil, false
+	}
+	home, err := store.GetHome(context.Request.Context(), homeID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home"})
+		return nil, false
+	}
+	if member == nil || home == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
+		return nil, false
+	}
+	if home.OwnerID != userID {
+		context.JSON(http.StatusForbidden, gin.H{"error": forbiddenMsg})
+		return nil, false
+	}
+	return home, true
+}
+
+// devices outside the caller's homes answer 404 like unknown ones, so their ids don't leak
+func authorizeDevice(context *gin.Context, store homes.HomeStore, deviceID string) bool {
+	return homesAllow(context, store, deviceID, "Device not found")
+}
+
+// same check for records hanging off a device (alerts, commands), notFoundMsg names the record
+func homesAllow(context *gin.Context, store homes.HomeStore, deviceID string, notFoundMsg string) bool {
+	userID := auth.UserID(context)
+
+	allowed, err := homes.CanAccessDevice(context.Request.Context(), store, userID, deviceID)
+	if err != nil {
+		slog.Error("failed to check device access", "user_id", userID, "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	if !allowed {
+		context.JSON(http.StatusNotFound, gin.H{"error": notFoundMsg})
+		return false
+	}
+	return true
+}
+
+// the caller's role must allow sending the action, whether now or later from a schedule or rule.
+// denials are logged and audited with who tried what and answer 403. returns the caller's membership
+// of the device's home
+func authorizeCommand(context *gin.Context, store homes.HomeStore, recorder *audit.Recorder, deviceID string, deviceType string, action string) (*models.HomeMember, bool) {
+	userID := auth.UserID(context)
+
+	member, err := homes.DeviceMember(context.Request.Context(), store, userID, deviceID)
+	if err != nil {
+		slog.Error("failed to check device access", "user_id", userID, "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if member == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+	if !homes.Allows(*member, homes.CommandPermission(deviceType, action)) {
+		slog.Warn("command denied", "user_id", userID, "device_id", deviceID, "action", action, "role", homes.RoleOf(*member), "path", context.FullPath())
+		recordAudit(context, recorder, models.AuditEvent{
+			Action:   audit.ActionPermissionDenied,
+			HomeID:   member.HomeID,
+			DeviceID: deviceID,
+			After:    map[string]interface{}{"action": action},
+			Reason:   "role " + homes.RoleOf(*member) + " may not " + action + " " + deviceType,
+		})
+		context.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("You are not allowed to %s this device", action)})
+		return nil, false
+	}
+	return member, true
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAlertsLimit = 20
+
+type AlertHandler struct {
+	AlertStore alerts.AlertStore
+	Homes      homes.HomeStore
+	Audit      *audit.Recorder
+}
+
+// handling GET /alerts?status=OPEN, limited to the devices of the caller's homes
+func (handler *AlertHandler) ListAlerts(context *gin.Context) {
+	status := context.DefaultQuery("status", alerts.StatusOpen)
+	if !alerts.IsAlertStatus(status) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "status must be OPEN, ACKNOWLEDGED or RESOLVED"})
+		return
+	}
+
+	limit, err := queryInt(context, "limit")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+
+	deviceIDs, ok := callerDevices(context, handler.Homes)
+	if !ok {
+		return
+	}
+
+	if limit <= 0 {
+		limit = defaultAlertsLimit
+	}
+
+	// read per device, the status index mixes every home's alerts and would use up the limit
+	alertList := []models.Alert{}
+	for deviceID := range deviceIDs {
+		deviceAlerts, err := handler.AlertStore.GetAlertsByDeviceStatus(context.Request.Context(), deviceID, []string{status}, int32(limit))
+		if err != nil {
+			slog.Error("failed to fetch alerts", "device_id", deviceID, "error", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+			return
+		}
+		alertList = append(alertList, deviceAlerts...)
+	}
+
+	slices.SortFunc(alertList, func(a, b models.Alert) int {
+		return cmp.Or(cmp.Compare(b.Timestamp, a.Timestamp), cmp.Compare(a.DeviceID, b.DeviceID))
+	})
+	if len(alertList) > int(limit) {
+		alertList = alertList[:limit]
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": alertList})
+}
+
+// handling POST /alerts/:device_id/:timestamp/ack
+func (handler *AlertHandler) AcknowledgeAlert(context *gin.Context) {
+	handler.changeStatus(context, handler.AlertStore.AcknowledgeAlert, audit.ActionAlertAcknowledged, "Only OPEN alerts can be acknowledged")
+}
+
+// handling POST /alerts/:device_id/:timestamp/resolve
+func (handler *AlertHandler) ResolveAlert(context *gin.Context) {
+	handler.changeStatus(context, handler.AlertStore.ResolveAlert, audit.ActionAlertResolved, "Alert is already RESOLVED")
+}
+
+type alertTransition func(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error)
+
+// conflictMsg is returned when the alert is not in a status the transition starts from
+func (handler *AlertHandler) changeStatus(context *gin.Context, transition alertTransition, auditAction string, conflictMsg string) {
+	deviceID := context.Param("device_id")
+	timestamp, err := strconv.ParseInt(context.Param("timestamp"), 10, 64)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "timestamp must be a unix timestamp"})
+		return
+	}
+
+	if !homesAllow(context, handler.Homes, deviceID, "Alert not found") {
+		return
+	}
+
+	// always the verified caller, so the audit trail can't be written in someone else's name
+	alert, err := transition(context.Request.Context(), deviceID, timestamp, auth.UserID(context), time.Now().Unix())
+	switch {
+	case errors.Is(err, alerts.ErrAlertNotFound):
+		context.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+	case errors.Is(err, alerts.ErrInvalidAlertTransition):
+		context.JSON(http.StatusConflict, gin.H{"error": conflictMsg})
+	case err != nil:
+		slog.Error("failed to update alert status", "device_id", deviceID, "timestamp", timestamp, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+	default:
+		recordAudit(context, handler.Audit, models.AuditEvent{
+			Action:   auditAction,
+			DeviceID: deviceID,
+			TargetID: strconv.FormatInt(timestamp, 10),
+			After:    alert,
+		})
+		context.JSON(http.StatusOK, alert)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI signs requests as any user and sends them through the auth middleware, like the api does
+type testAPI struct {
+	router *gin.Engine
+	v1     *gin.RouterGroup
+	key    *rsa.PrivateKey
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &auth.Verifier{
+		Keys:     &auth.StaticKeySource{Keys: map[string]*rsa.PublicKey{"key-1": &key.PublicKey}},
+		Issuer:   "https://issuer.example",
+		Audience: "fleexa-app",
+	}
+
+	api := &testAPI{router: gin.New(), key: key}
+	api.v1 = api.router.Group("/v1", auth.Middleware(verifier))
+	return api
+}
+
+func (api *testAPI) do(t *testing.T, userID string, method string, path string, body string) int {
+	t.Helper()
+	token, err := auth.SignToken(api.key, "key-1", auth.Claims{
+		Subject:   userID,
+		Issuer:    "https://issuer.example",
+		Audience:  auth.Audience{"fleexa-app"},
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		IssuedAt:  time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	api.router.ServeHTTP(res, req)
+	return res.Code
+}
+
+// home-1 is owned by owner-1
+func newTestHomes(t *testing.T) *homes.MemoryHomeStore {
+	t.Helper()
+	ctx := context.Background()
+	homeStore := homes.NewMemoryHomeStore()
+	if err := homeStore.SaveHome(ctx, models.Home{HomeID: "home-1", OwnerID: "owner-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := homeStore.SaveMember(ctx, models.HomeMember{HomeID: "home-1", UserID: "owner-1", Role: homes.RoleOwner}); err != nil {
+		t.Fatal(err)
+	}
+	return homeStore
+}
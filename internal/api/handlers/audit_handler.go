@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	Events audit.AuditStore
+	Homes  homes.HomeStore
+}
+
+// handling GET /audit?home_id=...&device_id=...&actor_id=...&action=...&from=...&to=...&limit=...&cursor=...
+// without home_id the caller reads their own trail, a home's trail is for its owner
+func (handler *AuditHandler) ListEvents(context *gin.Context) {
+	userID := auth.UserID(context)
+	query := audit.Query{
+		HomeID:   context.Query("home_id"),
+		ActorID:  context.Query("actor_id"),
+		DeviceID: context.Query("device_id"),
+		Action:   context.Query("action"),
+		Cursor:   context.Query("cursor"),
+	}
+
+	var err error
+	if query.From, err = queryInt(context, "from"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "from must be a unix timestamp"})
+		return
+	}
+	if query.To, err = queryInt(context, "to"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "to must be a unix timestamp"})
+		return
+	}
+	limit, err := queryInt(context, "limit")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+	query.Limit = int32(limit)
+
+	if query.HomeID == "" {
+		if query.ActorID != "" && query.ActorID != userID {
+			context.JSON(http.StatusForbidden, gin.H{"error": "home_id is required to read the events of other users"})
+			return
+		}
+		query.ActorID = userID
+	} else if _, ok := requireHomeOwner(context, handler.Homes, query.HomeID, "Only the home owner can read its audit log"); !ok {
+		return
+	}
+
+	page, err := handler.Events.QueryEvents(context.Request.Context(), query)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit events"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"data":        page.Events,
+		"next_cursor": page.NextCursor,
+	})
+}
+
+// appends an event done by the caller, a failed write is logged and never fails the request
+func recordAudit(context *gin.Context, recorder *audit.Recorder, event models.AuditEvent) {
+	if event.ActorID == "" {
+		event.ActorID = auth.UserID(context)
+	}
+	if err := recorder.Record(context.Request.Context(), event); err != nil {
+		slog.Error("failed to record audit event", "action", event.Action, "error", err)
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+type AutomationHandler struct {
+	StateStore devices.StateStore
+	Rules      automation.RuleStore
+	Homes      homes.HomeStore
+	Audit      *audit.Recorder
+}
+
+type AutomationRequest struct {
+	Name            string                    `json:"name" binding:"required"`
+	Trigger         models.AutomationTrigger  `json:"trigger"`
+	Action          models.AutomationAction   `json:"action"`
+	Weather         *models.AutomationWeather `json:"weather"` // optional outdoor condition
+	CooldownSeconds int64                     `json:"cooldown_seconds"`
+	Enabled         *bool                     `json:"enabled"` // defaults to true
+}
+
+// handling GET /automations, the rules whose devices are all in the caller's homes
+func (handler *AutomationHandler) ListRules(context *gin.Context) {
+	deviceIDs, ok := callerDevices(context, handler.Homes)
+	if !ok {
+		return
+	}
+
+	ruleList, err := handler.Rules.ListRules(context.Request.Context())
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automations"})
+		return
+	}
+	ruleList = slices.DeleteFunc(ruleList, func(rule models.AutomationRule) bool {
+		return !deviceIDs[rule.Trigger.DeviceID] || !deviceIDs[rule.Action.DeviceID]
+	})
+
+	context.JSON(http.StatusOK, gin.H{"data": ruleList})
+}
+
+// handling GET /automations/:rule_id
+func (handler *AutomationHandler) GetRule(context *gin.Context) {
+	rule, ok := handler.loadRule(context)
+	if !ok {
+		return
+	}
+	context.JSON(http.StatusOK, rule)
+}
+
+// handling POST /automations
+func (handler *AutomationHandler) CreateRule(context *gin.Context) {
+	var req AutomationRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid automation format! name is required."})
+		return
+	}
+
+	now := time.Now()
+	rule := models.AutomationRule{
+		RuleID:    fmt.Sprintf("rule-%d", now.UnixNano()),
+		CreatedAt: now.Unix(),
+	}
+	if !handler.applyRequest(context, &rule, req, now) {
+		return
+	}
+
+	if err := handler.Rules.SaveRule(context.Request.Context(), rule); err != nil {
+		slog.Error("failed to save automation", "rule_id", rule.RuleID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save automation"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRuleCreated,
+		DeviceID: rule.Action.DeviceID,
+		TargetID: rule.RuleID,
+		After:    rule,
+	})
+
+	context.JSON(http.StatusCreated, rule)
+}
+
+// handling PUT /automations/:rule_id
+func (handler *AutomationHandler) UpdateRule(context *gin.Context) {
+	var req AutomationRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid automation format! name is required."})
+		return
+	}
+
+	rule, ok := handler.loadRule(context)
+	if !ok {
+		return
+	}
+	before := *rule
+
+	// a changed rule starts a new debounce, past firings stay recorded
+	rule.ConditionSince = 0
+	if !handler.applyRequest(context, rule, req, time.Now()) {
+		return
+	}
+
+	if err := handler.Rules.SaveRule(context.Request.Context(), *rule); err != nil {
+		slog.Error("failed to update automation", "rule_id", rule.RuleID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save automation"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRuleUpdated,
+		DeviceID: rule.Action.DeviceID,
+		TargetID: rule.RuleID,
+		Before:   before,
+		After:    rule,
+	})
+
+	context.JSON(http.StatusOK, rule)
+}
+
+// handling DELETE /automations/:rule_id
+func (handler *AutomationHandler) DeleteRule(context *gin.Context) {
+	rule, ok := handler.loadRule(context)
+	if !ok {
+		return
+	}
+
+	if err := handler.Rules.DeleteRule(context.Request.Context(), rule.RuleID); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete automation"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRuleDeleted,
+		DeviceID: rule.Action.DeviceID,
+		TargetID: rule.RuleID,
+		Before:   rule,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+// handling GET /automations/:rule_id/history
+func (handler *AutomationHandler) GetRuleHistory(context *gin.Context) {
+	rule, ok := handler.loadRule(context)
+	if !ok {
+		return
+	}
+
+	limit, err := queryInt(context, "limit")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+
+	firings, err := handler.Rules.GetFirings(context.Request.Context(), rule.RuleID, int32(limit))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automation history"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"rule_id": rule.RuleID, "data": firings})
+}
+
+// checks both devices exist, the caller may send the action and it is valid for the target, writes the 4xx itself
+func (handler *AutomationHandler) applyRequest(context *gin.Context, rule *models.AutomationRule, req AutomationRequest, now time.Time) bool {
+	rule.Name = req.Name
+	rule.Trigger = req.Trigger
+	rule.Action = req.Action
+	rule.Weather = req.Weather
+	rule.CooldownSeconds = req.CooldownSeconds
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.UpdatedAt = now.Unix()
+
+	if err := automation.Prepare(rule); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if _, ok := handler.loadDevice(context, rule.Trigger.DeviceID); !ok {
+		return false
+	}
+	if rule.Weather != nil && !handler.locatedHome(context, rule.Trigger.DeviceID) {
+		return false
+	}
+	target, ok := handler.loadDevice(context, rule.Action.DeviceID)
+	if !ok {
+		return false
+	}
+	member, ok := authorizeCommand(context, handler.Homes, handler.Audit, rule.Action.DeviceID, target.Type, rule.Action.Action)
+	if !ok {
+		return false
+	}
+	rule.HomeID = member.HomeID
+	rule.CreatedBy = member.UserID // the last editor, the engine checks their permission on every firing
+
+	if err := devices.ValidateCommand(target.Type, rule.Action.Action, rule.Action.Parameters); err != nil {
+		var validationErr *devices.CommandValidationError
+		if errors.As(err, &validationErr) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr})
+			return false
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	return true
+}
+
+func (handler *AutomationHandler) loadDevice(context *gin.Context, deviceID string) (*models.DeviceState, bool) {
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return nil, false
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+	return state, true
+}
+
+// a weather condition is read at the trigger device's home, which needs a location
+func (handler *AutomationHandler) locatedHome(context *gin.Context, deviceID string) bool {
+	homeDevice, err := handler.Homes.GetDeviceHome(context.Request.Context(), deviceID)
+	var home *models.Home
+	if err == nil && homeDevice != nil {
+		home, err = handler.Homes.GetHome(context.Request.Context(), homeDevice.HomeID)
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	if home == nil || home.Location == nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": "weather needs the trigger device's home to have a location"})
+		return false
+	}
+	return true
+}
+
+func (handler *AutomationHandler) loadRule(context *gin.Context) (*models.AutomationRule, bool) {
+	rule, err := handler.Rules.GetRule(context.Request.Context(), context.Param("rule_id"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automation"})
+		return nil, false
+	}
+	if rule == nil || !handler.ownsRule(context, rule) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Automation not found"})
+		return nil, false
+	}
+	return rule, true
+}
+
+// a rule is visible when both its trigger and its target are in the caller's homes
+func (handler *AutomationHandler) ownsRule(context *gin.Context, rule *models.AutomationRule) bool {
+	userID := auth.UserID(context)
+	for _, deviceID := range []string{rule.Trigger.DeviceID, rule.Action.DeviceID} {
+		allowed, err := homes.CanAccessDevice(context.Request.Context(), handler.Homes, userID, deviceID)
+		if err != nil {
+			slog.Error("failed to check automation access", "rule_id", rule.RuleID, "error", err)
+			return false
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
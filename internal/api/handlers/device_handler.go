@@ -1,29 +1,48 @@
 package handlers
 
 import (
+    "errors"
     "log/slog"
     "net/http"
+    "strconv"
     "time"
     "fmt"
     "context"
+    "maps"
+    "slices"
+    "strings"
 	
 
     "github.com/Fleexa-Graduation-Project/Backend/internal/devices"
     "github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
     "github.com/Fleexa-Graduation-Project/Backend/models"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
     "github.com/Fleexa-Graduation-Project/Backend/internal/commands"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/weather"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
     "github.com/gin-gonic/gin"
 )
 
+// the longest period=range of monthly charts one request reads
+const maxChartMonths = 24
+
+// the most metrics one telemetry request charts at once
+const maxChartMetrics = 8
+
 type DeviceHandler struct {
-    StateStore     *devices.StateStore
-    TelemetryStore *telemetry.TelemetryStore
-    AlertStore     *alerts.AlertStore
-    CommandStore   *commands.CommandStore 
-    IoTPublisher   *iot.Publisher
+    StateStore     devices.StateStore
+    TelemetryStore telemetry.TelemetryStore
+    AlertStore     alerts.AlertStore
+    CommandStore   commands.CommandStore 
+    Dispatcher     *commands.Dispatcher
     S3Fetcher      *iot.S3Client
+    Homes          homes.HomeStore // every read and command is limited to the caller's homes
+    Audit          *audit.Recorder
+    Weather        weather.Provider // optional, outdoor weather of the AC details
 }
 
 type SendCommandRequest struct {
@@ -55,14 +74,18 @@ func addLightStatus(payload map[string]interface{}, operationalState string) {
 
 // handling GET /devices
 func (handler *DeviceHandler) GetDevices(context *gin.Context) {
+    deviceIDs, ok := callerDevices(context, handler.Homes)
+    if !ok {
+        return
+    }
 	
-    states, err := handler.StateStore.GetAllStates(context.Request.Context())
+    states, err := visibleStates(context.Request.Context(), handler.StateStore, deviceIDs)
     if err != nil {
         context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch device states"})
         return
     }
     for i := range states {
-		states[i].Status = devices.ConnectionStatus(states[i].LastSeenAt)
+		states[i].Status = devices.LiveStatus(states[i])
         if states[i].Type == "light-sensor" {
             addLightStatus(states[i].Payload, states[i].OperationalState)
         }
@@ -71,14 +94,14 @@ func (handler *DeviceHandler) GetDevices(context *gin.Context) {
 }
 
 // showing last 5 Recent Events with its time - the Last Activity time - warning and alerts based on unlock time
-func showDoorStats(payload map[string]interface{}, history []models.Telemetry, now int64) {
+func showDoorStats(payload map[string]interface{}, history []models.Telemetry, now int64, location *time.Location) {
 	if len(history) == 0 {
 		payload["recent_events"] = []map[string]interface{}{}
 		payload["last_activity_time"] = "No activity"
 		payload["security_alert"] = "SAFE"
 		return
 	}
-	payload["recent_events"] = telemetry.FormatDoorEvents(history)
+	payload["recent_events"] = telemetry.FormatDoorEvents(history, location)
 	payload["last_activity_time"] = telemetry.TimeAgo(history[0].Timestamp, now)
 	
 	if lockState, ok := payload["lock_state"].(string); ok && lockState == "UNLOCKED" {
@@ -115,21 +138,37 @@ func addDoorInsights(response gin.H, data []models.Telemetry, state *models.Devi
 	}
 }
 
-//getting info for AC based on temp and timer
-func (handler *DeviceHandler) showACStats(ctx context.Context, payload map[string]interface{}, now int64) {
-	
-	insideTemp := 0.0
+//getting info for AC based on its temp sensor, the outdoor weather and timer
+func (handler *DeviceHandler) showACStats(ctx context.Context, state *models.DeviceState, now int64) {
+	payload := state.Payload
+	payload["inside_temp"] = nil
+	payload["outside_temp"] = nil
+
+	homeDevice, err := handler.Homes.GetDeviceHome(ctx, state.DeviceID)
+	if err != nil || homeDevice == nil {
+		slog.Warn("failed to fetch the AC's home", "device_id", state.DeviceID, "error", err)
+		homeDevice = &models.HomeDevice{DeviceID: state.DeviceID}
+	}
 
-	tempState, err := handler.StateStore.GetStateByID(ctx, "temp-sensor-01")  //temp sensor name may be changed
-	if err == nil && tempState != nil {
-		if val, ok := tempState.Payload["temp"].(float64);
+	// the linked sensor, or one in the AC's room, null when there is none
+	homeStates, err := handler.homeStates(ctx, homeDevice.HomeID)
+	if err != nil {
+		slog.Warn("failed to fetch home devices for AC stats", "device_id", state.DeviceID, "error", err)
+	}
+	if sensor := devices.TempSensorFor(*state, homeStates); sensor != nil {
+		if val, ok := sensor.Payload["temp"].(float64);
         ok {
-			insideTemp = val
+			payload["inside_temp"] = val
+			payload["inside_temp_sensor"] = sensor.DeviceID
 		}
 	}
-	payload["inside_temp"] = insideTemp
-	
-	payload["outside_temp"] = 36.0 // demo for now, api fetch later
+
+	// null when the home has no location or the weather can't be fetched
+	if report := handler.homeWeather(ctx, homeDevice.HomeID); report != nil {
+		payload["outside_temp"] = report.Temperature
+		payload["outside_humidity"] = report.Humidity
+		payload["forecast"] = report.Forecast
+	}
 
 	// calculate remaining timer time in manual mode
 	if timeremaining, ok := payload["timer_end_timestamp"].(float64); 
@@ -166,6 +205,9 @@ func (handler *DeviceHandler) showACStats(ctx context.Context, payload map[strin
 // handling GET /devices/:id
 func (handler *DeviceHandler) GetDeviceByID(context *gin.Context) {
     deviceID := context.Param("id")
+    if !authorizeDevice(context, handler.Homes, deviceID) {
+        return
+    }
 
     state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
     if err != nil {
@@ -177,8 +219,12 @@ func (handler *DeviceHandler) GetDeviceByID(context *gin.Context) {
         context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
         return
     }
+    location, ok := deviceZone(context, handler.Homes, deviceID)
+    if !ok {
+        return
+    }
 
-    state.Status = devices.ConnectionStatus(state.LastSeenAt)
+    state.Status = devices.LiveStatus(*state)
     if state.Type == "light-sensor" {
         addLightStatus(state.Payload, state.OperationalState)
     }
@@ -189,7 +235,7 @@ func (handler *DeviceHandler) GetDeviceByID(context *gin.Context) {
 		if dbErr != nil {
 			slog.Warn("failed to fetch recent door history", "device_id", deviceID, "error", dbErr)
 		}
-		showDoorStats(state.Payload, recentHistory, now)
+		showDoorStats(state.Payload, recentHistory, now, location)
 	}
     if state.Type == "ac-actuator" {
 		now := time.Now().Unix()
@@ -197,10 +243,10 @@ func (handler *DeviceHandler) GetDeviceByID(context *gin.Context) {
 		if dbErr != nil {
 			slog.Warn("failed to fetch recent AC history", "device_id", deviceID, "error", dbErr)
 		} else if len(recentHistory) > 0 {
-			state.Payload["recent_events"] = telemetry.FormatACEvents(recentHistory)
+			state.Payload["recent_events"] = telemetry.FormatACEvents(recentHistory, location)
 		}
 	
-		handler.showACStats(context.Request.Context(), state.Payload, now)
+		handler.showACStats(context.Request.Context(), state, now)
 	}
 	if state.Type == "temp-sensor" {
 		now := time.Now().Unix()
@@ -229,11 +275,28 @@ func (handler *DeviceHandler) GetDeviceByID(context *gin.Context) {
     context.JSON(http.StatusOK, state)
 }
 
-// handling GET /devices/:id/telemetry?period=...&metric=...
+// handling GET /devices/:id/telemetry?period=...&metric=a,b,...
+// one metric answers its chart in data, several answer aligned series read from one history
 func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
     deviceID := context.Param("id")
+    if !authorizeDevice(context, handler.Homes, deviceID) {
+        return
+    }
     period := context.DefaultQuery("period", "24h")
-    metric := context.DefaultQuery("metric", "temp")
+    metrics := metricList(context.DefaultQuery("metric", "temp"))
+    if len(metrics) == 0 {
+        context.JSON(http.StatusBadRequest, gin.H{"error": "metric must name at least one metric"})
+        return
+    }
+    if len(metrics) > maxChartMetrics {
+        context.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d metrics can be charted at once", maxChartMetrics)})
+        return
+    }
+    metric := metrics[0]
+    sampling, ok := queryDownsampling(context)
+    if !ok {
+        return
+    }
 
     now := time.Now().Unix()
     state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
@@ -245,10 +308,18 @@ func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
         context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
         return
     }
+    location, ok := deviceZone(context, handler.Homes, deviceID)
+    if !ok {
+        return
+    }
 
     response := gin.H{
         "device_id": deviceID,
         "period":    period,
+        "time_zone": location.String(),
+    }
+    if len(metrics) > 1 {
+        response["metrics"] = metrics
     }
 
     if isHotTier(period) {
@@ -262,7 +333,16 @@ func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
         }
 
         response["source"] = "DynamoDB"
-        response["data"] = telemetry.FilterTime(rawData, metric, period, now)
+        if len(metrics) > 1 {
+            response["series"] = telemetry.BuildSeries(rawData, metrics, period, now, location, sampling)
+        } else if sampling != nil {
+            response["data"] = telemetry.DownsampleTime(rawData, metric, period, now, location, *sampling)
+        } else {
+            response["data"] = telemetry.FilterTime(rawData, metric, period, now, location)
+        }
+        if sampling != nil {
+            response["downsample"] = sampling
+        }
 
        
         if state.Type == "door-actuator" {
@@ -271,7 +351,7 @@ func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
         if state.Type == "ac-actuator" {
 			if period == "7d" { 
 				// The Usage Bar Chart
-				response["usage_bar"] = telemetry.CalculateACUsage(rawData, now, period)
+				response["usage_bar"] = telemetry.CalculateACUsage(rawData, now, period, location)
 			}
 			
 			if period == "24h" {
@@ -282,16 +362,39 @@ func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
 
     } else {
         response["source"] = "S3 processed data"
-		if period == "1m" {
-            currentMonth := time.Now().Format("2006-01")
-            s3Key := fmt.Sprintf("processed-charts/%s/%s.json", deviceID, currentMonth)
-            s3Data, err := handler.S3Fetcher.GetMonthlyChart(context.Request.Context(), s3Key)  //download json file from s3        
-            if err != nil {
-                slog.Warn("failed to fetch monthly S3 chart", "device_id", deviceID, "error", err)
-                response["data"] = []telemetry.ChartPoint{} //to not cause app crash return an empty array
+        months, monthsErr := chartMonths(context, period, time.Unix(now, 0).In(location))
+        if monthsErr != nil {
+            context.JSON(http.StatusBadRequest, gin.H{"error": monthsErr.Error()})
+            return
+        }
+		if len(months) > 0 {
+            response["from"] = months[0].Format("2006-01")
+            response["to"] = months[len(months)-1].Format("2006-01")
+            fetchCharts := handler.S3Fetcher.GetCharts
+            if period == "1y" {
+                fetchCharts = handler.S3Fetcher.GetYearlyCharts // one point per month
+            }
+            if len(metrics) > 1 {
+                series, missing, err := chartSeries(context.Request.Context(), fetchCharts, deviceID, metrics, months)
+                if err != nil {
+                    slog.Warn("failed to fetch monthly S3 charts", "device_id", deviceID, "error", err)
+                    response["series"] = []telemetry.Series{}
+                } else {
+                    response["series"] = series
+                    response["missing_months"] = missing
+                }
             } else {
-                response["data"] = s3Data // The pre-calculated array from Python!
+                s3Data, missing, err := fetchCharts(context.Request.Context(), deviceID, metric, months)  //download the json files from s3
+                if err != nil {
+                    slog.Warn("failed to fetch monthly S3 charts", "device_id", deviceID, "error", err)
+                    response["data"] = []telemetry.ChartPoint{} //to not cause app crash return an empty array
+                } else {
+                    response["data"] = s3Data // The pre-calculated arrays from the processing job
+                    response["missing_months"] = missing
+                }
             }
+        } else if len(metrics) > 1 {
+             response["series"] = []telemetry.Series{}
         } else {
              response["data"] = []telemetry.ChartPoint{}
         }
@@ -303,6 +406,9 @@ func (handler *DeviceHandler) GetDeviceTelemetry(context *gin.Context) {
 
 func (handler *DeviceHandler) GetDeviceAlerts(context *gin.Context) {
     deviceID := context.Param("id")
+    if !authorizeDevice(context, handler.Homes, deviceID) {
+        return
+    }
 
     state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
     if err != nil {
@@ -314,13 +420,82 @@ func (handler *DeviceHandler) GetDeviceAlerts(context *gin.Context) {
         return
     }
 
-    alertList, err := handler.AlertStore.GetAlertsByDevice(context.Request.Context(), deviceID, 0)
+    // optional ?status=OPEN|ACKNOWLEDGED|RESOLVED
+    status := context.Query("status")
+    var alertList []models.Alert
+    switch {
+    case status == "":
+        alertList, err = handler.AlertStore.GetAlertsByDevice(context.Request.Context(), deviceID, 0)
+    case alerts.IsAlertStatus(status):
+        alertList, err = handler.AlertStore.GetAlertsByDeviceStatus(context.Request.Context(), deviceID, []string{status}, 20)
+    default:
+        context.JSON(http.StatusBadRequest, gin.H{"error": "status must be OPEN, ACKNOWLEDGED or RESOLVED"})
+        return
+    }
     if err != nil {
         context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
         return
     }
     context.JSON(http.StatusOK, gin.H{"data": alertList})
 }
+// the months a cold tier period covers in the zone of now, oldest first and never past the current month:
+// 1m takes month=YYYY-MM (this month when unset), 1y takes year=YYYY (the last 12 months
+// when unset) and range takes from=YYYY-MM&to=YYYY-MM
+func chartMonths(context *gin.Context, period string, now time.Time) ([]time.Time, error) {
+    current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+    var from, to time.Time
+
+    switch period {
+    case "1m":
+        from = current
+        if raw := context.Query("month"); raw != "" {
+            month, err := time.ParseInLocation("2006-01", raw, now.Location())
+            if err != nil {
+                return nil, fmt.Errorf("month must be YYYY-MM")
+            }
+            from = month
+        }
+        to = from
+    case "1y":
+        from, to = current.AddDate(0, -11, 0), current
+        if raw := context.Query("year"); raw != "" {
+            year, err := time.ParseInLocation("2006", raw, now.Location())
+            if err != nil {
+                return nil, fmt.Errorf("year must be YYYY")
+            }
+            from, to = year, year.AddDate(0, 11, 0)
+        }
+    case "range":
+        var fromErr, toErr error
+        from, fromErr = time.ParseInLocation("2006-01", context.Query("from"), now.Location())
+        to, toErr = time.ParseInLocation("2006-01", context.Query("to"), now.Location())
+        if fromErr != nil || toErr != nil {
+            return nil, fmt.Errorf("from and to must be YYYY-MM")
+        }
+        if to.Before(from) {
+            return nil, fmt.Errorf("from must not be after to")
+        }
+    default:
+        return nil, nil
+    }
+
+    if to.After(current) {
+        to = current
+    }
+    if from.After(to) {
+        return nil, fmt.Errorf("no processed charts exist for future months")
+    }
+
+    var months []time.Time
+    for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+        months = append(months, month)
+    }
+    if len(months) > maxChartMonths {
+        return nil, fmt.Errorf("a range covers at most %d months", maxChartMonths)
+    }
+    return months, nil
+}
+
 func isHotTier(period string) bool {
     switch period {
     case  "24h", "7d":
@@ -336,8 +511,16 @@ func (handler *DeviceHandler) GetSystemOverview(context *gin.Context) {
 	now := time.Now().Unix()
 	cutoff := telemetry.PeriodCutoff(now, timeFilter)
 
+	deviceIDs, ok := callerDevices(context, handler.Homes)
+	if !ok {
+		return
+	}
+	location, ok := userZone(context, handler.Homes)
+	if !ok {
+		return
+	}
 	
-	states, err := handler.StateStore.GetAllStates(context.Request.Context())
+	states, err := visibleStates(context.Request.Context(), handler.StateStore, deviceIDs)
 	if err != nil {
 		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch device states"})
 		return
@@ -345,7 +528,7 @@ func (handler *DeviceHandler) GetSystemOverview(context *gin.Context) {
 
 	onlineCount := 0
 	for _, state := range states {   //count how many online devices
-		if devices.ConnectionStatus(state.LastSeenAt) == "ONLINE" {
+		if devices.ConnectionStatus(state.Type, state.LastSeenAt) == "ONLINE" {
 			onlineCount++
 		}
 	}
@@ -354,21 +537,35 @@ func (handler *DeviceHandler) GetSystemOverview(context *gin.Context) {
 	if onlineCount > 0 {
 		systemStatus = "Connected"
 	}
-    //get alerts
-	alertsList, err := handler.AlertStore.GetAllAlerts(context.Request.Context(), cutoff)
-	if err != nil {
-		slog.Warn("Failed to get alerts for system overview", "error", err)
+    //get alerts, read per device like ListAlerts
+	var alertsList []models.Alert
+	for deviceID := range deviceIDs {
+		deviceAlerts, err := handler.AlertStore.GetAlertsByDeviceSince(context.Request.Context(), deviceID, cutoff)
+		if err != nil {
+			slog.Warn("Failed to get alerts for system overview", "device_id", deviceID, "error", err)
+			continue
+		}
+		alertsList = append(alertsList, deviceAlerts...)
 	}
-	alertsChart := telemetry.GetAlerts(alertsList, timeFilter)
+	alertsChart := telemetry.GetAlerts(alertsList, timeFilter, location)
 
-    //calculate Energy Consumption
-	acData, err := handler.TelemetryStore.GetTelemetryHistory(context.Request.Context(), "ac-01", 0, cutoff)  //ac name may be changed later
-	if err != nil {
-		slog.Warn("Failed to fetch AC telemetry for energy chart", "error", err)
+    //calculate Energy Consumption of every device drawing power
+	var usages []telemetry.DeviceUsage
+	for _, state := range states {
+		if !devices.ConsumesEnergy(state.Type) {
+			continue
+		}
+		history, err := handler.TelemetryStore.GetTelemetryHistory(context.Request.Context(), state.DeviceID, 0, cutoff)
+		if err != nil {
+			slog.Warn("Failed to fetch telemetry for energy chart", "device_id", state.DeviceID, "error", err)
+			continue
+		}
+		usages = append(usages, telemetry.DeviceUsage{
+			Usage:   telemetry.CalculateACUsage(history, now, timeFilter, location),
+			PowerKW: devices.Rules[state.Type].RatedPowerKW,
+		})
 	}
-	
-	acUsage := telemetry.CalculateACUsage(acData, now, timeFilter)
-	energyData := telemetry.CalculateEnergy(acUsage)
+	energyData := telemetry.CalculateEnergy(usages)
 
 
 	context.JSON(http.StatusOK, gin.H{
@@ -384,6 +581,9 @@ func (handler *DeviceHandler) GetSystemOverview(context *gin.Context) {
 //handling POST /devices/:id/commands
 func (handler *DeviceHandler) SendCommand(context *gin.Context) {
 	deviceID := context.Param("id")
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return
+	}
 
 	var req SendCommandRequest
 	if err := context.ShouldBindJSON(&req); err != nil {
@@ -391,35 +591,386 @@ func (handler *DeviceHandler) SendCommand(context *gin.Context) {
 		return
 	}
 
-	requestID := fmt.Sprintf("cmd-%d", time.Now().UnixNano())
-	mqttPayload := map[string]interface{}{
-		"request_id": requestID,
-		"action":     req.Action,
-		"parameters": req.Parameters,
-	}
-
-	topic := fmt.Sprintf("devices/%s/command", deviceID)
-	err := handler.IoTPublisher.Publish(context.Request.Context(), topic, mqttPayload)
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
 	if err != nil {
-		slog.Error("failed to publish command to iot Core", "device_id", deviceID, "error", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to communicate with device"})
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if state.Status == devices.StatusDecommissioned {
+		context.JSON(http.StatusConflict, gin.H{"error": "Device is decommissioned"})
+		return
+	}
+	if _, ok := authorizeCommand(context, handler.Homes, handler.Audit, deviceID, state.Type, req.Action); !ok {
 		return
 	}
 
-	commandRecord := models.Command{
-		RequestID:  requestID,
+	cmd, err := handler.Dispatcher.Dispatch(context.Request.Context(), commands.Request{
 		DeviceID:   deviceID,
-		Timestamp:  time.Now().Unix(),
+		DeviceType: state.Type,
 		Action:     req.Action,
 		Parameters: req.Parameters,
-	}
-	
-	if storeErr := handler.CommandStore.SaveCommand(context.Request.Context(), commandRecord); storeErr != nil {
-		slog.Warn("Command sent, but failed to save history to DB", "error", storeErr)
+		IssuedBy:   auth.UserID(context),
+	})
+	if err != nil {
+		// reject anything the device type doesn't understand before it reaches MQTT
+		var validationErr *devices.CommandValidationError
+		if errors.As(err, &validationErr) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr})
+			return
+		}
+		slog.Error("failed to publish command to iot Core", "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to communicate with device"})
+		return
 	}
 
 	context.JSON(http.StatusAccepted, gin.H{
 		"message":    "Command dispatched successfully",
-		"request_id": requestID,
+		"request_id": cmd.RequestID,
+		"status":     cmd.Status,
+	})
+}
+
+//handling GET /commands/:request_id
+func (handler *DeviceHandler) GetCommand(context *gin.Context) {
+	requestID := context.Param("request_id")
+
+	cmd, err := handler.CommandStore.GetCommand(context.Request.Context(), requestID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch command"})
+		return
+	}
+	if cmd == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		return
+	}
+	if !homesAllow(context, handler.Homes, cmd.DeviceID, "Command not found") {
+		return
+	}
+
+	context.JSON(http.StatusOK, commands.ResolveStatus(*cmd, time.Now().Unix()))
+}
+
+//handling GET /devices/:id/commands?from=...&to=...&limit=...&cursor=...
+func (handler *DeviceHandler) GetDeviceCommands(context *gin.Context) {
+	deviceID := context.Param("id")
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return
+	}
+
+	query := commands.HistoryQuery{
+		DeviceID: deviceID,
+		Cursor:   context.Query("cursor"),
+	}
+
+	var err error
+	if query.From, err = queryInt(context, "from"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "from must be a unix timestamp"})
+		return
+	}
+	if query.To, err = queryInt(context, "to"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "to must be a unix timestamp"})
+		return
+	}
+	limit, err := queryInt(context, "limit")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+	query.Limit = int32(limit)
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	page, err := handler.CommandStore.GetCommandsByDevice(context.Request.Context(), query)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch commands"})
+		return
+	}
+
+	now := time.Now().Unix()
+	for i := range page.Commands {
+		page.Commands[i] = commands.ResolveStatus(page.Commands[i], now)
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"data":        page.Commands,
+		"next_cursor": page.NextCursor,
 	})
-}
\ No newline at end of file
+}
+
+//handling GET /devices/:id/telemetry/aggregate?from=...&to=...&bucket=1m|5m|1h|1d&metric=...
+func (handler *DeviceHandler) GetDeviceTelemetryAggregate(context *gin.Context) {
+	deviceID := context.Param("id")
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return
+	}
+	metric := context.DefaultQuery("metric", "temp")
+
+	bucketSize, err := telemetry.ParseBucket(context.DefaultQuery("bucket", "1h"))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the last 24h when unset
+	to, err := queryInt(context, "to")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "to must be a unix timestamp"})
+		return
+	}
+	if to == 0 {
+		to = time.Now().Unix()
+	}
+	from, err := queryInt(context, "from")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "from must be a unix timestamp"})
+		return
+	}
+	if from == 0 {
+		from = to - 86400
+	}
+	if from > to {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	// raw readings expire, older ranges are only in the month and year charts
+	if from < time.Now().Add(-telemetry.TelemetryTTL).Unix() {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "readings are only kept 7 days, use the month or year charts (period=1m|1y|range) for older data"})
+		return
+	}
+	location, ok := deviceZone(context, handler.Homes, deviceID)
+	if !ok {
+		return
+	}
+	step := int64(bucketSize.Seconds())
+	if (to-telemetry.BucketStart(from, bucketSize, location))/step+1 > telemetry.MaxBuckets {
+		context.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the range holds more than %d buckets, use a bigger bucket", telemetry.MaxBuckets)})
+		return
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	history, err := telemetry.ReadRange(context.Request.Context(), handler.TelemetryStore, deviceID, metric, from, to)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch telemetry"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"device_id": deviceID,
+		"metric":    metric,
+		"bucket":    context.DefaultQuery("bucket", "1h"),
+		"from":      from,
+		"to":        to,
+		"time_zone": location.String(),
+		"data":      telemetry.Aggregate(history, metric, from, to, bucketSize, location),
+	})
+}
+
+//handling GET /devices/:id/telemetry/raw?from=...&to=...&metric=a,b&order=asc|desc&limit=...&cursor=...
+func (handler *DeviceHandler) GetDeviceTelemetryRaw(context *gin.Context) {
+	deviceID := context.Param("id")
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return
+	}
+
+	query := telemetry.HistoryQuery{
+		DeviceID: deviceID,
+		Cursor:   context.Query("cursor"),
+	}
+
+	var err error
+	if query.From, err = queryInt(context, "from"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "from must be a unix timestamp"})
+		return
+	}
+	if query.To, err = queryInt(context, "to"); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "to must be a unix timestamp"})
+		return
+	}
+	limit, err := queryInt(context, "limit")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+		return
+	}
+	query.Limit = int32(limit)
+
+	switch context.DefaultQuery("order", "desc") {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+	default:
+		context.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+
+	query.Metrics = metricList(context.Query("metric"))
+	sampling, ok := queryDownsampling(context)
+	if !ok {
+		return
+	}
+	if sampling != nil && len(query.Metrics) != 1 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "downsample needs exactly one metric"})
+		return
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	page, err := handler.TelemetryStore.QueryTelemetry(context.Request.Context(), query)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch telemetry"})
+		return
+	}
+
+	response := gin.H{
+		"data":        page.Readings,
+		"next_cursor": page.NextCursor,
+	}
+	if sampling != nil {
+		response["data"] = telemetry.DownsampleReadings(page.Readings, query.Metrics[0], *sampling)
+		response["downsample"] = sampling
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// the active devices of the home
+func (handler *DeviceHandler) homeStates(ctx context.Context, homeID string) ([]models.DeviceState, error) {
+	if homeID == "" {
+		return nil, nil
+	}
+	deviceList, err := handler.Homes.GetHomeDevices(ctx, homeID)
+	if err != nil {
+		return nil, err
+	}
+	deviceIDs := make(map[string]bool, len(deviceList))
+	for _, device := range deviceList {
+		deviceIDs[device.DeviceID] = true
+	}
+
+	return visibleStates(ctx, handler.StateStore, deviceIDs)
+}
+
+func (handler *DeviceHandler) homeWeather(ctx context.Context, homeID string) *models.Weather {
+	if handler.Weather == nil || homeID == "" {
+		return nil
+	}
+	report, err := weather.AtHome(ctx, handler.Weather, handler.Homes, homeID)
+	if err != nil {
+		if !errors.Is(err, weather.ErrNoLocation) {
+			slog.Warn("failed to fetch outdoor weather", "home_id", homeID, "error", err)
+		}
+		return nil
+	}
+	return report
+}
+
+// the states of the devices, read by id instead of scanning every device, decommissioned ones are left out
+func visibleStates(ctx context.Context, store devices.StateStore, deviceIDs map[string]bool) ([]models.DeviceState, error) {
+	states, err := store.GetStates(ctx, slices.Collect(maps.Keys(deviceIDs)))
+	if err != nil {
+		return nil, err
+	}
+	return ownedStates(states, deviceIDs), nil
+}
+
+// keeps the states of the devices the caller can see, decommissioned ones are left out of lists
+func ownedStates(states []models.DeviceState, deviceIDs map[string]bool) []models.DeviceState {
+	return slices.DeleteFunc(states, func(state models.DeviceState) bool {
+		return !deviceIDs[state.DeviceID] || !devices.Active(&state)
+	})
+}
+
+// the metrics of a comma separated list, in order and without repeats
+func metricList(raw string) []string {
+	var metrics []string
+	for _, metric := range strings.Split(raw, ",") {
+		if metric = strings.TrimSpace(metric); metric != "" && !slices.Contains(metrics, metric) {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// the processed charts of every metric as series, fetched with fetchCharts (monthly or yearly).
+// missing_months of the response lists the months any metric is missing
+func chartSeries(ctx context.Context, fetchCharts func(context.Context, string, string, []time.Time) ([]telemetry.ChartPoint, []string, error), deviceID string, metrics []string, months []time.Time) ([]telemetry.Series, []string, error) {
+	series := make([]telemetry.Series, 0, len(metrics))
+	missingMonths := []string{}
+	for _, metric := range metrics {
+		points, missing, err := fetchCharts(ctx, deviceID, metric, months)
+		if err != nil {
+			return nil, nil, err
+		}
+		series = append(series, telemetry.ChartSeries(metric, points))
+		for _, month := range missing {
+			if !slices.Contains(missingMonths, month) {
+				missingMonths = append(missingMonths, month)
+			}
+		}
+	}
+	slices.Sort(missingMonths)
+	return series, missingMonths, nil
+}
+
+// ?downsample=lttb|minmax&points=N, nil when unset. a bad value answers 400
+func queryDownsampling(context *gin.Context) (*telemetry.Downsampling, bool) {
+	mode := context.Query("downsample")
+	if mode == "" {
+		return nil, true
+	}
+	points, err := queryInt(context, "points")
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "points must be a number"})
+		return nil, false
+	}
+	sampling, err := telemetry.ParseDownsampling(mode, points)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &sampling, true
+}
+
+// reads an optional integer query parameter, missing means 0
+func queryInt(context *gin.Context, name string) (int64, error) {
+	raw := context.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
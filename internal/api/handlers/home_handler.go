@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+type HomeHandler struct {
+	StateStore devices.StateStore
+	Homes      homes.HomeStore
+	Audit      *audit.Recorder
+}
+
+type CreateHomeRequest struct {
+	Name     string           `json:"name" binding:"required"`
+	Location *models.Location `json:"location"`  // optional, needed for the outdoor weather
+	TimeZone string           `json:"time_zone"` // optional IANA zone, UTC when empty
+}
+
+type HomeDeviceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+}
+
+type HomeMemberRequest struct {
+	UserID      string   `json:"user_id" binding:"required"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	ExpiresAt   int64    `json:"expires_at"`
+}
+
+type UpdateMemberRequest struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	ExpiresAt   int64    `json:"expires_at"`
+}
+
+// handling GET /homes, the homes the caller belongs to
+func (handler *HomeHandler) ListHomes(context *gin.Context) {
+	memberships, err := handler.Homes.GetMemberships(context.Request.Context(), auth.UserID(context))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch homes"})
+		return
+	}
+
+	now := time.Now().Unix()
+	homeList := make([]models.Home, 0, len(memberships))
+	for _, membership := range memberships {
+		if !homes.Active(membership, now) {
+			continue
+		}
+		home, err := handler.Homes.GetHome(context.Request.Context(), membership.HomeID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch homes"})
+			return
+		}
+		if home != nil {
+			homeList = append(homeList, *home)
+		}
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": homeList})
+}
+
+// handling POST /homes, the caller becomes its owner
+func (handler *HomeHandler) CreateHome(context *gin.Context) {
+	var req CreateHomeRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid home format! name is required."})
+		return
+	}
+
+	if err := homes.ValidateLocation(req.Location); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := homes.ValidateTimeZone(req.TimeZone); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	home := models.Home{
+		HomeID:    fmt.Sprintf("home-%d", now.UnixNano()),
+		Name:      req.Name,
+		OwnerID:   auth.UserID(context),
+		Location:  req.Location,
+		TimeZone:  req.TimeZone,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+	}
+
+	if err := handler.Homes.SaveHome(context.Request.Context(), home); err != nil {
+		slog.Error("failed to save home", "home_id", home.HomeID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home"})
+		return
+	}
+
+	owner := models.HomeMember{UserID: home.OwnerID, HomeID: home.HomeID, Role: homes.RoleOwner, AddedAt: now.Unix()}
+	if err := handler.Homes.SaveMember(context.Request.Context(), owner); err != nil {
+		slog.Error("failed to save home owner", "home_id", home.HomeID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeCreated,
+		HomeID:   home.HomeID,
+		TargetID: home.HomeID,
+		After:    home,
+	})
+
+	context.JSON(http.StatusCreated, home)
+}
+
+// handling GET /homes/:home_id
+func (handler *HomeHandler) GetHome(context *gin.Context) {
+	home, ok := handler.loadHome(context, false)
+	if !ok {
+		return
+	}
+
+	members, err := handler.Homes.GetMembers(context.Request.Context(), home.HomeID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home members"})
+		return
+	}
+
+	deviceList, err := handler.Homes.GetHomeDevices(context.Request.Context(), home.HomeID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home devices"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"home": home, "members": members, "devices": deviceList})
+}
+
+// handling PUT /homes/:home_id, renames the home or moves it, a missing location or time_zone removes it
+func (handler *HomeHandler) UpdateHome(context *gin.Context) {
+	home, ok := handler.loadHome(context, true)
+	if !ok {
+		return
+	}
+
+	var req CreateHomeRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid home format! name is required."})
+		return
+	}
+	if err := homes.ValidateLocation(req.Location); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err := homes.ValidateTimeZone(req.TimeZone); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := *home
+	home.Name = req.Name
+	home.Location = req.Location
+	home.TimeZone = req.TimeZone
+	home.UpdatedAt = time.Now().Unix()
+	if err := handler.Homes.SaveHome(context.Request.Context(), *home); err != nil {
+		slog.Error("failed to save home", "home_id", home.HomeID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeUpdated,
+		HomeID:   home.HomeID,
+		TargetID: home.HomeID,
+		Before:   before,
+		After:    home,
+	})
+
+	context.JSON(http.StatusOK, home)
+}
+
+// handling POST /homes/:home_id/devices
+func (handler *HomeHandler) AddDevice(context *gin.Context) {
+	var req HomeDeviceRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid device format! device_id is required."})
+		return
+	}
+
+	home, ok := handler.loadHome(context, true)
+	if !ok {
+		return
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), req.DeviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !devices.Active(state) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	device := models.HomeDevice{DeviceID: req.DeviceID, HomeID: home.HomeID, AddedAt: time.Now().Unix()}
+	claimed, err := handler.Homes.ClaimDevice(context.Request.Context(), device)
+	if err != nil {
+		slog.Error("failed to add device to home", "home_id", home.HomeID, "device_id", req.DeviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add device"})
+		return
+	}
+	if !claimed {
+		context.JSON(http.StatusConflict, gin.H{"error": "Device already belongs to another home"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeDeviceAdded,
+		HomeID:   home.HomeID,
+		DeviceID: device.DeviceID,
+		After:    device,
+	})
+
+	context.JSON(http.StatusCreated, device)
+}
+
+// handling DELETE /homes/:home_id/devices/:device_id
+func (handler *HomeHandler) RemoveDevice(context *gin.Context) {
+	home, ok := handler.loadHome(context, true)
+	if !ok {
+		return
+	}
+
+	deviceID := context.Param("device_id")
+	if err := handler.Homes.ReleaseDevice(context.Request.Context(), home.HomeID, deviceID); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove device"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeDeviceRemoved,
+		HomeID:   home.HomeID,
+		DeviceID: deviceID,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+// handling POST /homes/:home_id/members
+func (handler *HomeHandler) AddMember(context *gin.Context) {
+	var req HomeMemberRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid member format! user_id is required."})
+		return
+	}
+
+	home, ok := handler.loadHome(context, true)
+	if !ok {
+		return
+	}
+	if req.UserID == home.OwnerID {
+		context.JSON(http.StatusConflict, gin.H{"error": "The owner's role can't be changed"})
+		return
+	}
+
+	now := time.Now()
+	member := models.HomeMember{
+		UserID:      req.UserID,
+		HomeID:      home.HomeID,
+		Role:        req.Role,
+		Permissions: req.Permissions,
+		ExpiresAt:   req.ExpiresAt,
+		AddedAt:     now.Unix(),
+		UpdatedAt:   now.Unix(),
+	}
+	if !handler.saveMember(context, &member, now) {
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeMemberAdded,
+		HomeID:   home.HomeID,
+		TargetID: member.UserID,
+		After:    member,
+	})
+
+	context.JSON(http.StatusCreated, member)
+}
+
+// handling PUT /homes/:home_id/members/:user_id, changes the role, permissions or guest expiry
+func (handler *HomeHandler) UpdateMember(context *gin.Context) {
+	var req UpdateMemberRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid member format!"})
+		return
+	}
+
+	home, ok := handler.loadHome(context, true)
+	if !ok {
+		return
+	}
+	userID := context.Param("user_id")
+	if userID == home.OwnerID {
+		context.JSON(http.StatusConflict, gin.H{"error": "The owner's role can't be changed"})
+		return
+	}
+
+	member, err := handler.Homes.GetMember(context.Request.Context(), home.HomeID, userID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home member"})
+		return
+	}
+	if member == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	before := *member
+	now := time.Now()
+	member.Role = req.Role
+	member.Permissions = req.Permissions
+	member.ExpiresAt = req.ExpiresAt
+	member.UpdatedAt = now.Unix()
+	if !handler.saveMember(context, member, now) {
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeMemberUpdated,
+		HomeID:   home.HomeID,
+		TargetID: member.UserID,
+		Before:   before,
+		After:    member,
+	})
+
+	context.JSON(http.StatusOK, member)
+}
+
+// handling DELETE /homes/:home_id/members/:user_id, the owner removes (or revokes a guest) anyone else, members can leave
+func (handler *HomeHandler) RemoveMember(context *gin.Context) {
+	userID := context.Param("user_id")
+	leaving := userID == auth.UserID(context)
+
+	home, ok := handler.loadHome(context, !leaving)
+	if !ok {
+		return
+	}
+	if userID == home.OwnerID {
+		context.JSON(http.StatusConflict, gin.H{"error": "The owner can't be removed from the home"})
+		return
+	}
+
+	if err := handler.Homes.DeleteMember(context.Request.Context(), home.HomeID, userID); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove home member"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionHomeMemberRemoved,
+		HomeID:   home.HomeID,
+		TargetID: userID,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+// validates the role and permissions then saves, writes the 4xx itself
+func (handler *HomeHandler) saveMember(context *gin.Context, member *models.HomeMember, now time.Time) bool {
+	if err := homes.PrepareMember(member, now); err != nil {
+		if errors.Is(err, homes.ErrInvalidMember) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return false
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	if err := handler.Homes.SaveMember(context.Request.Context(), *member); err != nil {
+		slog.Error("failed to save home member", "home_id", member.HomeID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save home member"})
+		return false
+	}
+	return true
+}
+
+// members only, homes of others (or an expired guest pass) answer 404. ownerOnly answers 403 to the other members
+func (handler *HomeHandler) loadHome(context *gin.Context, ownerOnly bool) (*models.Home, bool) {
+	homeID := context.Param("home_id")
+	userID := auth.UserID(context)
+
+	member, err := homes.ActiveMember(context.Request.Context(), handler.Homes, homeID, userID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home"})
+		return nil, false
+	}
+	home, err := handler.Homes.GetHome(context.Request.Context(), homeID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch home"})
+		return nil, false
+	}
+	if member == nil || home == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Home not found"})
+		return nil, false
+	}
+	if ownerOnly && home.OwnerID != userID {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only the home owner can change it"})
+		return nil, false
+	}
+	return home, true
+}
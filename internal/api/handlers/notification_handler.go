@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/notify"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	Tokens      notify.TokenStore
+	Preferences notify.PreferenceStore
+}
+
+type PushTokenRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Provider string `json:"provider" binding:"required"`
+	Platform string `json:"platform"`
+}
+
+type PreferencesRequest struct {
+	MinSeverity string             `json:"min_severity"`
+	QuietHours  *models.QuietHours `json:"quiet_hours"`
+}
+
+// handling GET /users/:user_id/push-tokens
+func (handler *NotificationHandler) ListPushTokens(context *gin.Context) {
+	if !sameUser(context) {
+		return
+	}
+
+	tokenList, err := handler.Tokens.GetTokens(context.Request.Context(), context.Param("user_id"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch push tokens"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": tokenList})
+}
+
+// handling POST /users/:user_id/push-tokens, registering the same token again just refreshes it
+func (handler *NotificationHandler) RegisterPushToken(context *gin.Context) {
+	if !sameUser(context) {
+		return
+	}
+
+	var req PushTokenRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid push token format! token and provider are required."})
+		return
+	}
+
+	if req.Provider != notify.ProviderFCM && req.Provider != notify.ProviderWebhook {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": "provider must be fcm or webhook"})
+		return
+	}
+
+	now := time.Now().Unix()
+	token := models.PushToken{
+		UserID:    context.Param("user_id"),
+		Token:     req.Token,
+		Provider:  req.Provider,
+		Platform:  req.Platform,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := handler.Tokens.SaveToken(context.Request.Context(), token); err != nil {
+		slog.Error("failed to save push token", "user_id", token.UserID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save push token"})
+		return
+	}
+
+	context.JSON(http.StatusCreated, token)
+}
+
+// handling DELETE /users/:user_id/push-tokens/:token
+func (handler *NotificationHandler) DeletePushToken(context *gin.Context) {
+	if !sameUser(context) {
+		return
+	}
+
+	if err := handler.Tokens.DeleteToken(context.Request.Context(), context.Param("user_id"), context.Param("token")); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete push token"})
+		return
+	}
+
+	context.Status(http.StatusNoContent)
+}
+
+// handling GET /users/:user_id/notification-preferences, defaults when nothing was saved
+func (handler *NotificationHandler) GetPreferences(context *gin.Context) {
+	if !sameUser(context) {
+		return
+	}
+
+	userID := context.Param("user_id")
+
+	preferences, err := handler.Preferences.GetPreferences(context.Request.Context(), userID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+	if preferences == nil {
+		defaults := notify.DefaultPreferences(userID)
+		preferences = &defaults
+	}
+
+	context.JSON(http.StatusOK, preferences)
+}
+
+// handling PUT /users/:user_id/notification-preferences
+func (handler *NotificationHandler) UpdatePreferences(context *gin.Context) {
+	if !sameUser(context) {
+		return
+	}
+
+	var req PreferencesRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification preferences format"})
+		return
+	}
+
+	preferences := models.NotificationPreferences{
+		UserID:      context.Param("user_id"),
+		MinSeverity: req.MinSeverity,
+		QuietHours:  req.QuietHours,
+		UpdatedAt:   time.Now().Unix(),
+	}
+	if err := notify.PreparePreferences(&preferences); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := handler.Preferences.SavePreferences(context.Request.Context(), preferences); err != nil {
+		slog.Error("failed to save notification preferences", "user_id", preferences.UserID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification preferences"})
+		return
+	}
+
+	context.JSON(http.StatusOK, preferences)
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/rooms"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/schedules"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RegistryHandler provisions, edits and retires devices, all of it is for the home owner
+type RegistryHandler struct {
+	Registry       devices.RegistryStore
+	StateStore     devices.StateStore
+	TelemetryStore telemetry.TelemetryStore
+	AlertStore     alerts.AlertStore
+	CommandStore   commands.CommandStore
+	Schedules      schedules.ScheduleStore
+	Rules          automation.RuleStore
+	Thresholds     alerts.ThresholdStore
+	Quarantine     devices.QuarantineStore // optional, registering clears the device's quarantine entry
+	Homes          homes.HomeStore
+	Rooms          rooms.RoomStore
+	Audit          *audit.Recorder
+}
+
+type RegisterDeviceRequest struct {
+	DeviceID    string            `json:"device_id" binding:"required"`
+	HomeID      string            `json:"home_id" binding:"required"`
+	Type        string            `json:"type" binding:"required"`
+	Name        string            `json:"name"`
+	RoomID      string            `json:"room_id"`
+	Links       map[string]string `json:"links"`
+	Firmware    string            `json:"firmware"`
+	InstalledAt int64             `json:"installed_at"`
+}
+
+// only the fields sent are changed, the type is fixed at registration
+type UpdateDeviceRequest struct {
+	Type        string            `json:"type"`
+	Name        *string           `json:"name"`
+	RoomID      *string           `json:"room_id"` // "" takes the device out of its room
+	Links       map[string]string `json:"links"`   // replaces every link, {} removes them
+	Firmware    *string           `json:"firmware"`
+	InstalledAt *int64            `json:"installed_at"`
+}
+
+// handling POST /devices, registers the device and adds it to the caller's home
+func (handler *RegistryHandler) RegisterDevice(context *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid device format! device_id, home_id and type are required."})
+		return
+	}
+
+	home, ok := requireHomeOwner(context, handler.Homes, req.HomeID, "Only the home owner can register devices")
+	if !ok {
+		return
+	}
+
+	existing, err := handler.StateStore.GetStateByID(context.Request.Context(), req.DeviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	owner, err := handler.Homes.GetDeviceHome(context.Request.Context(), req.DeviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if owner != nil && owner.HomeID != home.HomeID {
+		context.JSON(http.StatusConflict, gin.H{"error": "Device already belongs to another home"})
+		return
+	}
+
+	now := time.Now()
+	state := models.DeviceState{
+		DeviceID:    req.DeviceID,
+		Type:        req.Type,
+		Name:        req.Name,
+		RoomID:      req.RoomID,
+		Links:       req.Links,
+		Firmware:    req.Firmware,
+		InstalledAt: req.InstalledAt,
+	}
+	err = devices.PrepareRegistration(&state, existing, now)
+	if err == nil {
+		err = handler.validateRoom(context, home.HomeID, state.RoomID)
+	}
+	if err == nil {
+		err = handler.validateLinks(context, home.HomeID, state)
+	}
+	if err == nil {
+		err = handler.Registry.RegisterDevice(context.Request.Context(), state)
+	}
+	switch {
+	case errors.Is(err, devices.ErrInvalidDevice), errors.Is(err, errRoomNotInHome):
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, devices.ErrDeviceExists):
+		context.JSON(http.StatusConflict, gin.H{"error": "Device already registered"})
+		return
+	case err != nil:
+		slog.Error("failed to register device", "device_id", req.DeviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	if owner == nil {
+		claimed, err := handler.Homes.ClaimDevice(context.Request.Context(), models.HomeDevice{DeviceID: req.DeviceID, HomeID: home.HomeID, AddedAt: now.Unix()})
+		if err != nil {
+			slog.Error("failed to add device to home", "home_id", home.HomeID, "device_id", req.DeviceID, "error", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+			return
+		}
+		if !claimed {
+			context.JSON(http.StatusConflict, gin.H{"error": "Device already belongs to another home"})
+			return
+		}
+
+		// only when joining a home, thresholds the owner deleted don't come back on re-registering
+		if _, err := alerts.SeedThresholds(context.Request.Context(), handler.Thresholds, req.DeviceID, state.Type, now.Unix()); err != nil {
+			slog.Warn("failed to save default thresholds", "device_id", req.DeviceID, "error", err)
+		}
+	}
+
+	if handler.Quarantine != nil {
+		if err := handler.Quarantine.ReleaseDevice(context.Request.Context(), req.DeviceID); err != nil {
+			slog.Warn("failed to release quarantined device", "device_id", req.DeviceID, "error", err)
+		}
+	}
+
+	event := models.AuditEvent{
+		Action:   audit.ActionDeviceRegistered,
+		HomeID:   home.HomeID,
+		DeviceID: req.DeviceID,
+		After:    registryInfo(state),
+	}
+	if existing != nil {
+		event.Before = registryInfo(*existing)
+	}
+	recordAudit(context, handler.Audit, event)
+
+	// an adopted device already has a reported state, read it back whole
+	if registered, err := handler.StateStore.GetStateByID(context.Request.Context(), req.DeviceID); err == nil && registered != nil {
+		state = *registered
+	}
+	state.Status = devices.LiveStatus(state)
+	context.JSON(http.StatusCreated, state)
+}
+
+// handling PATCH /devices/:id
+func (handler *RegistryHandler) UpdateDevice(context *gin.Context) {
+	deviceID := context.Param("id")
+	homeDevice, ok := handler.authorizeOwner(context, deviceID)
+	if !ok {
+		return
+	}
+
+	var req UpdateDeviceRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid device format!"})
+		return
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if state.Status == devices.StatusDecommissioned {
+		context.JSON(http.StatusConflict, gin.H{"error": "Device is decommissioned"})
+		return
+	}
+	if req.Type != "" && req.Type != state.Type {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": "type can't be changed, decommission the device and register it again"})
+		return
+	}
+
+	before := registryInfo(*state)
+	if req.Name != nil {
+		state.Name = *req.Name
+	}
+	if req.RoomID != nil {
+		state.RoomID = *req.RoomID
+	}
+	if req.Links != nil {
+		state.Links = req.Links
+	}
+	if req.Firmware != nil {
+		state.Firmware = *req.Firmware
+	}
+	if req.InstalledAt != nil {
+		state.InstalledAt = *req.InstalledAt
+	}
+
+	now := time.Now()
+	err = devices.ValidateInfo(*state, now)
+	if err == nil && req.RoomID != nil {
+		err = handler.validateRoom(context, homeDevice.HomeID, state.RoomID)
+	}
+	if err == nil && req.Links != nil {
+		err = handler.validateLinks(context, homeDevice.HomeID, *state)
+	}
+	if errors.Is(err, devices.ErrInvalidDevice) || errors.Is(err, errRoomNotInHome) {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	state.LastUpdated = now.Unix()
+
+	err = handler.Registry.UpdateDeviceInfo(context.Request.Context(), *state)
+	if errors.Is(err, devices.ErrDeviceNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update device", "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update device"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionDeviceUpdated,
+		DeviceID: deviceID,
+		Before:   before,
+		After:    registryInfo(*state),
+	})
+
+	state.Status = devices.LiveStatus(*state)
+	context.JSON(http.StatusOK, state)
+}
+
+// handling DELETE /devices/:id?purge=true
+// archives the device by default, purge also deletes its state, telemetry and alerts and frees the id
+func (handler *RegistryHandler) DecommissionDevice(context *gin.Context) {
+	deviceID := context.Param("id")
+	homeDevice, ok := handler.authorizeOwner(context, deviceID)
+	if !ok {
+		return
+	}
+
+	purge := false
+	if raw := context.Query("purge"); raw != "" {
+		var err error
+		if purge, err = strconv.ParseBool(raw); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "purge must be true or false"})
+			return
+		}
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	// a purge that failed half way leaves the home link behind, purging again finishes it
+	if state == nil && !purge {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	event := models.AuditEvent{
+		Action:   audit.ActionDeviceDecommissioned,
+		HomeID:   homeDevice.HomeID, // set now, a purge removes the device from its home
+		DeviceID: deviceID,
+		Reason:   "archived",
+	}
+
+	if state != nil {
+		event.Before = registryInfo(*state)
+		// stops ingestion and commands first, so nothing is written while the data is deleted
+		if state.Status != devices.StatusDecommissioned {
+			err = handler.Registry.DecommissionDevice(context.Request.Context(), deviceID, time.Now().Unix())
+			if err != nil && !errors.Is(err, devices.ErrDeviceNotFound) {
+				slog.Error("failed to decommission device", "device_id", deviceID, "error", err)
+				context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decommission device"})
+				return
+			}
+		}
+	}
+
+	if purge {
+		if !handler.purge(context, homeDevice, &event) {
+			return
+		}
+	}
+	recordAudit(context, handler.Audit, event)
+
+	context.Status(http.StatusNoContent)
+}
+
+func (handler *RegistryHandler) purge(context *gin.Context, homeDevice *models.HomeDevice, event *models.AuditEvent) bool {
+	ctx := context.Request.Context()
+	deviceID := homeDevice.DeviceID
+	deleted := gin.H{}
+	event.After = deleted
+
+	// what sends commands to the device goes first, the id is free for another home once released
+	var err error
+	deleted["schedules_deleted"], err = handler.purgeSchedules(context, deviceID)
+	if err == nil {
+		deleted["rules_deleted"], err = handler.purgeRules(context, deviceID)
+	}
+	if err == nil {
+		deleted["thresholds_deleted"], err = handler.Thresholds.DeleteDeviceThresholds(ctx, deviceID)
+	}
+	if err == nil {
+		deleted["commands_deleted"], err = handler.CommandStore.DeleteDeviceCommands(ctx, deviceID)
+	}
+	if err == nil {
+		deleted["telemetry_deleted"], err = handler.TelemetryStore.DeleteDeviceTelemetry(ctx, deviceID)
+	}
+	if err == nil {
+		deleted["alerts_deleted"], err = handler.AlertStore.DeleteDeviceAlerts(ctx, deviceID)
+	}
+	if err == nil {
+		err = handler.Registry.DeleteDevice(ctx, deviceID)
+	}
+	if err == nil {
+		err = handler.Homes.ReleaseDevice(ctx, homeDevice.HomeID, deviceID)
+	}
+	if err != nil {
+		slog.Error("failed to purge device", "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge device"})
+		return false
+	}
+
+	event.Reason = "purged"
+	return true
+}
+
+func (handler *RegistryHandler) purgeSchedules(context *gin.Context, deviceID string) (int, error) {
+	scheduleList, err := handler.Schedules.GetSchedulesByDevice(context.Request.Context(), deviceID)
+	if err != nil {
+		return 0, err
+	}
+	for i, schedule := range scheduleList {
+		if err := handler.Schedules.DeleteSchedule(context.Request.Context(), schedule.ScheduleID); err != nil {
+			return i, err
+		}
+	}
+	return len(scheduleList), nil
+}
+
+// the rules the device triggers or receives the command of
+func (handler *RegistryHandler) purgeRules(context *gin.Context, deviceID string) (int, error) {
+	ruleList, err := handler.Rules.ListRules(context.Request.Context())
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, rule := range ruleList {
+		if rule.Trigger.DeviceID != deviceID && rule.Action.DeviceID != deviceID {
+			continue
+		}
+		if err := handler.Rules.DeleteRule(context.Request.Context(), rule.RuleID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// an empty room_id is no room, any other must be a room of the device's home
+func (handler *RegistryHandler) validateRoom(context *gin.Context, homeID string, roomID string) error {
+	if roomID == "" {
+		return nil
+	}
+	room, err := handler.Rooms.GetRoom(context.Request.Context(), roomID)
+	if err != nil {
+		return err
+	}
+	if room == nil || room.HomeID != homeID {
+		return errRoomNotInHome
+	}
+	return nil
+}
+
+// every linked device must be in the same home
+func (handler *RegistryHandler) validateLinks(context *gin.Context, homeID string, state models.DeviceState) error {
+	targets := make(map[string]*models.DeviceState, len(state.Links))
+	for _, targetID := range state.Links {
+		homeDevice, err := handler.Homes.GetDeviceHome(context.Request.Context(), targetID)
+		if err != nil {
+			return err
+		}
+		if homeDevice == nil || homeDevice.HomeID != homeID {
+			continue
+		}
+		if targets[targetID], err = handler.StateStore.GetStateByID(context.Request.Context(), targetID); err != nil {
+			return err
+		}
+	}
+	return devices.ValidateLinks(state, targets)
+}
+
+// the device must be in one of the caller's homes (404 otherwise) and the caller its owner (403)
+func (handler *RegistryHandler) authorizeOwner(context *gin.Context, deviceID string) (*models.HomeDevice, bool) {
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return nil, false
+	}
+
+	homeDevice, err := handler.Homes.GetDeviceHome(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if homeDevice == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+
+	if _, ok := requireHomeOwner(context, handler.Homes, homeDevice.HomeID, "Only the home owner can manage its devices"); !ok {
+		return nil, false
+	}
+	return homeDevice, true
+}
+
+// what the audit log keeps of a device, its readings stay out
+func registryInfo(state models.DeviceState) gin.H {
+	return gin.H{
+		"type":         state.Type,
+		"status":       state.Status,
+		"name":         state.Name,
+		"room_id":      state.RoomID,
+		"links":        state.Links,
+		"firmware":     state.Firmware,
+		"installed_at": state.InstalledAt,
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/schedules"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type releasingQuarantine struct {
+	released []string
+}
+
+func (quarantine *releasingQuarantine) QuarantineMessage(ctx context.Context, entry models.QuarantinedDevice) error {
+	return nil
+}
+
+func (quarantine *releasingQuarantine) ReleaseDevice(ctx context.Context, deviceID string) error {
+	quarantine.released = append(quarantine.released, deviceID)
+	return nil
+}
+
+type registryTest struct {
+	*testAPI
+	handler    *RegistryHandler
+	states     *devices.MemoryStateStore
+	telemetry  *telemetry.MemoryTelemetryStore
+	alerts     *alerts.MemoryAlertStore
+	commands   *commands.MemoryCommandStore
+	schedules  *schedules.MemoryScheduleStore
+	rules      *automation.MemoryRuleStore
+	thresholds *alerts.MemoryThresholdStore
+	homes      *homes.MemoryHomeStore
+	quarantine *releasingQuarantine
+}
+
+func newRegistryTest(t *testing.T) *registryTest {
+	t.Helper()
+	test := &registryTest{
+		testAPI:    newTestAPI(t),
+		states:     devices.NewMemoryStateStore(),
+		telemetry:  telemetry.NewMemoryTelemetryStore(),
+		alerts:     alerts.NewMemoryAlertStore(),
+		commands:   commands.NewMemoryCommandStore(),
+		schedules:  schedules.NewMemoryScheduleStore(),
+		rules:      automation.NewMemoryRuleStore(),
+		thresholds: alerts.NewMemoryThresholdStore(),
+		homes:      newTestHomes(t),
+		quarantine: &releasingQuarantine{},
+	}
+	test.handler = &RegistryHandler{
+		Registry:       test.states,
+		StateStore:     test.states,
+		TelemetryStore: test.telemetry,
+		AlertStore:     test.alerts,
+		CommandStore:   test.commands,
+		Schedules:      test.schedules,
+		Rules:          test.rules,
+		Thresholds:     test.thresholds,
+		Quarantine:     test.quarantine,
+		Homes:          test.homes,
+	}
+	test.v1.POST("/devices", test.handler.RegisterDevice)
+	test.v1.DELETE("/devices/:id", test.handler.DecommissionDevice)
+	return test
+}
+
+// gas-1 registered in home-1 with a reading, an alert, a command, a schedule and two rules
+func (test *registryTest) registerWithData(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	if code := test.do(t, "owner-1", http.MethodPost, "/v1/devices", `{"device_id":"gas-1","home_id":"home-1","type":"gas-sensor"}`); code != http.StatusCreated {
+		t.Fatalf("register: status %d", code)
+	}
+	if err := test.telemetry.SaveTelemetry(ctx, models.Telemetry{DeviceID: "gas-1", Timestamp: now, Type: "gas-sensor", Payload: map[string]interface{}{"gas_level": 100.0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.alerts.SaveAlert(ctx, models.Alert{DeviceID: "gas-1", Timestamp: now, Type: "gas-sensor", Severity: "CRITICAL"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.commands.SaveCommand(ctx, models.Command{RequestID: "req-1", DeviceID: "gas-1", Timestamp: now, Action: "SELF_TEST", Status: "PENDING"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.schedules.SaveSchedule(ctx, models.Schedule{ScheduleID: "sch-1", DeviceID: "gas-1", HomeID: "home-1", Action: "SELF_TEST", RunAt: now + 3600}); err != nil {
+		t.Fatal(err)
+	}
+	rules := []models.AutomationRule{
+		{RuleID: "rule-trigger", Trigger: models.AutomationTrigger{DeviceID: "gas-1"}, Action: models.AutomationAction{DeviceID: "fan-1"}},
+		{RuleID: "rule-other", Trigger: models.AutomationTrigger{DeviceID: "door-1"}, Action: models.AutomationAction{DeviceID: "fan-1"}},
+	}
+	for _, rule := range rules {
+		if err := test.rules.SaveRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegisterReleasesQuarantine(t *testing.T) {
+	test := newRegistryTest(t)
+
+	if code := test.do(t, "owner-1", http.MethodPost, "/v1/devices", `{"device_id":"gas-1","home_id":"home-1","type":"gas-sensor"}`); code != http.StatusCreated {
+		t.Fatalf("register: status %d", code)
+	}
+	if len(test.quarantine.released) != 1 || test.quarantine.released[0] != "gas-1" {
+		t.Errorf("released %v, want gas-1 out of the quarantine", test.quarantine.released)
+	}
+	state, _ := test.states.GetStateByID(context.Background(), "gas-1")
+	if state == nil || state.Status != devices.StatusProvisioned {
+		t.Errorf("state = %+v, want gas-1 provisioned", state)
+	}
+
+	if code := test.do(t, "owner-1", http.MethodPost, "/v1/devices", `{"device_id":"gas-1","home_id":"home-1","type":"gas-sensor"}`); code != http.StatusConflict {
+		t.Errorf("registering again: status %d, want 409", code)
+	}
+}
+
+// archiving keeps the data, the device stays in its home
+func TestDecommissionKeepsData(t *testing.T) {
+	ctx := context.Background()
+	test := newRegistryTest(t)
+	test.registerWithData(t)
+
+	if code := test.do(t, "owner-1", http.MethodDelete, "/v1/devices/gas-1", ""); code != http.StatusNoContent {
+		t.Fatalf("decommission: status %d", code)
+	}
+
+	state, _ := test.states.GetStateByID(ctx, "gas-1")
+	if state == nil || state.Status != devices.StatusDecommissioned {
+		t.Errorf("state = %+v, want gas-1 decommissioned", state)
+	}
+	if history, _ := test.telemetry.GetTelemetryHistory(ctx, "gas-1", 0, 0); len(history) != 1 {
+		t.Errorf("%d readings left, archiving keeps them", len(history))
+	}
+	if device, _ := test.homes.GetDeviceHome(ctx, "gas-1"); device == nil {
+		t.Error("gas-1 left home-1, archiving keeps it there")
+	}
+}
+
+func TestPurgeDevice(t *testing.T) {
+	ctx := context.Background()
+	test := newRegistryTest(t)
+	test.registerWithData(t)
+
+	if code := test.do(t, "owner-1", http.MethodDelete, "/v1/devices/gas-1?purge=true", ""); code != http.StatusNoContent {
+		t.Fatalf("purge: status %d", code)
+	}
+
+	if state, _ := test.states.GetStateByID(ctx, "gas-1"); state != nil {
+		t.Errorf("state = %+v, want it deleted", state)
+	}
+	if history, _ := test.telemetry.GetTelemetryHistory(ctx, "gas-1", 0, 0); len(history) != 0 {
+		t.Errorf("%d readings left", len(history))
+	}
+	if alertList, _ := test.alerts.GetAlertsByDevice(ctx, "gas-1", 0); len(alertList) != 0 {
+		t.Errorf("%d alerts left", len(alertList))
+	}
+	if cmd, _ := test.commands.GetCommand(ctx, "req-1"); cmd != nil {
+		t.Errorf("command = %+v, want it deleted", cmd)
+	}
+	if scheduleList, _ := test.schedules.GetSchedulesByDevice(ctx, "gas-1"); len(scheduleList) != 0 {
+		t.Errorf("%d schedules left", len(scheduleList))
+	}
+	if thresholds, _ := test.thresholds.GetThresholds(ctx, "gas-1"); len(thresholds) != 0 {
+		t.Errorf("%d thresholds left", len(thresholds))
+	}
+	ruleList, _ := test.rules.ListRules(ctx)
+	if len(ruleList) != 1 || ruleList[0].RuleID != "rule-other" {
+		t.Errorf("rules = %+v, want only the rule without gas-1 kept", ruleList)
+	}
+	if device, _ := test.homes.GetDeviceHome(ctx, "gas-1"); device != nil {
+		t.Errorf("gas-1 still in %s, a purge frees the id", device.HomeID)
+	}
+
+	// the id is free: registering it again starts over
+	if code := test.do(t, "owner-1", http.MethodPost, "/v1/devices", `{"device_id":"gas-1","home_id":"home-1","type":"gas-sensor"}`); code != http.StatusCreated {
+		t.Errorf("registering the purged id: status %d, want 201", code)
+	}
+}
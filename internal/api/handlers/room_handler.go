@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/rooms"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+const maxRoomNameLength = 64
+
+var errRoomNotInHome = errors.New("room_id is not a room of the device's home")
+
+// RoomHandler groups the devices of a home by room, members read them, the owner edits them
+type RoomHandler struct {
+	Rooms          rooms.RoomStore
+	Registry       devices.RegistryStore
+	StateStore     devices.StateStore
+	TelemetryStore telemetry.TelemetryStore
+	Homes          homes.HomeStore
+	Dispatcher     *commands.Dispatcher
+	Audit          *audit.Recorder
+}
+
+type CreateRoomRequest struct {
+	HomeID string `json:"home_id" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+}
+
+type UpdateRoomRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// the command goes to every device of the room accepting the action, device_type narrows it down
+type RoomCommandRequest struct {
+	DeviceType string                 `json:"device_type"`
+	Action     string                 `json:"action" binding:"required"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+type RoomView struct {
+	models.Room
+	Summary rooms.Summary `json:"summary"`
+}
+
+// handling GET /rooms?home_id=..., the rooms of the caller's homes with their merged state
+func (handler *RoomHandler) ListRooms(context *gin.Context) {
+	ctx := context.Request.Context()
+
+	memberships, err := handler.Homes.GetMemberships(ctx, auth.UserID(context))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+
+	homeFilter := context.Query("home_id")
+	now := time.Now().Unix()
+	var roomList []models.Room
+	deviceIDs := make(map[string]bool)
+	for _, membership := range memberships {
+		if !homes.Active(membership, now) || (homeFilter != "" && membership.HomeID != homeFilter) {
+			continue
+		}
+		homeRooms, err := handler.Rooms.GetRooms(ctx, membership.HomeID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+			return
+		}
+		deviceList, err := handler.Homes.GetHomeDevices(ctx, membership.HomeID)
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+			return
+		}
+		roomList = append(roomList, homeRooms...)
+		for _, device := range deviceList {
+			deviceIDs[device.DeviceID] = true
+		}
+	}
+
+	states, err := visibleStates(ctx, handler.StateStore, deviceIDs)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch device states"})
+		return
+	}
+	byRoom := make(map[string][]models.DeviceState)
+	for _, state := range states {
+		if state.RoomID != "" {
+			byRoom[state.RoomID] = append(byRoom[state.RoomID], state)
+		}
+	}
+
+	views := make([]RoomView, 0, len(roomList))
+	for _, room := range roomList {
+		views = append(views, RoomView{Room: room, Summary: rooms.Summarize(byRoom[room.RoomID])})
+	}
+	context.JSON(http.StatusOK, gin.H{"data": views})
+}
+
+// handling POST /rooms
+func (handler *RoomHandler) CreateRoom(context *gin.Context) {
+	var req CreateRoomRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid room format! home_id and name are required."})
+		return
+	}
+	if len(req.Name) > maxRoomNameLength {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("name is longer than %d characters", maxRoomNameLength)})
+		return
+	}
+
+	home, ok := requireHomeOwner(context, handler.Homes, req.HomeID, "Only the home owner can manage its rooms")
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	room := models.Room{
+		RoomID:    fmt.Sprintf("room-%d", now.UnixNano()),
+		HomeID:    home.HomeID,
+		Name:      req.Name,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+	}
+	if err := handler.Rooms.SaveRoom(context.Request.Context(), room); err != nil {
+		slog.Error("failed to save room", "room_id", room.RoomID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save room"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRoomCreated,
+		HomeID:   home.HomeID,
+		TargetID: room.RoomID,
+		After:    room,
+	})
+
+	context.JSON(http.StatusCreated, room)
+}
+
+// handling GET /rooms/:room_id, the room's devices, their merged state and the room analytics
+func (handler *RoomHandler) GetRoom(context *gin.Context) {
+	room, ok := handler.loadRoom(context, false)
+	if !ok {
+		return
+	}
+	states, ok := handler.roomDevices(context, room)
+	if !ok {
+		return
+	}
+	location, ok := requestZone(context, func() (*time.Location, error) {
+		home, err := handler.Homes.GetHome(context.Request.Context(), room.HomeID)
+		return homes.Zone(home), err
+	})
+	if !ok {
+		return
+	}
+
+	now := time.Now().Unix()
+	histories := make(map[string][]models.Telemetry)
+	for _, state := range states {
+		if !rooms.NeedsHistory(state.Type) {
+			continue
+		}
+		history, err := handler.TelemetryStore.GetTelemetryHistory(context.Request.Context(), state.DeviceID, 0, telemetry.PeriodCutoff(now, "7d"))
+		if err != nil {
+			slog.Warn("failed to fetch room device history", "room_id", room.RoomID, "device_id", state.DeviceID, "error", err)
+			continue
+		}
+		histories[state.DeviceID] = history
+	}
+
+	for i := range states {
+		states[i].Status = devices.LiveStatus(states[i])
+	}
+	context.JSON(http.StatusOK, gin.H{
+		"room":     room,
+		"devices":  states,
+		"summary":  rooms.Summarize(states),
+		"insights": rooms.BuildInsights(states, histories, now, location),
+	})
+}
+
+// handling PUT /rooms/:room_id
+func (handler *RoomHandler) UpdateRoom(context *gin.Context) {
+	room, ok := handler.loadRoom(context, true)
+	if !ok {
+		return
+	}
+
+	var req UpdateRoomRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid room format! name is required."})
+		return
+	}
+	if len(req.Name) > maxRoomNameLength {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("name is longer than %d characters", maxRoomNameLength)})
+		return
+	}
+
+	before := *room
+	room.Name = req.Name
+	room.UpdatedAt = time.Now().Unix()
+	if err := handler.Rooms.SaveRoom(context.Request.Context(), *room); err != nil {
+		slog.Error("failed to save room", "room_id", room.RoomID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save room"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRoomUpdated,
+		HomeID:   room.HomeID,
+		TargetID: room.RoomID,
+		Before:   before,
+		After:    room,
+	})
+
+	context.JSON(http.StatusOK, room)
+}
+
+// handling DELETE /rooms/:room_id, its devices stay in the home without a room
+func (handler *RoomHandler) DeleteRoom(context *gin.Context) {
+	room, ok := handler.loadRoom(context, true)
+	if !ok {
+		return
+	}
+	states, ok := handler.roomDevices(context, room)
+	if !ok {
+		return
+	}
+
+	// the devices are moved out first, a failure leaves the room in place to try again
+	now := time.Now().Unix()
+	for _, state := range states {
+		state.RoomID = ""
+		state.LastUpdated = now
+		err := handler.Registry.UpdateDeviceInfo(context.Request.Context(), state)
+		if err != nil && !errors.Is(err, devices.ErrDeviceNotFound) {
+			slog.Error("failed to move device out of room", "room_id", room.RoomID, "device_id", state.DeviceID, "error", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room"})
+			return
+		}
+	}
+
+	if err := handler.Rooms.DeleteRoom(context.Request.Context(), room.RoomID); err != nil {
+		slog.Error("failed to delete room", "room_id", room.RoomID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionRoomDeleted,
+		HomeID:   room.HomeID,
+		TargetID: room.RoomID,
+		Before:   room,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+// handling POST /rooms/:room_id/commands, "turn off all ACs in the bedroom".
+// every target is checked before anything is sent, then each device gets its own command
+func (handler *RoomHandler) SendRoomCommand(context *gin.Context) {
+	room, ok := handler.loadRoom(context, false)
+	if !ok {
+		return
+	}
+
+	var req RoomCommandRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid command format! action is required."})
+		return
+	}
+
+	states, ok := handler.roomDevices(context, room)
+	if !ok {
+		return
+	}
+	targets := slices.DeleteFunc(states, func(state models.DeviceState) bool {
+		if req.DeviceType != "" && state.Type != req.DeviceType {
+			return true
+		}
+		_, accepts := devices.Rules[state.Type].Commands[req.Action]
+		return !accepts
+	})
+	if len(targets) == 0 {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("no device in this room accepts %s", req.Action)})
+		return
+	}
+
+	// the schema and the caller's permissions depend on the device type only, one check per type
+	checked := make(map[string]bool)
+	for _, target := range targets {
+		if checked[target.Type] {
+			continue
+		}
+		checked[target.Type] = true
+
+		var validationErr *devices.CommandValidationError
+		if err := devices.ValidateCommand(target.Type, req.Action, req.Parameters); errors.As(err, &validationErr) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr})
+			return
+		}
+		if _, ok := authorizeCommand(context, handler.Homes, handler.Audit, target.DeviceID, target.Type, req.Action); !ok {
+			return
+		}
+	}
+
+	results := make([]gin.H, 0, len(targets))
+	for _, target := range targets {
+		cmd, err := handler.Dispatcher.Dispatch(context.Request.Context(), commands.Request{
+			DeviceID:   target.DeviceID,
+			DeviceType: target.Type,
+			Action:     req.Action,
+			Parameters: req.Parameters,
+			IssuedBy:   auth.UserID(context),
+		})
+		if err != nil {
+			slog.Error("failed to publish room command", "room_id", room.RoomID, "device_id", target.DeviceID, "error", err)
+			results = append(results, gin.H{"device_id": target.DeviceID, "error": "Failed to communicate with device"})
+			continue
+		}
+		results = append(results, gin.H{"device_id": target.DeviceID, "request_id": cmd.RequestID, "status": cmd.Status})
+	}
+
+	context.JSON(http.StatusAccepted, gin.H{"room_id": room.RoomID, "data": results})
+}
+
+// members only, rooms of other homes answer 404. ownerOnly answers 403 to the other members
+func (handler *RoomHandler) loadRoom(context *gin.Context, ownerOnly bool) (*models.Room, bool) {
+	room, err := handler.Rooms.GetRoom(context.Request.Context(), context.Param("room_id"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room"})
+		return nil, false
+	}
+
+	var member *models.HomeMember
+	if room != nil {
+		member, err = homes.ActiveMember(context.Request.Context(), handler.Homes, room.HomeID, auth.UserID(context))
+		if err != nil {
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room"})
+			return nil, false
+		}
+	}
+	if member == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return nil, false
+	}
+	if ownerOnly {
+		if _, ok := requireHomeOwner(context, handler.Homes, room.HomeID, "Only the home owner can manage its rooms"); !ok {
+			return nil, false
+		}
+	}
+	return room, true
+}
+
+// the active devices pointing at the room, a device that left the home doesn't count even if it still does
+func (handler *RoomHandler) roomDevices(context *gin.Context, room *models.Room) ([]models.DeviceState, bool) {
+	deviceList, err := handler.Homes.GetHomeDevices(context.Request.Context(), room.HomeID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room devices"})
+		return nil, false
+	}
+	deviceIDs := make(map[string]bool, len(deviceList))
+	for _, device := range deviceList {
+		deviceIDs[device.DeviceID] = true
+	}
+
+	states, err := visibleStates(context.Request.Context(), handler.StateStore, deviceIDs)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch device states"})
+		return nil, false
+	}
+	return slices.DeleteFunc(states, func(state models.DeviceState) bool {
+		return state.RoomID != room.RoomID
+	}), true
+}
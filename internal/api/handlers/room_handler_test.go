@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/rooms"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	topics []string
+}
+
+func (publisher *recordingPublisher) Publish(ctx context.Context, topic string, payload interface{}) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publisher.topics = append(publisher.topics, topic)
+	return nil
+}
+
+// bedroom of home-1 holds ac-1, ac-2 and temp-1, ac-3 is in the living room and ac-4 is archived.
+// member-1 may only look at the devices
+func newRoomTest(t *testing.T) (*testAPI, *recordingPublisher) {
+	t.Helper()
+	ctx := context.Background()
+	api := newTestAPI(t)
+	homeStore := newTestHomes(t)
+	states := devices.NewMemoryStateStore()
+	roomStore := rooms.NewMemoryRoomStore()
+	publisher := &recordingPublisher{}
+
+	if err := homeStore.SaveMember(ctx, models.HomeMember{HomeID: "home-1", UserID: "member-1", Role: homes.RoleMember, Permissions: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, room := range []models.Room{{RoomID: "bedroom", HomeID: "home-1"}, {RoomID: "living", HomeID: "home-1"}} {
+		if err := roomStore.SaveRoom(ctx, room); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now().Unix()
+	deviceList := []models.DeviceState{
+		{DeviceID: "ac-1", Type: "ac-actuator", RoomID: "bedroom"},
+		{DeviceID: "ac-2", Type: "ac-actuator", RoomID: "bedroom"},
+		{DeviceID: "temp-1", Type: "temp-sensor", RoomID: "bedroom"},
+		{DeviceID: "ac-3", Type: "ac-actuator", RoomID: "living"},
+		{DeviceID: "ac-4", Type: "ac-actuator", RoomID: "bedroom"},
+	}
+	for _, state := range deviceList {
+		state.Status = devices.StatusProvisioned
+		state.RegisteredAt = now
+		if err := states.RegisterDevice(ctx, state); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := homeStore.ClaimDevice(ctx, models.HomeDevice{DeviceID: state.DeviceID, HomeID: "home-1", AddedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := states.DecommissionDevice(ctx, "ac-4", now); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &RoomHandler{
+		Rooms:      roomStore,
+		Registry:   states,
+		StateStore: states,
+		Homes:      homeStore,
+		Dispatcher: &commands.Dispatcher{Publisher: publisher, Store: commands.NewMemoryCommandStore()},
+	}
+	api.v1.POST("/rooms/:room_id/commands", handler.SendRoomCommand)
+	return api, publisher
+}
+
+func TestSendRoomCommand(t *testing.T) {
+	api, publisher := newRoomTest(t)
+
+	code := api.do(t, "owner-1", http.MethodPost, "/v1/rooms/bedroom/commands", `{"action":"SET_STATE","parameters":{"power":"OFF"}}`)
+	if code != http.StatusAccepted {
+		t.Fatalf("room command: status %d", code)
+	}
+	slices.Sort(publisher.topics)
+	want := []string{"devices/ac-1/command", "devices/ac-2/command"}
+	if !slices.Equal(publisher.topics, want) {
+		t.Errorf("published to %v, want %v", publisher.topics, want)
+	}
+}
+
+// nothing is sent when one target is refused
+func TestSendRoomCommandRefused(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		room   string
+		body   string
+		want   int
+	}{
+		{"no permission", "member-1", "bedroom", `{"action":"SET_STATE","parameters":{"power":"OFF"}}`, http.StatusForbidden},
+		{"invalid parameters", "owner-1", "bedroom", `{"action":"SET_STATE","parameters":{"power":"HALF"}}`, http.StatusUnprocessableEntity},
+		{"no device accepts it", "owner-1", "bedroom", `{"device_type":"temp-sensor","action":"SET_STATE","parameters":{"power":"OFF"}}`, http.StatusUnprocessableEntity},
+		{"room of another home", "owner-1", "attic", `{"action":"SET_STATE","parameters":{"power":"OFF"}}`, http.StatusNotFound},
+		{"not a member", "stranger-1", "bedroom", `{"action":"SET_STATE","parameters":{"power":"OFF"}}`, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		api, publisher := newRoomTest(t)
+		if code := api.do(t, test.userID, http.MethodPost, "/v1/rooms/"+test.room+"/commands", test.body); code != test.want {
+			t.Errorf("%s: status %d, want %d", test.name, code, test.want)
+		}
+		if len(publisher.topics) != 0 {
+			t.Errorf("%s: published to %v, want nothing sent", test.name, publisher.topics)
+		}
+	}
+}
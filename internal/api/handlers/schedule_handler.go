@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/schedules"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	StateStore devices.StateStore
+	Schedules  schedules.ScheduleStore
+	Homes      homes.HomeStore
+	Audit      *audit.Recorder
+}
+
+type ScheduleRequest struct {
+	Action     string                 `json:"action" binding:"required"`
+	Parameters map[string]interface{} `json:"parameters"`
+	RunAt      int64                  `json:"run_at"`
+	Cron       string                 `json:"cron"`
+	TimeZone   string                 `json:"time_zone"`
+	CatchUp    string                 `json:"catch_up"`
+	Enabled    *bool                  `json:"enabled"` // defaults to true
+}
+
+// handling GET /devices/:id/schedules
+func (handler *ScheduleHandler) ListSchedules(context *gin.Context) {
+	deviceID := context.Param("id")
+
+	if _, ok := handler.loadDevice(context, deviceID); !ok {
+		return
+	}
+
+	scheduleList, err := handler.Schedules.GetSchedulesByDevice(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedules"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": scheduleList})
+}
+
+// handling GET /devices/:id/schedules/:schedule_id
+func (handler *ScheduleHandler) GetSchedule(context *gin.Context) {
+	schedule, ok := handler.loadSchedule(context)
+	if !ok {
+		return
+	}
+	context.JSON(http.StatusOK, schedule)
+}
+
+// handling POST /devices/:id/schedules
+func (handler *ScheduleHandler) CreateSchedule(context *gin.Context) {
+	deviceID := context.Param("id")
+
+	var req ScheduleRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule format! action is required."})
+		return
+	}
+
+	state, ok := handler.loadDevice(context, deviceID)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	schedule := models.Schedule{
+		ScheduleID: fmt.Sprintf("sch-%d", now.UnixNano()),
+		DeviceID:   deviceID,
+		CreatedAt:  now.Unix(),
+	}
+	if !handler.applyRequest(context, &schedule, req, state.Type, now) {
+		return
+	}
+
+	if err := handler.Schedules.SaveSchedule(context.Request.Context(), schedule); err != nil {
+		slog.Error("failed to save schedule", "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save schedule"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionScheduleCreated,
+		DeviceID: deviceID,
+		TargetID: schedule.ScheduleID,
+		After:    schedule,
+	})
+
+	context.JSON(http.StatusCreated, schedule)
+}
+
+// handling PUT /devices/:id/schedules/:schedule_id
+func (handler *ScheduleHandler) UpdateSchedule(context *gin.Context) {
+	var req ScheduleRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule format! action is required."})
+		return
+	}
+
+	schedule, ok := handler.loadSchedule(context)
+	if !ok {
+		return
+	}
+	state, ok := handler.loadDevice(context, schedule.DeviceID)
+	if !ok {
+		return
+	}
+	before := *schedule
+
+	// a new timing starts over, past runs stay recorded
+	schedule.RunAt = 0
+	schedule.Cron = ""
+	if !handler.applyRequest(context, schedule, req, state.Type, time.Now()) {
+		return
+	}
+
+	if err := handler.Schedules.SaveSchedule(context.Request.Context(), *schedule); err != nil {
+		slog.Error("failed to update schedule", "schedule_id", schedule.ScheduleID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save schedule"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionScheduleUpdated,
+		DeviceID: schedule.DeviceID,
+		TargetID: schedule.ScheduleID,
+		Before:   before,
+		After:    schedule,
+	})
+
+	context.JSON(http.StatusOK, schedule)
+}
+
+// handling DELETE /devices/:id/schedules/:schedule_id
+func (handler *ScheduleHandler) DeleteSchedule(context *gin.Context) {
+	schedule, ok := handler.loadSchedule(context)
+	if !ok {
+		return
+	}
+
+	if err := handler.Schedules.DeleteSchedule(context.Request.Context(), schedule.ScheduleID); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionScheduleDeleted,
+		DeviceID: schedule.DeviceID,
+		TargetID: schedule.ScheduleID,
+		Before:   schedule,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+// validates the command against the caller's role, the device type and the timing, writes the 4xx itself
+func (handler *ScheduleHandler) applyRequest(context *gin.Context, schedule *models.Schedule, req ScheduleRequest, deviceType string, now time.Time) bool {
+	member, ok := authorizeCommand(context, handler.Homes, handler.Audit, schedule.DeviceID, deviceType, req.Action)
+	if !ok {
+		return false
+	}
+	if err := devices.ValidateCommand(deviceType, req.Action, req.Parameters); err != nil {
+		var validationErr *devices.CommandValidationError
+		if errors.As(err, &validationErr) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr})
+			return false
+		}
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	schedule.Action = req.Action
+	schedule.Parameters = req.Parameters
+	schedule.RunAt = req.RunAt
+	schedule.Cron = req.Cron
+	schedule.TimeZone = req.TimeZone
+	schedule.CatchUp = req.CatchUp
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+	schedule.HomeID = member.HomeID
+	schedule.CreatedBy = member.UserID // the last editor, the runner checks their permission on every run
+	schedule.UpdatedAt = now.Unix()
+
+	if err := schedules.Prepare(schedule, now); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (handler *ScheduleHandler) loadDevice(context *gin.Context, deviceID string) (*models.DeviceState, bool) {
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return nil, false
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+	return state, true
+}
+
+// the schedule must belong to the device in the path, itself in one of the caller's homes
+func (handler *ScheduleHandler) loadSchedule(context *gin.Context) (*models.Schedule, bool) {
+	if !authorizeDevice(context, handler.Homes, context.Param("id")) {
+		return nil, false
+	}
+
+	schedule, err := handler.Schedules.GetSchedule(context.Request.Context(), context.Param("schedule_id"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule"})
+		return nil, false
+	}
+	if schedule == nil || schedule.DeviceID != context.Param("id") {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return nil, false
+	}
+	return schedule, true
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/stream"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	streamKeepAlive = 25 * time.Second // under the usual 30-60s idle timeout of proxies and load balancers
+	maxRelayBody    = 1 << 20
+)
+
+type StreamHandler struct {
+	Hub         *stream.Hub
+	Homes       homes.HomeStore
+	RelaySecret string // signs the events relayed by the lambdas, empty disables the relay endpoint
+}
+
+// handling GET /stream?devices=a,b&types=device_state,alert
+// SSE by default, WebSocket when the request asks for an upgrade.
+// only devices of the caller's homes are streamed, a device added later needs a reconnect.
+// access is checked again on every keep-alive, the stream ends once a streamed device is taken away
+func (handler *StreamHandler) Stream(context *gin.Context) {
+	filter := stream.Filter{
+		Types: stream.SplitList(context.Query("types")),
+	}
+	for _, eventType := range filter.Types {
+		if !stream.IsEventType(eventType) {
+			context.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("unknown event type %q, expected device_state, alert or command", eventType)})
+			return
+		}
+	}
+
+	deviceIDs, ok := callerDevices(context, handler.Homes)
+	if !ok {
+		return
+	}
+	filter.Devices = deviceIDs
+	if requested := stream.SplitList(context.Query("devices")); len(requested) > 0 {
+		filter.Devices = make(map[string]bool)
+		for _, deviceID := range requested {
+			filter.Devices[deviceID] = deviceIDs[deviceID]
+		}
+	}
+
+	userID := auth.UserID(context)
+	if strings.EqualFold(context.GetHeader("Upgrade"), "websocket") {
+		handler.streamWebSocket(context, userID, filter)
+		return
+	}
+	handler.streamSSE(context, userID, filter)
+}
+
+// the caller still reaches every streamed device: member removed, guest expired or device moved
+// to another home all end the stream. a failed lookup keeps it, the next keep-alive checks again
+func (handler *StreamHandler) stillAllowed(ctx context.Context, userID string, filter stream.Filter) bool {
+	deviceIDs, err := homes.DeviceIDs(ctx, handler.Homes, userID)
+	if err != nil {
+		slog.Warn("failed to recheck stream access", "user_id", userID, "error", err)
+		return true
+	}
+	for deviceID, streamed := range filter.Devices {
+		if streamed && !deviceIDs[deviceID] {
+			return false
+		}
+	}
+	return true
+}
+
+func (handler *StreamHandler) streamSSE(context *gin.Context, userID string, filter stream.Filter) {
+	subscription := handler.Hub.Subscribe(filter)
+	defer handler.Hub.Unsubscribe(subscription)
+
+	context.Header("Content-Type", "text/event-stream")
+	context.Header("Cache-Control", "no-cache")
+	context.Header("X-Accel-Buffering", "no") // nginx would hold the events back otherwise
+
+	// headers go out now, not with the first event, so EventSource reports the connection open
+	context.Writer.WriteHeaderNow()
+	context.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	context.Stream(func(w io.Writer) bool {
+		select {
+		case <-context.Request.Context().Done():
+			return false
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return false // too slow, the client reconnects
+			}
+			context.Render(-1, sseEvent(event))
+			return true
+		case <-keepAlive.C:
+			if !handler.stillAllowed(context.Request.Context(), userID, filter) {
+				return false
+			}
+			context.SSEvent("ping", gin.H{"timestamp": time.Now().Unix()})
+			return true
+		}
+	})
+}
+
+func (handler *StreamHandler) streamWebSocket(context *gin.Context, userID string, filter stream.Filter) {
+	// no origin check, the dashboard is served from another domain
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		defer conn.Close()
+
+		subscription := handler.Hub.Subscribe(filter)
+		defer handler.Hub.Unsubscribe(subscription)
+
+		// clients only listen, reading is how a closed connection is noticed
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var discard string
+			for websocket.Message.Receive(conn, &discard) == nil {
+			}
+		}()
+
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case event, ok := <-subscription.Events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(conn, event); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if !handler.stillAllowed(conn.Request().Context(), userID, filter) {
+					return
+				}
+				if err := websocket.JSON.Send(conn, gin.H{"type": "ping", "timestamp": time.Now().Unix()}); err != nil {
+					return
+				}
+			}
+		}
+	}}
+
+	server.ServeHTTP(context.Writer, context.Request)
+}
+
+// the event id lets clients spot gaps, the name lets EventSource listeners pick a type
+func sseEvent(event stream.Event) sse.Event {
+	return sse.Event{
+		Event: event.Type,
+		Id:    fmt.Sprint(event.ID),
+		Data:  event,
+	}
+}
+
+// handling POST /internal/events, events relayed by the lambdas to the subscribers of this instance
+func (handler *StreamHandler) ReceiveRelay(context *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(context.Request.Body, maxRelayBody))
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "failed to read event"})
+		return
+	}
+
+	if handler.RelaySecret == "" || !stream.ValidSignature(handler.RelaySecret, body, context.GetHeader(stream.SignatureHeader)) {
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	var event stream.Event
+	if err := json.Unmarshal(body, &event); err != nil || !stream.IsEventType(event.Type) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid event format"})
+		return
+	}
+
+	if err := handler.Hub.Publish(context.Request.Context(), event); err != nil {
+		slog.Error("failed to publish relayed event", "type", event.Type, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish event"})
+		return
+	}
+
+	context.Status(http.StatusAccepted)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ThresholdHandler struct {
+	StateStore devices.StateStore
+	Thresholds alerts.ThresholdStore
+	Homes      homes.HomeStore
+	Audit      *audit.Recorder
+}
+
+type ThresholdRequest struct {
+	Metric          string  `json:"metric" binding:"required"`
+	Operator        string  `json:"operator" binding:"required"`
+	Value           float64 `json:"value"`
+	State           string  `json:"state"`
+	Hysteresis      float64 `json:"hysteresis"`
+	ForSeconds      int64   `json:"for_seconds"`
+	Severity        string  `json:"severity"`
+	CooldownSeconds int64   `json:"cooldown_seconds"`
+	Enabled         *bool   `json:"enabled"` // defaults to true
+}
+
+// handling GET /devices/:id/thresholds
+func (handler *ThresholdHandler) ListThresholds(context *gin.Context) {
+	deviceID := context.Param("id")
+
+	if !handler.deviceExists(context, deviceID) {
+		return
+	}
+
+	thresholdList, err := handler.Thresholds.GetThresholds(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thresholds"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"data": thresholdList})
+}
+
+// handling POST /devices/:id/thresholds
+func (handler *ThresholdHandler) CreateThreshold(context *gin.Context) {
+	deviceID := context.Param("id")
+
+	var req ThresholdRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid threshold format! metric and operator are required."})
+		return
+	}
+
+	if !handler.deviceExists(context, deviceID) {
+		return
+	}
+
+	now := time.Now()
+	threshold := models.Threshold{
+		DeviceID:    deviceID,
+		ThresholdID: fmt.Sprintf("thr-%d", now.UnixNano()),
+		CreatedAt:   now.Unix(),
+	}
+	if !applyThresholdRequest(context, &threshold, req, now) {
+		return
+	}
+
+	if err := handler.Thresholds.SaveThreshold(context.Request.Context(), threshold); err != nil {
+		slog.Error("failed to save threshold", "device_id", deviceID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save threshold"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionThresholdCreated,
+		DeviceID: deviceID,
+		TargetID: threshold.ThresholdID,
+		After:    threshold,
+	})
+
+	context.JSON(http.StatusCreated, threshold)
+}
+
+// handling PUT /devices/:id/thresholds/:threshold_id
+func (handler *ThresholdHandler) UpdateThreshold(context *gin.Context) {
+	var req ThresholdRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "invalid threshold format! metric and operator are required."})
+		return
+	}
+
+	threshold, ok := handler.loadThreshold(context)
+	if !ok {
+		return
+	}
+	before := *threshold
+
+	// new limits start a new breach, the last alert time is kept for the cooldown
+	threshold.BreachSince = 0
+	if !applyThresholdRequest(context, threshold, req, time.Now()) {
+		return
+	}
+
+	if err := handler.Thresholds.SaveThreshold(context.Request.Context(), *threshold); err != nil {
+		slog.Error("failed to update threshold", "threshold_id", threshold.ThresholdID, "error", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save threshold"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionThresholdUpdated,
+		DeviceID: threshold.DeviceID,
+		TargetID: threshold.ThresholdID,
+		Before:   before,
+		After:    threshold,
+	})
+
+	context.JSON(http.StatusOK, threshold)
+}
+
+// handling DELETE /devices/:id/thresholds/:threshold_id
+func (handler *ThresholdHandler) DeleteThreshold(context *gin.Context) {
+	threshold, ok := handler.loadThreshold(context)
+	if !ok {
+		return
+	}
+
+	if err := handler.Thresholds.DeleteThreshold(context.Request.Context(), threshold.DeviceID, threshold.ThresholdID); err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete threshold"})
+		return
+	}
+	recordAudit(context, handler.Audit, models.AuditEvent{
+		Action:   audit.ActionThresholdDeleted,
+		DeviceID: threshold.DeviceID,
+		TargetID: threshold.ThresholdID,
+		Before:   threshold,
+	})
+
+	context.Status(http.StatusNoContent)
+}
+
+func applyThresholdRequest(context *gin.Context, threshold *models.Threshold, req ThresholdRequest, now time.Time) bool {
+	threshold.Metric = req.Metric
+	threshold.Operator = req.Operator
+	threshold.Value = req.Value
+	threshold.State = req.State
+	threshold.Hysteresis = req.Hysteresis
+	threshold.ForSeconds = req.ForSeconds
+	threshold.Severity = req.Severity
+	threshold.CooldownSeconds = req.CooldownSeconds
+	threshold.Enabled = req.Enabled == nil || *req.Enabled
+	threshold.UpdatedAt = now.Unix()
+
+	if err := alerts.PrepareThreshold(threshold); err != nil {
+		context.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func (handler *ThresholdHandler) deviceExists(context *gin.Context, deviceID string) bool {
+	if !authorizeDevice(context, handler.Homes, deviceID) {
+		return false
+	}
+
+	state, err := handler.StateStore.GetStateByID(context.Request.Context(), deviceID)
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	if state == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return false
+	}
+	return true
+}
+
+func (handler *ThresholdHandler) loadThreshold(context *gin.Context) (*models.Threshold, bool) {
+	if !authorizeDevice(context, handler.Homes, context.Param("id")) {
+		return nil, false
+	}
+
+	threshold, err := handler.Thresholds.GetThreshold(context.Request.Context(), context.Param("id"), context.Param("threshold_id"))
+	if err != nil {
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch threshold"})
+		return nil, false
+	}
+	if threshold == nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+		return nil, false
+	}
+	return threshold, true
+}
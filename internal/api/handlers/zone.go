@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/gin-gonic/gin"
+)
+
+// the zone the charts and times of a response are shown in: ?tz= when the app sends the
+// user's own, else the one resolve finds (the home's), UTC if that fails. an unknown tz answers 400
+func requestZone(context *gin.Context, resolve func() (*time.Location, error)) (*time.Location, bool) {
+	if name := context.Query("tz"); name != "" {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone, like Africa/Cairo"})
+			return nil, false
+		}
+		return location, true
+	}
+
+	location, err := resolve()
+	if err != nil {
+		slog.Warn("failed to resolve time zone, using UTC", "error", err)
+		return time.UTC, true
+	}
+	return location, true
+}
+
+// the zone of the home holding the device
+func deviceZone(context *gin.Context, store homes.HomeStore, deviceID string) (*time.Location, bool) {
+	return requestZone(context, func() (*time.Location, error) {
+		return homes.DeviceZone(context.Request.Context(), store, deviceID)
+	})
+}
+
+// the zone of the caller's homes, for the views spanning all of them
+func userZone(context *gin.Context, store homes.HomeStore) (*time.Location, bool) {
+	return requestZone(context, func() (*time.Location, error) {
+		return homes.UserZone(context.Request.Context(), store, auth.UserID(context))
+	})
+}
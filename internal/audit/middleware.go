@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestID tags every api call with an id (the client's X-Request-ID, or a new one), echoed back
+// in the response and carried by the request context so the audit events of the call share it
+func RequestID() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		requestID := context.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = fmt.Sprintf("req-%d", time.Now().UnixNano())
+		}
+
+		context.Header(RequestIDHeader, requestID)
+		context.Request = context.Request.WithContext(WithRequestID(context.Request.Context(), requestID))
+		context.Next()
+	}
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// empty outside an api call (lambdas)
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Logins records the first request of every token as a LOGIN, it runs after auth.Middleware
+func Logins(recorder *Recorder) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if claims := auth.TokenClaims(context); claims != nil {
+			issuedAt := claims.IssuedAt
+			if issuedAt == 0 {
+				issuedAt = claims.ExpiresAt
+			}
+			if err := recorder.RecordLogin(context.Request.Context(), claims.Subject, issuedAt); err != nil {
+				slog.Error("failed to record login", "user_id", claims.Subject, "error", err)
+			}
+		}
+		context.Next()
+	}
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	ActionCommandDispatched = "COMMAND_DISPATCHED"
+	ActionCommandFailed     = "COMMAND_FAILED"
+	ActionPermissionDenied  = "PERMISSION_DENIED"
+	ActionLogin             = "LOGIN"
+
+	ActionAlertAcknowledged = "ALERT_ACKNOWLEDGED"
+	ActionAlertResolved     = "ALERT_RESOLVED"
+
+	ActionRuleCreated      = "RULE_CREATED"
+	ActionRuleUpdated      = "RULE_UPDATED"
+	ActionRuleDeleted      = "RULE_DELETED"
+	ActionScheduleCreated  = "SCHEDULE_CREATED"
+	ActionScheduleUpdated  = "SCHEDULE_UPDATED"
+	ActionScheduleDeleted  = "SCHEDULE_DELETED"
+	ActionThresholdCreated = "THRESHOLD_CREATED"
+	ActionThresholdUpdated = "THRESHOLD_UPDATED"
+	ActionThresholdDeleted = "THRESHOLD_DELETED"
+
+	ActionDeviceRegistered     = "DEVICE_REGISTERED"
+	ActionDeviceUpdated        = "DEVICE_UPDATED"
+	ActionDeviceDecommissioned = "DEVICE_DECOMMISSIONED"
+
+	ActionRoomCreated = "ROOM_CREATED"
+	ActionRoomUpdated = "ROOM_UPDATED"
+	ActionRoomDeleted = "ROOM_DELETED"
+
+	ActionHomeCreated       = "HOME_CREATED"
+	ActionHomeUpdated       = "HOME_UPDATED"
+	ActionHomeDeviceAdded   = "HOME_DEVICE_ADDED"
+	ActionHomeDeviceRemoved = "HOME_DEVICE_REMOVED"
+	ActionHomeMemberAdded   = "HOME_MEMBER_ADDED"
+	ActionHomeMemberUpdated = "HOME_MEMBER_UPDATED"
+	ActionHomeMemberRemoved = "HOME_MEMBER_REMOVED"
+)
+
+// logins already written by this instance, cleared when it grows past this
+const maxSeenLogins = 10000
+
+// Recorder fills in what every event needs and appends it to the store.
+// a nil Recorder records nothing, so stores and handlers work without one in tests
+type Recorder struct {
+	Store AuditStore
+	Homes homes.HomeStore // optional, resolves HomeID from DeviceID
+
+	mu         sync.Mutex
+	seenLogins map[string]bool
+}
+
+func NewRecorder(store AuditStore, homeStore homes.HomeStore) *Recorder {
+	return &Recorder{
+		Store: store,
+		Homes: homeStore,
+	}
+}
+
+func (recorder *Recorder) Record(ctx context.Context, event models.AuditEvent) error {
+	if recorder == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if event.Timestamp == 0 {
+		event.Timestamp = now.Unix()
+	}
+	if event.EventID == "" {
+		event.EventID = fmt.Sprintf("aud-%d", now.UnixNano())
+	}
+	if event.RequestID == "" {
+		event.RequestID = RequestIDFrom(ctx)
+	}
+
+	// the home is looked up now, a device can move to another home later
+	if event.HomeID == "" && event.DeviceID != "" && recorder.Homes != nil {
+		device, err := recorder.Homes.GetDeviceHome(ctx, event.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve home of device %s: %w", event.DeviceID, err)
+		}
+		if device != nil {
+			event.HomeID = device.HomeID
+		}
+	}
+
+	return recorder.Store.SaveEvent(ctx, event)
+}
+
+// RecordLogin writes one LOGIN per token (user + iat), however many requests it signs.
+// the event id is derived from the token so other api instances don't write it twice
+func (recorder *Recorder) RecordLogin(ctx context.Context, userID string, issuedAt int64) error {
+	if recorder == nil {
+		return nil
+	}
+
+	key := fmt.Sprintf("login-%s-%d", userID, issuedAt)
+
+	recorder.mu.Lock()
+	if recorder.seenLogins[key] {
+		recorder.mu.Unlock()
+		return nil
+	}
+	if recorder.seenLogins == nil || len(recorder.seenLogins) >= maxSeenLogins {
+		recorder.seenLogins = make(map[string]bool)
+	}
+	recorder.seenLogins[key] = true
+	recorder.mu.Unlock()
+
+	err := recorder.Record(ctx, models.AuditEvent{
+		EventID: key,
+		Action:  ActionLogin,
+		ActorID: userID,
+	})
+	if err != nil && !errors.Is(err, ErrDuplicateEvent) {
+		// try again on the next request
+		recorder.mu.Lock()
+		delete(recorder.seenLogins, key)
+		recorder.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestSaveEventAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAuditStore()
+
+	first := models.AuditEvent{EventID: "aud-1", Timestamp: time.Now().Unix(), Action: ActionRuleCreated, ActorID: "user-1", HomeID: "home-1"}
+	if err := store.SaveEvent(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	rewrite := first
+	rewrite.Action = ActionRuleDeleted
+	if err := store.SaveEvent(ctx, rewrite); !errors.Is(err, ErrDuplicateEvent) {
+		t.Fatalf("rewriting an event: err = %v, want ErrDuplicateEvent", err)
+	}
+
+	page, err := store.QueryEvents(ctx, Query{HomeID: "home-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 1 || page.Events[0].Action != ActionRuleCreated {
+		t.Errorf("events = %+v, want the first event unchanged", page.Events)
+	}
+}
+
+func TestRecordResolvesHome(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAuditStore()
+	homeStore := homes.NewMemoryHomeStore()
+	if _, err := homeStore.ClaimDevice(ctx, models.HomeDevice{DeviceID: "gas-1", HomeID: "home-1"}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewRecorder(store, homeStore)
+
+	if err := recorder.Record(ctx, models.AuditEvent{Action: ActionCommandDispatched, ActorID: "user-1", DeviceID: "gas-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := store.QueryEvents(ctx, Query{HomeID: "home-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 1 || page.Events[0].EventID == "" || page.Events[0].Timestamp == 0 {
+		t.Errorf("events = %+v, want one event filled in under home-1", page.Events)
+	}
+
+	var nilRecorder *Recorder
+	if err := nilRecorder.Record(ctx, models.AuditEvent{Action: ActionLogin}); err != nil {
+		t.Errorf("nil recorder: %v", err)
+	}
+}
+
+// one LOGIN per token, even when another api instance sees the same token
+func TestRecordLogin(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAuditStore()
+	recorder := NewRecorder(store, nil)
+	other := NewRecorder(store, nil)
+
+	calls := []struct {
+		recorder *Recorder
+		issuedAt int64
+	}{
+		{recorder, 1000},
+		{recorder, 1000},
+		{other, 1000},
+		{recorder, 2000},
+	}
+	for _, call := range calls {
+		if err := call.recorder.RecordLogin(ctx, "user-1", call.issuedAt); err != nil {
+			t.Fatalf("login at %d: %v", call.issuedAt, err)
+		}
+	}
+
+	page, err := store.QueryEvents(ctx, Query{ActorID: "user-1", Action: ActionLogin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Events) != 2 {
+		t.Errorf("%d login events, want one per token", len(page.Events))
+	}
+}
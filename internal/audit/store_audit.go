@@ -0,0 +1,231 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const (
+	// kept much longer than the 30 days of command history, AUDIT_RETENTION_DAYS overrides it
+	defaultRetention  = 365 * 24 * time.Hour
+	homeIndex         = "HomeIndex"
+	actorIndex        = "ActorIndex"
+	defaultQueryLimit = 50
+	maxQueryLimit     = 200
+)
+
+var (
+	// ErrDuplicateEvent means an event with the same id is already in the log, it is never overwritten
+	ErrDuplicateEvent = errors.New("audit event already recorded")
+	ErrInvalidQuery   = errors.New("invalid audit query")
+)
+
+// AuditStore is append-only, there is no way to change or remove an event
+type AuditStore interface {
+	SaveEvent(ctx context.Context, event models.AuditEvent) error
+	QueryEvents(ctx context.Context, query Query) (Page, error)
+}
+
+// Query selects events newest first, either of a home or of an actor.
+// the other fields narrow it down, From/To are inclusive unix seconds (0 = open)
+type Query struct {
+	HomeID   string
+	ActorID  string
+	DeviceID string
+	Action   string
+	From     int64
+	To       int64
+	Limit    int32
+	Cursor   string
+}
+
+type Page struct {
+	Events     []models.AuditEvent
+	NextCursor string // empty on the last page
+}
+
+type DynamoAuditStore struct {
+	Client    *dynamodb.Client
+	TableName string
+	Retention time.Duration
+}
+
+func NewAuditStore() (*DynamoAuditStore, error) {
+	tableName := os.Getenv("DYNAMODB_AUDIT_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_AUDIT_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	retention, err := retentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynamoAuditStore{
+		Client:    db.Client,
+		TableName: tableName,
+		Retention: retention,
+	}, nil
+}
+
+func retentionFromEnv() (time.Duration, error) {
+	raw := os.Getenv("AUDIT_RETENTION_DAYS")
+	if raw == "" {
+		return defaultRetention, nil
+	}
+
+	days, err := strconv.Atoi(raw)
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("AUDIT_RETENTION_DAYS must be a positive number of days")
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// the condition keeps the log append-only, an event id is written once
+func (store *DynamoAuditStore) SaveEvent(ctx context.Context, event models.AuditEvent) error {
+	if event.ExpiresAt == 0 {
+		event.ExpiresAt = time.Unix(event.Timestamp, 0).Add(store.Retention).Unix()
+	}
+
+	item, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(store.TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(event_id)"),
+	}
+
+	_, err = store.Client.PutItem(ctx, input)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEvent, event.EventID)
+		}
+		return fmt.Errorf("failed to store audit event in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+// the key of the index the query reads plus the table key
+func cursorKey(query Query) db.CursorKey {
+	if query.HomeID == "" {
+		return db.CursorKey{Partition: "actor_id", Value: query.ActorID, Strings: []string{"event_id"}, Numbers: []string{"timestamp"}}
+	}
+	return db.CursorKey{Partition: "home_id", Value: query.HomeID, Strings: []string{"event_id"}, Numbers: []string{"timestamp"}}
+}
+
+// HomeIndex (home_id + timestamp) or ActorIndex (actor_id + timestamp), the other filters run on the page.
+// a filtered page may hold fewer than Limit events while NextCursor is still set
+func (store *DynamoAuditStore) QueryEvents(ctx context.Context, query Query) (Page, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return Page{}, err
+	}
+
+	startKey, err := db.DecodeCursor(query.Cursor, cursorKey(query))
+	if err != nil {
+		return Page{}, err
+	}
+
+	names := map[string]string{
+		"#ts": "timestamp",
+	}
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberN{Value: fmt.Sprint(query.From)},
+		":to":   &types.AttributeValueMemberN{Value: fmt.Sprint(query.To)},
+	}
+
+	indexName := homeIndex
+	keyCondition := "home_id = :home AND #ts BETWEEN :from AND :to"
+	values[":home"] = &types.AttributeValueMemberS{Value: query.HomeID}
+	if query.HomeID == "" {
+		indexName = actorIndex
+		keyCondition = "actor_id = :actor AND #ts BETWEEN :from AND :to"
+		values[":actor"] = &types.AttributeValueMemberS{Value: query.ActorID}
+		delete(values, ":home")
+	}
+
+	var filters []string
+	if query.HomeID != "" && query.ActorID != "" {
+		filters = append(filters, "actor_id = :actor")
+		values[":actor"] = &types.AttributeValueMemberS{Value: query.ActorID}
+	}
+	if query.DeviceID != "" {
+		filters = append(filters, "device_id = :device")
+		values[":device"] = &types.AttributeValueMemberS{Value: query.DeviceID}
+	}
+	if query.Action != "" {
+		filters = append(filters, "#action = :action")
+		names["#action"] = "action"
+		values[":action"] = &types.AttributeValueMemberS{Value: query.Action}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(store.TableName),
+		IndexName:                 aws.String(indexName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false), // newest first
+		Limit:                     aws.Int32(query.Limit),
+		ExclusiveStartKey:         startKey,
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	res, err := store.Client.Query(ctx, input)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to query audit events: %w", err)
+	}
+
+	page := Page{Events: []models.AuditEvent{}}
+	if err = attributevalue.UnmarshalListOfMaps(res.Items, &page.Events); err != nil {
+		return Page{}, fmt.Errorf("failed to unmarshal audit events: %w", err)
+	}
+
+	page.NextCursor, err = db.EncodeCursor(res.LastEvaluatedKey)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return page, nil
+}
+
+func normalizeQuery(query Query) (Query, error) {
+	if query.HomeID == "" && query.ActorID == "" {
+		return query, fmt.Errorf("%w: a home or an actor is required", ErrInvalidQuery)
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultQueryLimit
+	}
+	if query.Limit > maxQueryLimit {
+		query.Limit = maxQueryLimit
+	}
+	if query.To <= 0 {
+		query.To = math.MaxInt64
+	}
+	return query, nil
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// MemoryAuditStore is an in-process AuditStore used for tests and local demos
+type MemoryAuditStore struct {
+	mu     sync.Mutex
+	events map[string]models.AuditEvent // event_id -> event
+}
+
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{
+		events: make(map[string]models.AuditEvent),
+	}
+}
+
+func (store *MemoryAuditStore) SaveEvent(ctx context.Context, event models.AuditEvent) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.events[event.EventID]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateEvent, event.EventID)
+	}
+	if event.ExpiresAt == 0 {
+		event.ExpiresAt = time.Unix(event.Timestamp, 0).Add(defaultRetention).Unix()
+	}
+	store.events[event.EventID] = event
+	return nil
+}
+
+// same order and cursor shape as the index queries, without the short filtered pages
+func (store *MemoryAuditStore) QueryEvents(ctx context.Context, query Query) (Page, error) {
+	query, err := normalizeQuery(query)
+	if err != nil {
+		return Page{}, err
+	}
+
+	startKey, err := db.DecodeCursor(query.Cursor, cursorKey(query))
+	if err != nil {
+		return Page{}, err
+	}
+	var after models.AuditEvent
+	if startKey != nil {
+		if after, err = parseEventKey(startKey); err != nil {
+			return Page{}, err
+		}
+	}
+
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	matches := make([]models.AuditEvent, 0)
+	for _, event := range store.events {
+		if event.ExpiresAt > 0 && event.ExpiresAt <= now {
+			continue
+		}
+		if event.Timestamp < query.From || event.Timestamp > query.To {
+			continue
+		}
+		if (query.HomeID != "" && event.HomeID != query.HomeID) ||
+			(query.ActorID != "" && event.ActorID != query.ActorID) ||
+			(query.DeviceID != "" && event.DeviceID != query.DeviceID) ||
+			(query.Action != "" && event.Action != query.Action) {
+			continue
+		}
+		matches = append(matches, event)
+	}
+	store.mu.Unlock()
+
+	slices.SortFunc(matches, compareNewestFirst)
+
+	if startKey != nil {
+		idx, _ := slices.BinarySearchFunc(matches, after, compareNewestFirst)
+		for idx < len(matches) && compareNewestFirst(matches[idx], after) <= 0 {
+			idx++
+		}
+		matches = matches[idx:]
+	}
+
+	page := Page{Events: matches}
+	if len(matches) > int(query.Limit) {
+		page.Events = matches[:query.Limit]
+		last := page.Events[len(page.Events)-1]
+		expected := cursorKey(query)
+		page.NextCursor, err = db.EncodeCursor(map[string]types.AttributeValue{
+			expected.Partition: &types.AttributeValueMemberS{Value: expected.Value},
+			"event_id":         &types.AttributeValueMemberS{Value: last.EventID},
+			"timestamp":        &types.AttributeValueMemberN{Value: strconv.FormatInt(last.Timestamp, 10)},
+		})
+		if err != nil {
+			return Page{}, err
+		}
+	}
+
+	return page, nil
+}
+
+func compareNewestFirst(a, b models.AuditEvent) int {
+	if c := cmp.Compare(b.Timestamp, a.Timestamp); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.EventID, a.EventID)
+}
+
+func parseEventKey(key map[string]types.AttributeValue) (models.AuditEvent, error) {
+	id, okID := key["event_id"].(*types.AttributeValueMemberS)
+	ts, okTs := key["timestamp"].(*types.AttributeValueMemberN)
+	if !okID || !okTs {
+		return models.AuditEvent{}, fmt.Errorf("%w: missing key attributes", db.ErrInvalidCursor)
+	}
+	timestamp, err := strconv.ParseInt(ts.Value, 10, 64)
+	if err != nil {
+		return models.AuditEvent{}, fmt.Errorf("%w: bad timestamp", db.ErrInvalidCursor)
+	}
+	return models.AuditEvent{EventID: id.Value, Timestamp: timestamp}, nil
+}
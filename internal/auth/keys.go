@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource resolves the public key a token was signed with from its "kid" header
+type KeySource interface {
+	PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517), only RSA keys are used
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// RSA keys of the set by kid, other key types are skipped
+func (set JWKS) RSAKeys() (map[string]*rsa.PublicKey, error) {
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// the JWK of a public key, used to write the local JWKS of tests and local runs
+func NewJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// StaticKeySource serves a fixed set of keys, loaded from a local JWKS file for tests and local runs
+type StaticKeySource struct {
+	Keys map[string]*rsa.PublicKey
+}
+
+func NewFileKeySource(path string) (*StaticKeySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks file: %w", err)
+	}
+
+	keys, err := set.RSAKeys()
+	if err != nil {
+		return nil, err
+	}
+	return &StaticKeySource{Keys: keys}, nil
+}
+
+func (source *StaticKeySource) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	key, exists := source.Keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+const (
+	jwksCacheTTL      = time.Hour
+	jwksRefreshMinGap = time.Minute // an unknown kid can't make us hammer the identity provider
+)
+
+// RemoteKeySource fetches the JWKS of the identity provider (Cognito, Auth0...) and caches it.
+// an unknown kid triggers a refresh, so rotated keys are picked up without a restart. only one
+// fetch runs at a time, outside the lock, the requests needing it wait for that one
+type RemoteKeySource struct {
+	Client *http.Client
+	URL    string
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	inFlight  *jwksFetch
+}
+
+// a fetch in flight, err is set before done is closed
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func NewRemoteKeySource(url string) *RemoteKeySource {
+	return &RemoteKeySource{
+		Client: &http.Client{Timeout: 5 * time.Second},
+		URL:    url,
+	}
+}
+
+func (source *RemoteKeySource) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	source.mu.Lock()
+	key, exists := source.keys[kid]
+	if exists && time.Since(source.fetchedAt) < jwksCacheTTL {
+		source.mu.Unlock()
+		return key, nil
+	}
+	if source.keys != nil && time.Since(source.triedAt) < jwksRefreshMinGap {
+		source.mu.Unlock()
+		return cachedKey(key, exists, kid, nil)
+	}
+
+	fetch := source.inFlight
+	if fetch == nil {
+		fetch = &jwksFetch{done: make(chan struct{})}
+		source.inFlight = fetch
+		// not tied to this request, a caller giving up must not fail the others waiting on it
+		go source.refresh(fetch)
+	}
+	source.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	source.mu.Lock()
+	key, exists = source.keys[kid]
+	source.mu.Unlock()
+	return cachedKey(key, exists, kid, fetch.err)
+}
+
+// a key kept from before a failed fetch is stale but still trusted, better than locking every user out
+func cachedKey(key *rsa.PublicKey, exists bool, kid string, fetchErr error) (*rsa.PublicKey, error) {
+	switch {
+	case exists:
+		return key, nil
+	case fetchErr != nil:
+		return nil, fetchErr
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+func (source *RemoteKeySource) refresh(fetch *jwksFetch) {
+	keys, err := source.fetch(context.Background())
+
+	source.mu.Lock()
+	source.triedAt = time.Now()
+	if err == nil {
+		source.keys = keys
+		source.fetchedAt = source.triedAt
+	}
+	source.inFlight = nil
+	source.mu.Unlock()
+
+	fetch.err = err
+	close(fetch.done)
+}
+
+// the client timeout bounds the fetch
+func (source *RemoteKeySource) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jwks request: %w", err)
+	}
+
+	res, err := source.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", res.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+	return set.RSAKeys()
+}
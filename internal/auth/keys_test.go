@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileKeySource(t *testing.T) {
+	key := signingKey(t)
+	set := JWKS{Keys: []JWK{
+		NewJWK("key-1", &key.PublicKey),
+		{Kty: "EC", Kid: "ec-key"},                             // skipped, not RSA
+		{Kty: "RSA", Kid: "enc-key", Use: "enc", N: "", E: ""}, // skipped, not for signatures
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewFileKeySource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(source.Keys) != 1 || !source.Keys["key-1"].Equal(&key.PublicKey) {
+		t.Fatalf("keys = %v, want key-1 only", source.Keys)
+	}
+	if _, err := source.PublicKey(context.Background(), "ec-key"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("PublicKey(ec-key) = %v, want ErrUnknownKey", err)
+	}
+}
+
+// a JWKS endpoint serving keys, counting the fetches
+type jwksServer struct {
+	*httptest.Server
+	keys    atomic.Value // JWKS
+	fetches atomic.Int32
+	fail    atomic.Bool
+	release chan struct{} // when set, requests wait for it
+}
+
+func newJWKSServer(t *testing.T, set JWKS, release chan struct{}) *jwksServer {
+	server := &jwksServer{release: release}
+	server.keys.Store(set)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.fetches.Add(1)
+		if server.release != nil {
+			<-server.release
+		}
+		if server.fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(server.keys.Load())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRemoteKeySource(t *testing.T) {
+	key := signingKey(t)
+	server := newJWKSServer(t, JWKS{Keys: []JWK{NewJWK("key-1", &key.PublicKey)}}, nil)
+	source := NewRemoteKeySource(server.URL)
+	ctx := context.Background()
+
+	got, err := source.PublicKey(ctx, "key-1")
+	if err != nil || !got.Equal(&key.PublicKey) {
+		t.Fatalf("PublicKey(key-1) = %v, %v", got, err)
+	}
+	if _, err := source.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("%d fetches, want the cached set reused", fetches)
+	}
+
+	// an unknown kid right after a fetch doesn't refetch, the min gap protects the provider
+	if _, err := source.PublicKey(ctx, "key-2"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("PublicKey(key-2) = %v, want ErrUnknownKey", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("%d fetches, want none for an unknown kid within the min gap", fetches)
+	}
+}
+
+func TestRemoteKeySourceRotation(t *testing.T) {
+	key := signingKey(t)
+	server := newJWKSServer(t, JWKS{Keys: []JWK{NewJWK("key-1", &key.PublicKey)}}, nil)
+	source := NewRemoteKeySource(server.URL)
+	ctx := context.Background()
+
+	if _, err := source.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the provider rotated, the old set was fetched longer ago than the min gap
+	server.keys.Store(JWKS{Keys: []JWK{NewJWK("key-2", &key.PublicKey)}})
+	source.mu.Lock()
+	source.triedAt = source.triedAt.Add(-2 * jwksRefreshMinGap)
+	source.mu.Unlock()
+
+	if _, err := source.PublicKey(ctx, "key-2"); err != nil {
+		t.Errorf("PublicKey(key-2) after rotation = %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("%d fetches, want the unknown kid to refresh once", fetches)
+	}
+}
+
+// a failed refresh keeps serving the keys already known
+func TestRemoteKeySourceStaleOnFailure(t *testing.T) {
+	key := signingKey(t)
+	server := newJWKSServer(t, JWKS{Keys: []JWK{NewJWK("key-1", &key.PublicKey)}}, nil)
+	source := NewRemoteKeySource(server.URL)
+	ctx := context.Background()
+
+	if _, err := source.PublicKey(ctx, "key-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.fail.Store(true)
+	source.mu.Lock()
+	source.fetchedAt = source.fetchedAt.Add(-2 * jwksCacheTTL)
+	source.triedAt = source.fetchedAt
+	source.mu.Unlock()
+
+	if _, err := source.PublicKey(ctx, "key-1"); err != nil {
+		t.Errorf("PublicKey(key-1) with the provider down = %v, want the stale key", err)
+	}
+	if _, err := source.PublicKey(ctx, "key-3"); err == nil {
+		t.Error("PublicKey(key-3) with the provider down succeeded")
+	}
+}
+
+func TestRemoteKeySourceSingleFetch(t *testing.T) {
+	key := signingKey(t)
+	release := make(chan struct{})
+	server := newJWKSServer(t, JWKS{Keys: []JWK{NewJWK("key-1", &key.PublicKey)}}, release)
+	source := NewRemoteKeySource(server.URL)
+
+	// a caller giving up doesn't cancel the fetch the others wait on
+	cancelled, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := source.PublicKey(cancelled, "key-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PublicKey with a cancelled ctx = %v, want DeadlineExceeded", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := source.PublicKey(context.Background(), "key-1")
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("PublicKey = %v", err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Errorf("%d fetches, want one shared by every caller", fetches)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userIDKey = "auth.user_id"
+	claimsKey = "auth.claims"
+)
+
+// Middleware rejects requests without a valid bearer token and stores the caller's id for the handlers
+func Middleware(verifier *Verifier) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		token, ok := bearerToken(context)
+		if !ok {
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims, err := verifier.Verify(context.Request.Context(), token)
+		if err != nil {
+			message := "invalid token"
+			if errors.Is(err, ErrExpiredToken) {
+				message = "token expired"
+			}
+			context.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+			return
+		}
+
+		context.Set(userIDKey, claims.Subject)
+		context.Set(claimsKey, claims)
+		context.Next()
+	}
+}
+
+// the "sub" of the verified token, empty outside the middleware
+func UserID(context *gin.Context) string {
+	return context.GetString(userIDKey)
+}
+
+// the whole verified token, nil outside the middleware
+func TokenClaims(context *gin.Context) *Claims {
+	claims, _ := context.Get(claimsKey)
+	verified, _ := claims.(*Claims)
+	return verified
+}
+
+// EventSource and browser WebSockets can't set headers, so /stream also takes ?access_token=.
+// other routes don't, query strings end up in access logs
+func bearerToken(context *gin.Context) (string, bool) {
+	header := context.GetHeader("Authorization")
+	if token, found := strings.CutPrefix(header, "Bearer "); found && token != "" {
+		return token, true
+	}
+	if strings.HasSuffix(context.FullPath(), "/stream") {
+		if token := context.Query("access_token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	keyOnce    sync.Once
+	testKey    *rsa.PrivateKey
+	testKeyErr error
+)
+
+// one key for the package, generating RSA keys is slow
+func signingKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	keyOnce.Do(func() {
+		testKey, testKeyErr = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if testKeyErr != nil {
+		t.Fatal(testKeyErr)
+	}
+	return testKey
+}
+
+var verifyNow = time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+
+func testVerifier(t *testing.T) *Verifier {
+	return &Verifier{
+		Keys:     &StaticKeySource{Keys: map[string]*rsa.PublicKey{"key-1": &signingKey(t).PublicKey}},
+		Issuer:   "https://issuer.example",
+		Audience: "fleexa-app",
+		Now:      func() time.Time { return verifyNow },
+	}
+}
+
+func validClaims() Claims {
+	return Claims{
+		Subject:   "user-1",
+		Issuer:    "https://issuer.example",
+		Audience:  Audience{"fleexa-app"},
+		ExpiresAt: verifyNow.Add(time.Hour).Unix(),
+		IssuedAt:  verifyNow.Unix(),
+	}
+}
+
+func sign(t *testing.T, kid string, claims Claims) string {
+	t.Helper()
+	token, err := SignToken(signingKey(t), kid, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// a token with any header, signed or not, to try what Verify must refuse
+func forge(t *testing.T, head map[string]string, claims Claims, signature string) string {
+	t.Helper()
+	rawHead, err := json.Marshal(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawClaims, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(rawHead) + "." + base64.RawURLEncoding.EncodeToString(rawClaims) + "." + signature
+}
+
+func TestVerify(t *testing.T) {
+	claims, err := testVerifier(t).Verify(context.Background(), sign(t, "key-1", validClaims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("subject = %q, want user-1", claims.Subject)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	good := sign(t, "key-1", validClaims())
+	parts := strings.Split(good, ".")
+
+	other := validClaims()
+	other.Subject = "user-2"
+	otherParts := strings.Split(sign(t, "key-1", other), ".")
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		want  error
+	}{
+		{"malformed", func(t *testing.T) string { return "not-a-token" }, ErrInvalidToken},
+		{"alg none", func(t *testing.T) string {
+			return forge(t, map[string]string{"alg": "none", "kid": "key-1"}, validClaims(), "")
+		}, ErrInvalidToken},
+		{"alg HS256", func(t *testing.T) string {
+			return forge(t, map[string]string{"alg": "HS256", "kid": "key-1"}, validClaims(), parts[2])
+		}, ErrInvalidToken},
+		{"unknown kid", func(t *testing.T) string { return sign(t, "key-2", validClaims()) }, ErrInvalidToken},
+		{"claims swapped", func(t *testing.T) string { return parts[0] + "." + otherParts[1] + "." + parts[2] }, ErrInvalidToken},
+		{"expired", func(t *testing.T) string {
+			claims := validClaims()
+			claims.ExpiresAt = verifyNow.Add(-2 * time.Minute).Unix()
+			return sign(t, "key-1", claims)
+		}, ErrExpiredToken},
+		{"not valid yet", func(t *testing.T) string {
+			claims := validClaims()
+			claims.NotBefore = verifyNow.Add(5 * time.Minute).Unix()
+			return sign(t, "key-1", claims)
+		}, ErrInvalidToken},
+		{"no exp", func(t *testing.T) string {
+			claims := validClaims()
+			claims.ExpiresAt = 0
+			return sign(t, "key-1", claims)
+		}, ErrInvalidToken},
+		{"no sub", func(t *testing.T) string {
+			claims := validClaims()
+			claims.Subject = ""
+			return sign(t, "key-1", claims)
+		}, ErrInvalidToken},
+		{"wrong issuer", func(t *testing.T) string {
+			claims := validClaims()
+			claims.Issuer = "https://elsewhere.example"
+			return sign(t, "key-1", claims)
+		}, ErrInvalidToken},
+		{"wrong audience", func(t *testing.T) string {
+			claims := validClaims()
+			claims.Audience = Audience{"other-app"}
+			return sign(t, "key-1", claims)
+		}, ErrInvalidToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testVerifier(t).Verify(context.Background(), test.token(t))
+			if !errors.Is(err, test.want) {
+				t.Errorf("Verify = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownKid(t *testing.T) {
+	_, err := testVerifier(t).Verify(context.Background(), sign(t, "key-2", validClaims()))
+	if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), "key-2") {
+		t.Errorf("Verify = %v, want ErrInvalidToken naming key-2", err)
+	}
+}
+
+// exp is allowed clockLeeway of drift between the issuer and us
+func TestVerifyLeeway(t *testing.T) {
+	claims := validClaims()
+	claims.ExpiresAt = verifyNow.Add(-30 * time.Second).Unix()
+	if _, err := testVerifier(t).Verify(context.Background(), sign(t, "key-1", claims)); err != nil {
+		t.Errorf("token expired 30s ago: %v, want accepted", err)
+	}
+}
+
+func TestAudienceForms(t *testing.T) {
+	for _, raw := range []string{`"fleexa-app"`, `["other-app","fleexa-app"]`} {
+		var audience Audience
+		if err := json.Unmarshal([]byte(raw), &audience); err != nil {
+			t.Fatalf("unmarshal %s: %v", raw, err)
+		}
+		if len(audience) == 0 || audience[len(audience)-1] != "fleexa-app" {
+			t.Errorf("unmarshal %s = %v", raw, audience)
+		}
+	}
+
+	var audience Audience
+	if err := json.Unmarshal([]byte(`42`), &audience); err == nil {
+		t.Error("numeric aud accepted")
+	}
+}
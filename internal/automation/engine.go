@@ -0,0 +1,157 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/weather"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// Engine runs the automation rules of a device every time ingestion derives its operational state
+type Engine struct {
+	Logger     *slog.Logger
+	Rules      RuleStore
+	StateStore devices.StateStore
+	Dispatcher *commands.Dispatcher
+	Homes      homes.HomeStore  // where each device is and who may still command it
+	Weather    weather.Provider // optional, rules with a weather condition don't fire without it
+}
+
+func (engine *Engine) Evaluate(ctx context.Context, deviceID string, opState string, at int64) error {
+	ruleList, err := engine.Rules.GetRulesByTrigger(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range ruleList {
+		if !rule.Enabled {
+			continue
+		}
+		if err := engine.evaluateRule(ctx, rule, opState, at); err != nil {
+			// one broken rule must not block the others
+			engine.Logger.Error("automation rule evaluation failed", "rule_id", rule.RuleID, "device_id", deviceID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (engine *Engine) evaluateRule(ctx context.Context, rule models.AutomationRule, opState string, at int64) error {
+	decision := Evaluate(rule, opState, at)
+
+	if decision.ConditionSince != rule.ConditionSince {
+		if err := engine.Rules.SetConditionSince(ctx, rule.RuleID, decision.ConditionSince); err != nil {
+			return err
+		}
+	}
+	if !decision.Fire {
+		return nil
+	}
+
+	// not firing on the weather keeps the episode open, the next message of the trigger checks it again
+	var outside *models.Weather
+	if rule.Weather != nil {
+		report, err := engine.outdoorWeather(ctx, rule.Trigger.DeviceID)
+		if err != nil {
+			engine.Logger.Warn("automation rule skipped, outdoor weather unavailable", "rule_id", rule.RuleID, "error", err)
+			return nil
+		}
+		if !WeatherAllows(*rule.Weather, *report) {
+			return nil
+		}
+		outside = report
+	}
+
+	claimed, err := engine.Rules.ClaimFiring(ctx, rule.RuleID, rule.LastFiredAt, at)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		engine.Logger.Info("automation rule already fired by another invocation", "rule_id", rule.RuleID)
+		return nil
+	}
+
+	firing := models.AutomationFiring{
+		RuleID:          rule.RuleID,
+		Timestamp:       at,
+		TriggerDeviceID: rule.Trigger.DeviceID,
+		TriggerState:    opState,
+		TargetDeviceID:  rule.Action.DeviceID,
+		Action:          rule.Action.Action,
+		Status:          FiringDispatched,
+	}
+	if outside != nil {
+		firing.OutsideTemp = &outside.Temperature
+	}
+
+	cmd, dispatchErr := engine.dispatch(ctx, rule)
+	firing.RequestID = cmd.RequestID
+	if errors.Is(dispatchErr, homes.ErrCommandRevoked) {
+		// the creator lost the permission or a device changed home, the rule stops for good
+		engine.Logger.Warn("disabling automation rule, command no longer allowed", "rule_id", rule.RuleID, "created_by", rule.CreatedBy, "error", dispatchErr)
+		if err := engine.Rules.DisableRule(ctx, rule.RuleID, at); err != nil {
+			return err
+		}
+	}
+	if dispatchErr != nil {
+		engine.Logger.Error("failed to dispatch automation command", "rule_id", rule.RuleID, "device_id", rule.Action.DeviceID, "error", dispatchErr)
+		firing.Status = FiringFailed
+		firing.Error = dispatchErr.Error()
+	} else {
+		engine.Logger.Info("automation rule fired", "rule_id", rule.RuleID, "device_id", rule.Action.DeviceID, "request_id", cmd.RequestID)
+	}
+
+	return engine.Rules.SaveFiring(ctx, firing)
+}
+
+func (engine *Engine) dispatch(ctx context.Context, rule models.AutomationRule) (models.Command, error) {
+	state, err := engine.StateStore.GetStateByID(ctx, rule.Action.DeviceID)
+	if err != nil {
+		return models.Command{}, err
+	}
+	if !devices.Active(state) {
+		return models.Command{}, fmt.Errorf("device %s not found or decommissioned", rule.Action.DeviceID)
+	}
+
+	// the trigger is read by its creator too, it must still be in a home they belong to
+	visible, err := homes.CanAccessDevice(ctx, engine.Homes, rule.CreatedBy, rule.Trigger.DeviceID)
+	if err != nil {
+		return models.Command{}, err
+	}
+	if !visible {
+		return models.Command{}, fmt.Errorf("%w: trigger %s is no longer in a home of %s", homes.ErrCommandRevoked, rule.Trigger.DeviceID, rule.CreatedBy)
+	}
+	err = homes.AuthorizeCommand(ctx, engine.Homes, rule.HomeID, rule.CreatedBy, rule.Action.DeviceID, state.Type, rule.Action.Action)
+	if err != nil {
+		return models.Command{}, err
+	}
+
+	return engine.Dispatcher.Dispatch(ctx, commands.Request{
+		DeviceID:   rule.Action.DeviceID,
+		DeviceType: state.Type,
+		Action:     rule.Action.Action,
+		Parameters: rule.Action.Parameters,
+		IssuedBy:   "automation:" + rule.RuleID,
+	})
+}
+
+// the weather at the home of the trigger device
+func (engine *Engine) outdoorWeather(ctx context.Context, deviceID string) (*models.Weather, error) {
+	if engine.Weather == nil {
+		return nil, fmt.Errorf("no weather provider configured")
+	}
+	homeDevice, err := engine.Homes.GetDeviceHome(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if homeDevice == nil {
+		return nil, fmt.Errorf("device %s is in no home", deviceID)
+	}
+	return weather.AtHome(ctx, engine.Weather, engine.Homes, homeDevice.HomeID)
+}
@@ -0,0 +1,127 @@
+package automation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// firing outcomes kept in the audit trail
+const (
+	FiringDispatched = "DISPATCHED"
+	FiringFailed     = "FAILED"
+
+	DefaultCooldownSeconds = 300
+)
+
+var ErrInvalidRule = errors.New("invalid automation rule")
+
+// checks the fields a rule needs to be evaluated and fills the defaults,
+// the action itself is validated against the target device type by the caller
+func Prepare(rule *models.AutomationRule) error {
+	switch {
+	case rule.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	case rule.Trigger.DeviceID == "":
+		return fmt.Errorf("%w: trigger.device_id is required", ErrInvalidRule)
+	case rule.Trigger.OperationalState == "":
+		return fmt.Errorf("%w: trigger.operational_state is required", ErrInvalidRule)
+	case rule.Trigger.ForSeconds < 0:
+		return fmt.Errorf("%w: trigger.for_seconds must not be negative", ErrInvalidRule)
+	case rule.Action.DeviceID == "":
+		return fmt.Errorf("%w: action.device_id is required", ErrInvalidRule)
+	case rule.CooldownSeconds < 0:
+		return fmt.Errorf("%w: cooldown_seconds must not be negative", ErrInvalidRule)
+	}
+
+	if err := validateWeather(rule.Weather); err != nil {
+		return err
+	}
+
+	if rule.CooldownSeconds == 0 {
+		rule.CooldownSeconds = DefaultCooldownSeconds
+	}
+	rule.TriggerDeviceID = rule.Trigger.DeviceID
+
+	return nil
+}
+
+// Decision is what a rule does with a new operational state of its trigger device
+type Decision struct {
+	Fire           bool
+	ConditionSince int64 // new debounce start, 0 when the trigger state no longer holds
+}
+
+// the trigger state must hold for ForSeconds before firing, a rule fires once per
+// episode and never sooner than CooldownSeconds after its previous firing
+func Evaluate(rule models.AutomationRule, opState string, at int64) Decision {
+	if opState != rule.Trigger.OperationalState {
+		return Decision{}
+	}
+
+	since := rule.ConditionSince
+	if since == 0 || since > at {
+		since = at
+	}
+	decision := Decision{ConditionSince: since}
+
+	if at-since < rule.Trigger.ForSeconds {
+		return decision
+	}
+	if rule.LastFiredAt >= since {
+		return decision // already fired for this episode
+	}
+
+	cooldown := rule.CooldownSeconds
+	if cooldown == 0 {
+		cooldown = DefaultCooldownSeconds
+	}
+	if rule.LastFiredAt > 0 && at-rule.LastFiredAt < cooldown {
+		return decision
+	}
+
+	decision.Fire = true
+	return decision
+}
+
+func validateWeather(condition *models.AutomationWeather) error {
+	if condition == nil {
+		return nil
+	}
+	bounds := []struct {
+		above *float64
+		below *float64
+		name  string
+	}{
+		{condition.OutsideTempAbove, condition.OutsideTempBelow, "outside_temp"},
+		{condition.HumidityAbove, condition.HumidityBelow, "humidity"},
+	}
+
+	set := false
+	for _, bound := range bounds {
+		if bound.above != nil && bound.below != nil && *bound.above >= *bound.below {
+			return fmt.Errorf("%w: weather.%s_above must be lower than weather.%s_below", ErrInvalidRule, bound.name, bound.name)
+		}
+		set = set || bound.above != nil || bound.below != nil
+	}
+	if !set {
+		return fmt.Errorf("%w: weather needs at least one bound", ErrInvalidRule)
+	}
+	return nil
+}
+
+// WeatherAllows tells if the outdoor weather is within every bound of the condition, bounds are exclusive
+func WeatherAllows(condition models.AutomationWeather, report models.Weather) bool {
+	switch {
+	case condition.OutsideTempAbove != nil && report.Temperature <= *condition.OutsideTempAbove:
+		return false
+	case condition.OutsideTempBelow != nil && report.Temperature >= *condition.OutsideTempBelow:
+		return false
+	case condition.HumidityAbove != nil && report.Humidity <= *condition.HumidityAbove:
+		return false
+	case condition.HumidityBelow != nil && report.Humidity >= *condition.HumidityBelow:
+		return false
+	}
+	return true
+}
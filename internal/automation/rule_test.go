@@ -0,0 +1,86 @@
+package automation
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	hot := func(since int64, lastFired int64) models.AutomationRule {
+		return models.AutomationRule{
+			Trigger:         models.AutomationTrigger{DeviceID: "temp-1", OperationalState: "HOT", ForSeconds: 60},
+			CooldownSeconds: 300,
+			ConditionSince:  since,
+			LastFiredAt:     lastFired,
+		}
+	}
+	tests := []struct {
+		name    string
+		rule    models.AutomationRule
+		opState string
+		at      int64
+		want    Decision
+	}{
+		{"first HOT starts the debounce", hot(0, 0), "HOT", 1000, Decision{ConditionSince: 1000}},
+		{"still debouncing", hot(1000, 0), "HOT", 1059, Decision{ConditionSince: 1000}},
+		{"held for ForSeconds", hot(1000, 0), "HOT", 1060, Decision{Fire: true, ConditionSince: 1000}},
+		{"another state clears the debounce", hot(1000, 0), "NORMAL", 1030, Decision{}},
+		{"a reading before the episode moves its start", hot(1000, 0), "HOT", 900, Decision{ConditionSince: 900}},
+		{"once per episode", hot(1000, 1060), "HOT", 2000, Decision{ConditionSince: 1000}},
+		{"new episode within the cooldown", hot(1100, 1060), "HOT", 1200, Decision{ConditionSince: 1100}},
+		{"new episode after the cooldown", hot(1400, 1060), "HOT", 1460, Decision{Fire: true, ConditionSince: 1400}},
+		{"default cooldown", models.AutomationRule{Trigger: models.AutomationTrigger{OperationalState: "HOT"}, ConditionSince: 1100, LastFiredAt: 1000}, "HOT", 1200, Decision{ConditionSince: 1100}},
+	}
+	for _, test := range tests {
+		if got := Evaluate(test.rule, test.opState, test.at); got != test.want {
+			t.Errorf("%s: Evaluate = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+// two invocations handling the same message both decide to fire, only one may claim it
+func TestClaimFiringRace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRuleStore()
+	if err := store.SaveRule(ctx, models.AutomationRule{RuleID: "rule-1", Enabled: true, LastFiredAt: 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	const invocations = 8
+	var wg sync.WaitGroup
+	claims := make(chan bool, invocations)
+	for range invocations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := store.ClaimFiring(ctx, "rule-1", 500, 1000)
+			if err != nil {
+				t.Error(err)
+			}
+			claims <- claimed
+		}()
+	}
+	wg.Wait()
+	close(claims)
+
+	won := 0
+	for claimed := range claims {
+		if claimed {
+			won++
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d invocations claimed the firing, want exactly 1", won)
+	}
+
+	rule, _ := store.GetRule(ctx, "rule-1")
+	if rule.LastFiredAt != 1000 {
+		t.Errorf("last fired at %d, want 1000", rule.LastFiredAt)
+	}
+	if claimed, _ := store.ClaimFiring(ctx, "rule-1", 500, 1001); claimed {
+		t.Error("a claim with a stale last_fired_at succeeded")
+	}
+}
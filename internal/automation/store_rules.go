@@ -0,0 +1,294 @@
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const (
+	triggerDeviceIndex  = "TriggerDeviceIndex"
+	firingTTL           = 90 * 24 * time.Hour
+	defaultFiringsLimit = 50
+)
+
+// RuleStore keeps the automation rules (keyed by rule_id) and their firing history (rule_id + timestamp)
+type RuleStore interface {
+	SaveRule(ctx context.Context, rule models.AutomationRule) error
+	GetRule(ctx context.Context, ruleID string) (*models.AutomationRule, error)
+	ListRules(ctx context.Context) ([]models.AutomationRule, error)
+	DeleteRule(ctx context.Context, ruleID string) error
+	GetRulesByTrigger(ctx context.Context, deviceID string) ([]models.AutomationRule, error)
+	SetConditionSince(ctx context.Context, ruleID string, since int64) error
+	DisableRule(ctx context.Context, ruleID string, at int64) error
+	ClaimFiring(ctx context.Context, ruleID string, previousFiredAt int64, firedAt int64) (bool, error)
+	SaveFiring(ctx context.Context, firing models.AutomationFiring) error
+	GetFirings(ctx context.Context, ruleID string, limit int32) ([]models.AutomationFiring, error)
+}
+
+type DynamoRuleStore struct {
+	Client           *dynamodb.Client
+	TableName        string
+	HistoryTableName string
+}
+
+func NewRuleStore() (*DynamoRuleStore, error) {
+	tableName := os.Getenv("DYNAMODB_AUTOMATIONS_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_AUTOMATIONS_TABLE environment variable is not set")
+	}
+
+	historyTableName := os.Getenv("DYNAMODB_AUTOMATION_HISTORY_TABLE")
+	if historyTableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_AUTOMATION_HISTORY_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoRuleStore{
+		Client:           db.Client,
+		TableName:        tableName,
+		HistoryTableName: historyTableName,
+	}, nil
+}
+
+func (store *DynamoRuleStore) SaveRule(ctx context.Context, rule models.AutomationRule) error {
+	rule.TriggerDeviceID = rule.Trigger.DeviceID
+
+	item, err := attributevalue.MarshalMap(rule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal automation rule: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store automation rule in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoRuleStore) GetRule(ctx context.Context, ruleID string) (*models.AutomationRule, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get automation rule %s: %w", ruleID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var rule models.AutomationRule
+	if err = attributevalue.UnmarshalMap(result.Item, &rule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal automation rule %s: %w", ruleID, err)
+	}
+
+	return &rule, nil
+}
+
+func (store *DynamoRuleStore) ListRules(ctx context.Context) ([]models.AutomationRule, error) {
+	ruleList := []models.AutomationRule{}
+
+	paginator := dynamodb.NewScanPaginator(store.Client, &dynamodb.ScanInput{
+		TableName: aws.String(store.TableName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan automation rules: %w", err)
+		}
+
+		var items []models.AutomationRule
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal automation rules: %w", err)
+		}
+		ruleList = append(ruleList, items...)
+	}
+
+	return ruleList, nil
+}
+
+func (store *DynamoRuleStore) DeleteRule(ctx context.Context, ruleID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete automation rule %s: %w", ruleID, err)
+	}
+	return nil
+}
+
+// rules watching a device, from the TriggerDeviceIndex GSI
+func (store *DynamoRuleStore) GetRulesByTrigger(ctx context.Context, deviceID string) ([]models.AutomationRule, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		IndexName:              aws.String(triggerDeviceIndex),
+		KeyConditionExpression: aws.String("trigger_device_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	}
+
+	ruleList := []models.AutomationRule{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query automation rules for device %s: %w", deviceID, err)
+		}
+
+		var items []models.AutomationRule
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal automation rules for device %s: %w", deviceID, err)
+		}
+		ruleList = append(ruleList, items...)
+	}
+
+	return ruleList, nil
+}
+
+// debounce bookkeeping, a rule deleted in the meantime is left deleted
+func (store *DynamoRuleStore) SetConditionSince(ctx context.Context, ruleID string, since int64) error {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+		ConditionExpression: aws.String("attribute_exists(rule_id)"),
+		UpdateExpression:    aws.String("SET condition_since = :since"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":since": &types.AttributeValueMemberN{Value: fmt.Sprint(since)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to update automation rule %s: %w", ruleID, err)
+	}
+	return nil
+}
+
+// a rule deleted in the meantime is left deleted
+func (store *DynamoRuleStore) DisableRule(ctx context.Context, ruleID string, at int64) error {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+		ConditionExpression: aws.String("attribute_exists(rule_id)"),
+		UpdateExpression:    aws.String("SET #enabled = :false, condition_since = :zero, updated_at = :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#enabled": "enabled",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":false": &types.AttributeValueMemberBOOL{Value: false},
+			":zero":  &types.AttributeValueMemberN{Value: "0"},
+			":at":    &types.AttributeValueMemberN{Value: fmt.Sprint(at)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to disable automation rule %s: %w", ruleID, err)
+	}
+	return nil
+}
+
+// conditional on last_fired_at so concurrent ingestion invocations fire a rule only once
+func (store *DynamoRuleStore) ClaimFiring(ctx context.Context, ruleID string, previousFiredAt int64, firedAt int64) (bool, error) {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"rule_id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+		ConditionExpression: aws.String("last_fired_at = :previous"),
+		UpdateExpression:    aws.String("SET last_fired_at = :fired_at"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":previous": &types.AttributeValueMemberN{Value: fmt.Sprint(previousFiredAt)},
+			":fired_at": &types.AttributeValueMemberN{Value: fmt.Sprint(firedAt)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim automation rule %s: %w", ruleID, err)
+	}
+	return true, nil
+}
+
+func (store *DynamoRuleStore) SaveFiring(ctx context.Context, firing models.AutomationFiring) error {
+	if firing.ExpiresAt == 0 {
+		firing.ExpiresAt = time.Now().Add(firingTTL).Unix()
+	}
+
+	item, err := attributevalue.MarshalMap(firing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal automation firing: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.HistoryTableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store automation firing in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+// newest firings first
+func (store *DynamoRuleStore) GetFirings(ctx context.Context, ruleID string, limit int32) ([]models.AutomationFiring, error) {
+	if limit <= 0 {
+		limit = defaultFiringsLimit
+	}
+
+	res, err := store.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(store.HistoryTableName),
+		KeyConditionExpression: aws.String("rule_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: ruleID},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query firings of rule %s: %w", ruleID, err)
+	}
+
+	firings := []models.AutomationFiring{}
+	if err = attributevalue.UnmarshalListOfMaps(res.Items, &firings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal firings of rule %s: %w", ruleID, err)
+	}
+
+	return firings, nil
+}
@@ -0,0 +1,172 @@
+package automation
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MemoryRuleStore is an in-process RuleStore used for tests and local demos
+type MemoryRuleStore struct {
+	mu      sync.Mutex
+	rules   map[string]models.AutomationRule
+	firings map[string][]models.AutomationFiring // rule_id -> firings
+}
+
+func NewMemoryRuleStore() *MemoryRuleStore {
+	return &MemoryRuleStore{
+		rules:   make(map[string]models.AutomationRule),
+		firings: make(map[string][]models.AutomationFiring),
+	}
+}
+
+func (store *MemoryRuleStore) SaveRule(ctx context.Context, rule models.AutomationRule) error {
+	rule.TriggerDeviceID = rule.Trigger.DeviceID
+	rule.Action.Parameters = maps.Clone(rule.Action.Parameters)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.rules[rule.RuleID] = rule
+	return nil
+}
+
+func (store *MemoryRuleStore) GetRule(ctx context.Context, ruleID string) (*models.AutomationRule, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rule, exists := store.rules[ruleID]
+	if !exists {
+		return nil, nil
+	}
+	rule.Action.Parameters = maps.Clone(rule.Action.Parameters)
+	return &rule, nil
+}
+
+func (store *MemoryRuleStore) ListRules(ctx context.Context) ([]models.AutomationRule, error) {
+	return store.collect(func(rule models.AutomationRule) bool { return true }), nil
+}
+
+func (store *MemoryRuleStore) DeleteRule(ctx context.Context, ruleID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.rules, ruleID)
+	return nil
+}
+
+func (store *MemoryRuleStore) GetRulesByTrigger(ctx context.Context, deviceID string) ([]models.AutomationRule, error) {
+	return store.collect(func(rule models.AutomationRule) bool {
+		return rule.TriggerDeviceID == deviceID
+	}), nil
+}
+
+func (store *MemoryRuleStore) SetConditionSince(ctx context.Context, ruleID string, since int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rule, exists := store.rules[ruleID]
+	if !exists {
+		return nil
+	}
+	rule.ConditionSince = since
+	store.rules[ruleID] = rule
+	return nil
+}
+
+func (store *MemoryRuleStore) DisableRule(ctx context.Context, ruleID string, at int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rule, exists := store.rules[ruleID]
+	if !exists {
+		return nil
+	}
+	rule.Enabled = false
+	rule.ConditionSince = 0
+	rule.UpdatedAt = at
+	store.rules[ruleID] = rule
+	return nil
+}
+
+func (store *MemoryRuleStore) ClaimFiring(ctx context.Context, ruleID string, previousFiredAt int64, firedAt int64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	rule, exists := store.rules[ruleID]
+	if !exists || rule.LastFiredAt != previousFiredAt {
+		return false, nil
+	}
+	rule.LastFiredAt = firedAt
+	store.rules[ruleID] = rule
+	return true, nil
+}
+
+func (store *MemoryRuleStore) SaveFiring(ctx context.Context, firing models.AutomationFiring) error {
+	if firing.ExpiresAt == 0 {
+		firing.ExpiresAt = time.Now().Add(firingTTL).Unix()
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// same key as the table (rule_id + timestamp), a repeated timestamp overwrites like PutItem
+	firings := slices.DeleteFunc(store.firings[firing.RuleID], func(existing models.AutomationFiring) bool {
+		return existing.Timestamp == firing.Timestamp
+	})
+	store.firings[firing.RuleID] = append(firings, firing)
+	return nil
+}
+
+// newest first, expired firings are dropped like the table TTL
+func (store *MemoryRuleStore) GetFirings(ctx context.Context, ruleID string, limit int32) ([]models.AutomationFiring, error) {
+	if limit <= 0 {
+		limit = defaultFiringsLimit
+	}
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.firings[ruleID] = slices.DeleteFunc(store.firings[ruleID], func(firing models.AutomationFiring) bool {
+		return firing.ExpiresAt > 0 && firing.ExpiresAt <= now
+	})
+
+	firings := slices.Clone(store.firings[ruleID])
+	if firings == nil {
+		firings = make([]models.AutomationFiring, 0)
+	}
+	slices.SortFunc(firings, func(a, b models.AutomationFiring) int {
+		return cmp.Compare(b.Timestamp, a.Timestamp)
+	})
+
+	if len(firings) > int(limit) {
+		firings = firings[:limit]
+	}
+	return firings, nil
+}
+
+// matching rules ordered by creation time
+func (store *MemoryRuleStore) collect(match func(models.AutomationRule) bool) []models.AutomationRule {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ruleList := make([]models.AutomationRule, 0)
+	for _, rule := range store.rules {
+		if !match(rule) {
+			continue
+		}
+		rule.Action.Parameters = maps.Clone(rule.Action.Parameters)
+		ruleList = append(ruleList, rule)
+	}
+
+	slices.SortFunc(ruleList, func(a, b models.AutomationRule) int {
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	})
+	return ruleList
+}
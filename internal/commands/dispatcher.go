@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/audit"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+var ErrPublishFailed = errors.New("failed to publish command")
+
+// Publisher sends a payload to an MQTT topic (iot.Publisher in production)
+type Publisher interface {
+	Publish(ctx context.Context, topic string, payload interface{}) error
+}
+
+// Auditor keeps the audit trail of every dispatch (audit.Recorder in production)
+type Auditor interface {
+	Record(ctx context.Context, event models.AuditEvent) error
+}
+
+// Request is a command to send to one device
+type Request struct {
+	DeviceID   string
+	DeviceType string
+	Action     string
+	Parameters map[string]interface{}
+	IssuedBy   string // user id, schedule:{id} or automation:{id}
+}
+
+// Dispatcher is the single path every command takes to a device (api, schedules, automations)
+type Dispatcher struct {
+	Publisher Publisher
+	Store     CommandStore
+	Audit     Auditor // optional
+}
+
+// validates the command, saves it as PENDING, then publishes it to devices/{id}/command.
+// validation failures come back as *devices.CommandValidationError
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context, req Request) (models.Command, error) {
+	if err := devices.ValidateCommand(req.DeviceType, req.Action, req.Parameters); err != nil {
+		return models.Command{}, err
+	}
+
+	now := time.Now()
+	cmd := models.Command{
+		RequestID:  fmt.Sprintf("cmd-%d", now.UnixNano()),
+		DeviceID:   req.DeviceID,
+		Timestamp:  now.Unix(),
+		Action:     req.Action,
+		Parameters: req.Parameters,
+		IssuedBy:   req.IssuedBy,
+		Status:     StatusPending,
+	}
+
+	// saved before publishing so an early ack from the device finds the record
+	var reason string
+	if err := dispatcher.Store.SaveCommand(ctx, cmd); err != nil {
+		slog.Warn("failed to save command history to DB, sending anyway", "device_id", req.DeviceID, "error", err)
+		reason = "command history not saved"
+	}
+
+	mqttPayload := map[string]interface{}{
+		"request_id": cmd.RequestID,
+		"action":     cmd.Action,
+		"parameters": cmd.Parameters,
+	}
+
+	topic := fmt.Sprintf("devices/%s/command", req.DeviceID)
+	if err := dispatcher.Publisher.Publish(ctx, topic, mqttPayload); err != nil {
+		failed := StatusUpdate{Status: StatusFailed, Error: "failed to publish command"}
+		if storeErr := dispatcher.Store.UpdateCommandStatus(ctx, cmd.RequestID, cmd.DeviceID, failed); storeErr != nil {
+			slog.Warn("failed to mark command as failed", "request_id", cmd.RequestID, "error", storeErr)
+		}
+		dispatcher.record(ctx, cmd, audit.ActionCommandFailed, "failed to publish command")
+		return cmd, fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+
+	dispatcher.record(ctx, cmd, audit.ActionCommandDispatched, reason)
+	return cmd, nil
+}
+
+// the audit trail outlives the command history, a failed write never blocks the command
+func (dispatcher *Dispatcher) record(ctx context.Context, cmd models.Command, action string, reason string) {
+	if dispatcher.Audit == nil {
+		return
+	}
+
+	err := dispatcher.Audit.Record(ctx, models.AuditEvent{
+		Action:   action,
+		ActorID:  cmd.IssuedBy,
+		DeviceID: cmd.DeviceID,
+		TargetID: cmd.RequestID,
+		After: map[string]interface{}{
+			"action":     cmd.Action,
+			"parameters": cmd.Parameters,
+		},
+		Reason: reason,
+	})
+	if err != nil {
+		slog.Error("failed to record command in audit log", "request_id", cmd.RequestID, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	StatusPending   = "PENDING"   // saved, not yet seen by the device
+	StatusDelivered = "DELIVERED" // device received it
+	StatusSucceeded = "SUCCEEDED" // device executed it
+	StatusFailed    = "FAILED"    // device (or the publisher) reported a failure
+	StatusTimedOut  = "TIMED_OUT" // no result within CommandTimeout
+
+	CommandTimeout = 60 * time.Second
+)
+
+var (
+	ErrCommandNotFound   = errors.New("command not found")
+	ErrInvalidTransition = errors.New("invalid command status transition")
+)
+
+// statuses a device is allowed to report in devices/{id}/command_ack
+func IsAckStatus(status string) bool {
+	switch status {
+	case StatusDelivered, StatusSucceeded, StatusFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+func IsTerminal(status string) bool {
+	switch status {
+	case StatusSucceeded, StatusFailed, StatusTimedOut:
+		return true
+	default:
+		return false
+	}
+}
+
+// commands saved before status tracking have no status, they count as pending
+func canTransition(from string, to string) bool {
+	if from == "" {
+		from = StatusPending
+	}
+	switch to {
+	case StatusDelivered:
+		return from == StatusPending
+	case StatusSucceeded, StatusFailed, StatusTimedOut:
+		return !IsTerminal(from)
+	default:
+		return false
+	}
+}
+
+// the last second a device ack is accepted, a command with no result by then is TIMED_OUT
+func Deadline(cmd models.Command) int64 {
+	return cmd.Timestamp + int64(CommandTimeout.Seconds())
+}
+
+// an ack past the deadline is refused, the command already reads TIMED_OUT and must stay so
+func acceptsAckAt(cmd models.Command, status string, at int64) bool {
+	return status == StatusTimedOut || at <= Deadline(cmd)
+}
+
+// TIMED_OUT is computed at read time, same as devices.ConnectionStatus
+func ResolveStatus(cmd models.Command, now int64) models.Command {
+	if cmd.Status == "" {
+		cmd.Status = StatusPending
+	}
+	if IsTerminal(cmd.Status) {
+		return cmd
+	}
+
+	deadline := Deadline(cmd)
+	if now > deadline {
+		cmd.Status = StatusTimedOut
+		cmd.CompletedAt = deadline
+	}
+	return cmd
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{"", StatusDelivered, true}, // saved before status tracking, counts as pending
+		{StatusPending, StatusDelivered, true},
+		{StatusPending, StatusSucceeded, true},
+		{StatusPending, StatusFailed, true},
+		{StatusPending, StatusTimedOut, true},
+		{StatusDelivered, StatusDelivered, false},
+		{StatusDelivered, StatusSucceeded, true},
+		{StatusDelivered, StatusFailed, true},
+		{StatusSucceeded, StatusFailed, false},
+		{StatusFailed, StatusSucceeded, false},
+		{StatusTimedOut, StatusSucceeded, false},
+		{StatusSucceeded, StatusDelivered, false},
+		{StatusPending, StatusPending, false},
+		{StatusPending, "UNKNOWN", false},
+	}
+	for _, test := range tests {
+		if got := canTransition(test.from, test.to); got != test.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestResolveStatus(t *testing.T) {
+	sent := int64(1_000)
+	deadline := sent + int64(CommandTimeout.Seconds())
+
+	pending := ResolveStatus(models.Command{Timestamp: sent}, deadline)
+	if pending.Status != StatusPending {
+		t.Errorf("status at the deadline = %s, want %s", pending.Status, StatusPending)
+	}
+
+	late := ResolveStatus(models.Command{Timestamp: sent, Status: StatusDelivered}, deadline+1)
+	if late.Status != StatusTimedOut || late.CompletedAt != deadline {
+		t.Errorf("after the deadline got %s completed at %d, want %s at %d", late.Status, late.CompletedAt, StatusTimedOut, deadline)
+	}
+
+	done := ResolveStatus(models.Command{Timestamp: sent, Status: StatusSucceeded, CompletedAt: sent + 5}, deadline+1)
+	if done.Status != StatusSucceeded || done.CompletedAt != sent+5 {
+		t.Errorf("a finished command was changed to %s completed at %d", done.Status, done.CompletedAt)
+	}
+}
+
+func TestMemoryUpdateCommandStatus(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCommandStore()
+	if err := store.SaveCommand(ctx, models.Command{RequestID: "req-1", DeviceID: "dev-1", Timestamp: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusDelivered, At: 110}); err != nil {
+		t.Fatalf("PENDING -> DELIVERED: %v", err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-2", StatusUpdate{Status: StatusSucceeded, At: 115}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ack from another device: got %v, want ErrInvalidTransition", err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusSucceeded, At: 120, Result: map[string]interface{}{"power": "ON"}}); err != nil {
+		t.Fatalf("DELIVERED -> SUCCEEDED: %v", err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusFailed, At: 130}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("SUCCEEDED -> FAILED: got %v, want ErrInvalidTransition", err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "missing", "dev-1", StatusUpdate{Status: StatusDelivered}); !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("unknown command: got %v, want ErrCommandNotFound", err)
+	}
+
+	cmd, err := store.GetCommand(ctx, "req-1")
+	if err != nil || cmd == nil {
+		t.Fatalf("GetCommand: %v, %v", cmd, err)
+	}
+	if cmd.Status != StatusSucceeded || cmd.DeliveredAt != 110 || cmd.CompletedAt != 120 || cmd.Result["power"] != "ON" {
+		t.Errorf("got %+v, want SUCCEEDED delivered at 110 and completed at 120 with the result", *cmd)
+	}
+}
+
+func TestMemorySucceededWithoutDelivery(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCommandStore()
+	if err := store.SaveCommand(ctx, models.Command{RequestID: "req-1", DeviceID: "dev-1", Timestamp: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusSucceeded, At: 120}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, _ := store.GetCommand(ctx, "req-1")
+	if cmd.DeliveredAt != 120 || cmd.CompletedAt != 120 {
+		t.Errorf("delivered at %d completed at %d, want both 120", cmd.DeliveredAt, cmd.CompletedAt)
+	}
+}
+
+// the command reads TIMED_OUT after its deadline, a late ack must not turn it into SUCCEEDED
+func TestMemoryLateAck(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCommandStore()
+	if err := store.SaveCommand(ctx, models.Command{RequestID: "req-1", DeviceID: "dev-1", Timestamp: 100}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := Deadline(models.Command{Timestamp: 100})
+
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusDelivered, At: deadline}); err != nil {
+		t.Fatalf("ack at the deadline: %v", err)
+	}
+	if err := store.UpdateCommandStatus(ctx, "req-1", "dev-1", StatusUpdate{Status: StatusSucceeded, At: deadline + 1}); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("ack after the deadline: got %v, want ErrInvalidTransition", err)
+	}
+
+	cmd, _ := store.GetCommand(ctx, "req-1")
+	if resolved := ResolveStatus(*cmd, deadline+1); resolved.Status != StatusTimedOut {
+		t.Errorf("status after the late ack = %s, want %s", resolved.Status, StatusTimedOut)
+	}
+}
@@ -2,24 +2,65 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/Fleexa-Graduation-Project/Backend/models"
 	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
 )
 
-type CommandStore struct {
+const (
+	commandTTL          = 30 * 24 * time.Hour
+	deviceHistoryIndex  = "DeviceHistoryIndex"
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// CommandStore keeps every command sent to a device (keyed by request_id)
+type CommandStore interface {
+	SaveCommand(ctx context.Context, cmd models.Command) error
+	GetCommand(ctx context.Context, requestID string) (*models.Command, error)
+	UpdateCommandStatus(ctx context.Context, requestID string, deviceID string, update StatusUpdate) error
+	GetCommandsByDevice(ctx context.Context, query HistoryQuery) (HistoryPage, error)
+	DeleteDeviceCommands(ctx context.Context, deviceID string) (int, error)
+}
+
+// HistoryQuery selects a device's commands newest first, From/To are inclusive unix seconds (0 = open)
+type HistoryQuery struct {
+	DeviceID string
+	From     int64
+	To       int64
+	Limit    int32
+	Cursor   string
+}
+
+type HistoryPage struct {
+	Commands   []models.Command
+	NextCursor string // empty on the last page
+}
+
+// StatusUpdate is one lifecycle transition of a command
+type StatusUpdate struct {
+	Status string
+	At     int64
+	Result map[string]interface{}
+	Error  string
+}
+
+type DynamoCommandStore struct {
 	Client    *dynamodb.Client
 	TableName string
 }
 
-func NewCommandStore() (*CommandStore, error) {
+func NewCommandStore() (*DynamoCommandStore, error) {
 	tableName := os.Getenv("DYNAMODB_COMMANDS_TABLE")
 	if tableName == "" {
 		return nil, fmt.Errorf("DYNAMODB_COMMANDS_TABLE environment variable is not set")
@@ -29,21 +70,25 @@ func NewCommandStore() (*CommandStore, error) {
 		return nil, fmt.Errorf("dynamodb client is not initialized")
 	}
 
-	return &CommandStore{
+	return &DynamoCommandStore{
 		Client:    db.Client,
 		TableName: tableName,
 	}, nil
 }
 
-func (store *CommandStore) SaveCommand(ctx context.Context, cmd models.Command) error {
+func (store *DynamoCommandStore) SaveCommand(ctx context.Context, cmd models.Command) error {
 	if cmd.ExpiresAt == 0 {
-		cmd.ExpiresAt = time.Now().Add(30 * 24 * time.Hour).Unix()
+		cmd.ExpiresAt = time.Now().Add(commandTTL).Unix()
 	}
 
 	if cmd.Timestamp == 0 {
 		cmd.Timestamp = time.Now().Unix()
 	}
 
+	if cmd.Status == "" {
+		cmd.Status = StatusPending
+	}
+
 	item, err := attributevalue.MarshalMap(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to marshal command: %w", err)
@@ -61,3 +106,180 @@ func (store *CommandStore) SaveCommand(ctx context.Context, cmd models.Command)
 
 	return nil
 }
+
+// retrieve a single command by its request id
+func (store *DynamoCommandStore) GetCommand(ctx context.Context, requestID string) (*models.Command, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"request_id": &types.AttributeValueMemberS{Value: requestID},
+		},
+	}
+
+	result, err := store.Client.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command %s: %w", requestID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var cmd models.Command
+	if err = attributevalue.UnmarshalMap(result.Item, &cmd); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal command %s: %w", requestID, err)
+	}
+
+	return &cmd, nil
+}
+
+// moves a command forward in its lifecycle, the condition mirrors canTransition and acceptsAckAt
+func (store *DynamoCommandStore) UpdateCommandStatus(ctx context.Context, requestID string, deviceID string, update StatusUpdate) error {
+	if update.At == 0 {
+		update.At = time.Now().Unix()
+	}
+
+	names := map[string]string{
+		"#status": "status",
+	}
+	values := map[string]types.AttributeValue{
+		":status":  &types.AttributeValueMemberS{Value: update.Status},
+		":at":      &types.AttributeValueMemberN{Value: fmt.Sprint(update.At)},
+		":device":  &types.AttributeValueMemberS{Value: deviceID},
+		":pending": &types.AttributeValueMemberS{Value: StatusPending},
+	}
+
+	var updateExpr, condition string
+	switch update.Status {
+	case StatusDelivered:
+		updateExpr = "SET #status = :status, delivered_at = :at"
+		condition = "device_id = :device AND (attribute_not_exists(#status) OR #status = :pending)"
+	case StatusSucceeded, StatusFailed, StatusTimedOut:
+		updateExpr = "SET #status = :status, completed_at = :at"
+		if update.Status == StatusSucceeded {
+			updateExpr += ", delivered_at = if_not_exists(delivered_at, :at)"
+		}
+		condition = "device_id = :device AND (attribute_not_exists(#status) OR #status IN (:pending, :delivered))"
+		values[":delivered"] = &types.AttributeValueMemberS{Value: StatusDelivered}
+	default:
+		return fmt.Errorf("%w: unknown status %s", ErrInvalidTransition, update.Status)
+	}
+	if update.Status != StatusTimedOut {
+		// sent at most CommandTimeout before the ack
+		condition += " AND #ts >= :earliest"
+		names["#ts"] = "timestamp"
+		values[":earliest"] = &types.AttributeValueMemberN{Value: fmt.Sprint(update.At - int64(CommandTimeout.Seconds()))}
+	}
+
+	if len(update.Result) > 0 {
+		result, err := attributevalue.Marshal(update.Result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal command result: %w", err)
+		}
+		updateExpr += ", #result = :result"
+		names["#result"] = "result"
+		values[":result"] = result
+	}
+	if update.Error != "" {
+		updateExpr += ", #error = :error"
+		names["#error"] = "error"
+		values[":error"] = &types.AttributeValueMemberS{Value: update.Error}
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"request_id": &types.AttributeValueMemberS{Value: requestID},
+		},
+		UpdateExpression:                    aws.String(updateExpr),
+		ConditionExpression:                 aws.String(condition),
+		ExpressionAttributeNames:            names,
+		ExpressionAttributeValues:           values,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+
+	_, err := store.Client.UpdateItem(ctx, input)
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			if len(conditionErr.Item) == 0 {
+				return fmt.Errorf("%w: %s", ErrCommandNotFound, requestID)
+			}
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, requestID, update.Status)
+		}
+		return fmt.Errorf("failed to update command status: %w", err)
+	}
+
+	return nil
+}
+
+// the DeviceHistoryIndex key plus the table key, a page of one device
+func historyCursorKey(deviceID string) db.CursorKey {
+	return db.CursorKey{Partition: "device_id", Value: deviceID, Strings: []string{"request_id"}, Numbers: []string{"timestamp"}}
+}
+
+// device command history from the DeviceHistoryIndex GSI (device_id + timestamp)
+func (store *DynamoCommandStore) GetCommandsByDevice(ctx context.Context, query HistoryQuery) (HistoryPage, error) {
+	query = normalizeHistoryQuery(query)
+
+	startKey, err := db.DecodeCursor(query.Cursor, historyCursorKey(query.DeviceID))
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		IndexName:              aws.String(deviceHistoryIndex),
+		KeyConditionExpression: aws.String("device_id = :id AND #ts BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":   &types.AttributeValueMemberS{Value: query.DeviceID},
+			":from": &types.AttributeValueMemberN{Value: fmt.Sprint(query.From)},
+			":to":   &types.AttributeValueMemberN{Value: fmt.Sprint(query.To)},
+		},
+		ScanIndexForward:  aws.Bool(false), // newest first
+		Limit:             aws.Int32(query.Limit),
+		ExclusiveStartKey: startKey,
+	}
+
+	res, err := store.Client.Query(ctx, input)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to query commands for device %s: %w", query.DeviceID, err)
+	}
+
+	page := HistoryPage{Commands: []models.Command{}}
+	if err = attributevalue.UnmarshalListOfMaps(res.Items, &page.Commands); err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to unmarshal commands for device %s: %w", query.DeviceID, err)
+	}
+
+	page.NextCursor, err = db.EncodeCursor(res.LastEvaluatedKey)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	return page, nil
+}
+
+func normalizeHistoryQuery(query HistoryQuery) HistoryQuery {
+	if query.Limit <= 0 {
+		query.Limit = defaultHistoryLimit
+	}
+	if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+	if query.To <= 0 {
+		query.To = math.MaxInt64
+	}
+	return query
+}
+
+// drops the command history of a purged device
+func (store *DynamoCommandStore) DeleteDeviceCommands(ctx context.Context, deviceID string) (int, error) {
+	deleted, err := db.DeleteIndexed(ctx, store.Client, store.TableName, deviceHistoryIndex, "device_id", "request_id", deviceID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete commands of device %s: %w", deviceID, err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// MemoryCommandStore is an in-process CommandStore used for tests and local demos
+type MemoryCommandStore struct {
+	mu       sync.Mutex
+	commands map[string]models.Command // request_id -> command
+}
+
+func NewMemoryCommandStore() *MemoryCommandStore {
+	return &MemoryCommandStore{
+		commands: make(map[string]models.Command),
+	}
+}
+
+func (store *MemoryCommandStore) SaveCommand(ctx context.Context, cmd models.Command) error {
+	if cmd.ExpiresAt == 0 {
+		cmd.ExpiresAt = time.Now().Add(commandTTL).Unix()
+	}
+
+	if cmd.Timestamp == 0 {
+		cmd.Timestamp = time.Now().Unix()
+	}
+	if cmd.Status == "" {
+		cmd.Status = StatusPending
+	}
+	cmd.Parameters = maps.Clone(cmd.Parameters)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.commands[cmd.RequestID] = cmd
+	return nil
+}
+
+func (store *MemoryCommandStore) GetCommand(ctx context.Context, requestID string) (*models.Command, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	cmd, exists := store.commands[requestID]
+	if !exists {
+		return nil, nil
+	}
+
+	cmd.Parameters = maps.Clone(cmd.Parameters)
+	cmd.Result = maps.Clone(cmd.Result)
+	return &cmd, nil
+}
+
+func (store *MemoryCommandStore) UpdateCommandStatus(ctx context.Context, requestID string, deviceID string, update StatusUpdate) error {
+	if update.At == 0 {
+		update.At = time.Now().Unix()
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	cmd, exists := store.commands[requestID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCommandNotFound, requestID)
+	}
+	if cmd.DeviceID != deviceID || !canTransition(cmd.Status, update.Status) || !acceptsAckAt(cmd, update.Status, update.At) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, requestID, update.Status)
+	}
+
+	cmd.Status = update.Status
+	switch update.Status {
+	case StatusDelivered:
+		cmd.DeliveredAt = update.At
+	case StatusSucceeded:
+		if cmd.DeliveredAt == 0 {
+			cmd.DeliveredAt = update.At
+		}
+		cmd.CompletedAt = update.At
+	default:
+		cmd.CompletedAt = update.At
+	}
+	if len(update.Result) > 0 {
+		cmd.Result = maps.Clone(update.Result)
+	}
+	if update.Error != "" {
+		cmd.Error = update.Error
+	}
+
+	store.commands[requestID] = cmd
+	return nil
+}
+
+// same order and cursor shape as the DeviceHistoryIndex query
+func (store *MemoryCommandStore) GetCommandsByDevice(ctx context.Context, query HistoryQuery) (HistoryPage, error) {
+	query = normalizeHistoryQuery(query)
+
+	startKey, err := db.DecodeCursor(query.Cursor, historyCursorKey(query.DeviceID))
+	if err != nil {
+		return HistoryPage{}, err
+	}
+	var afterTs int64
+	var afterID string
+	if startKey != nil {
+		afterID, afterTs, err = parseHistoryKey(startKey)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+	}
+
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	matches := make([]models.Command, 0)
+	for _, cmd := range store.commands {
+		if cmd.ExpiresAt > 0 && cmd.ExpiresAt <= now {
+			continue
+		}
+		if cmd.DeviceID != query.DeviceID || cmd.Timestamp < query.From || cmd.Timestamp > query.To {
+			continue
+		}
+		cmd.Parameters = maps.Clone(cmd.Parameters)
+		cmd.Result = maps.Clone(cmd.Result)
+		matches = append(matches, cmd)
+	}
+	store.mu.Unlock()
+
+	slices.SortFunc(matches, compareNewestFirst)
+
+	if startKey != nil {
+		cursor := models.Command{RequestID: afterID, Timestamp: afterTs}
+		idx, _ := slices.BinarySearchFunc(matches, cursor, compareNewestFirst)
+		for idx < len(matches) && compareNewestFirst(matches[idx], cursor) <= 0 {
+			idx++
+		}
+		matches = matches[idx:]
+	}
+
+	page := HistoryPage{Commands: matches}
+	if len(matches) > int(query.Limit) {
+		page.Commands = matches[:query.Limit]
+		last := page.Commands[len(page.Commands)-1]
+		page.NextCursor, err = db.EncodeCursor(map[string]types.AttributeValue{
+			"request_id": &types.AttributeValueMemberS{Value: last.RequestID},
+			"device_id":  &types.AttributeValueMemberS{Value: last.DeviceID},
+			"timestamp":  &types.AttributeValueMemberN{Value: strconv.FormatInt(last.Timestamp, 10)},
+		})
+		if err != nil {
+			return HistoryPage{}, err
+		}
+	}
+
+	return page, nil
+}
+
+func compareNewestFirst(a, b models.Command) int {
+	if c := cmp.Compare(b.Timestamp, a.Timestamp); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.RequestID, a.RequestID)
+}
+
+func parseHistoryKey(key map[string]types.AttributeValue) (string, int64, error) {
+	id, okID := key["request_id"].(*types.AttributeValueMemberS)
+	ts, okTs := key["timestamp"].(*types.AttributeValueMemberN)
+	if !okID || !okTs {
+		return "", 0, fmt.Errorf("%w: missing key attributes", db.ErrInvalidCursor)
+	}
+	timestamp, err := strconv.ParseInt(ts.Value, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: bad timestamp", db.ErrInvalidCursor)
+	}
+	return id.Value, timestamp, nil
+}
+
+func (store *MemoryCommandStore) DeleteDeviceCommands(ctx context.Context, deviceID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := 0
+	for requestID, cmd := range store.commands {
+		if cmd.DeviceID == deviceID {
+			delete(store.commands, requestID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
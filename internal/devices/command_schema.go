@@ -0,0 +1,159 @@
+package devices
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// parameter types, matching what encoding/json decodes into
+const (
+	ParamNumber = "number"
+	ParamString = "string"
+	ParamBool   = "bool"
+)
+
+// validation failure codes returned to the app
+const (
+	CodeNotActuator       = "DEVICE_NOT_ACTUATOR"
+	CodeUnsupportedAction = "UNSUPPORTED_ACTION"
+	CodeInvalidParameters = "INVALID_PARAMETERS"
+)
+
+type ParamRange struct {
+	Min float64
+	Max float64
+}
+
+// ParamSpec describes one key inside a command's parameters
+type ParamSpec struct {
+	Type     string
+	Required bool
+	Range    *ParamRange // numbers only
+	Enum     []string    // strings only
+}
+
+// CommandSpec describes one action an actuator accepts
+type CommandSpec struct {
+	Params    map[string]ParamSpec
+	MinParams int // e.g. SET_STATE must change at least one thing
+}
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// CommandValidationError is returned by ValidateCommand, the api sends it back as a 422
+type CommandValidationError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+func (e *CommandValidationError) Error() string {
+	if len(e.Details) == 0 {
+		return e.Message
+	}
+	reasons := make([]string, 0, len(e.Details))
+	for _, detail := range e.Details {
+		reasons = append(reasons, detail.Field+": "+detail.Reason)
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, strings.Join(reasons, "; "))
+}
+
+// IsActuator tells if the device type accepts commands at all
+func IsActuator(deviceType string) bool {
+	rules, ok := Rules[deviceType]
+	return ok && len(rules.Commands) > 0
+}
+
+// checks an action and its parameters against the device type's command schema
+func ValidateCommand(deviceType string, action string, params map[string]interface{}) error {
+	if !IsActuator(deviceType) {
+		return &CommandValidationError{
+			Code:    CodeNotActuator,
+			Message: fmt.Sprintf("device type %q does not accept commands", deviceType),
+		}
+	}
+
+	spec, ok := Rules[deviceType].Commands[action]
+	if !ok {
+		return &CommandValidationError{
+			Code:    CodeUnsupportedAction,
+			Message: fmt.Sprintf("action %q is not supported by %s, allowed: %s", action, deviceType, strings.Join(AllowedActions(deviceType), ", ")),
+		}
+	}
+
+	var details []FieldError
+
+	for name, paramSpec := range spec.Params {
+		value, exists := params[name]
+		if !exists {
+			if paramSpec.Required {
+				details = append(details, FieldError{Field: name, Reason: "is required"})
+			}
+			continue
+		}
+		if reason := checkParam(paramSpec, value); reason != "" {
+			details = append(details, FieldError{Field: name, Reason: reason})
+		}
+	}
+
+	for name := range params {
+		if _, known := spec.Params[name]; !known {
+			details = append(details, FieldError{Field: name, Reason: "is not a parameter of " + action})
+		}
+	}
+
+	if len(params) < spec.MinParams {
+		details = append(details, FieldError{Field: "parameters", Reason: fmt.Sprintf("at least %d parameter(s) required", spec.MinParams)})
+	}
+
+	if len(details) > 0 {
+		slices.SortFunc(details, func(a, b FieldError) int { return cmp.Compare(a.Field, b.Field) })
+		return &CommandValidationError{
+			Code:    CodeInvalidParameters,
+			Message: fmt.Sprintf("invalid parameters for %s", action),
+			Details: details,
+		}
+	}
+
+	return nil
+}
+
+func AllowedActions(deviceType string) []string {
+	actions := make([]string, 0, len(Rules[deviceType].Commands))
+	for action := range Rules[deviceType].Commands {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	return actions
+}
+
+func checkParam(spec ParamSpec, value interface{}) string {
+	switch spec.Type {
+	case ParamNumber:
+		num, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if spec.Range != nil && (num < spec.Range.Min || num > spec.Range.Max) {
+			return fmt.Sprintf("must be between %g and %g", spec.Range.Min, spec.Range.Max)
+		}
+	case ParamString:
+		str, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(spec.Enum) > 0 && !slices.Contains(spec.Enum, str) {
+			return "must be one of " + strings.Join(spec.Enum, ", ")
+		}
+	case ParamBool:
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package devices
 
 import (
 	"strings"
+	"time"
 )
 
 var Rules = map[string]DeviceRules{
@@ -58,6 +59,11 @@ var Rules = map[string]DeviceRules{
 		EvaluateHealth: func(op string) string {
 			return "HEALTHY"
 		},
+		Commands: map[string]CommandSpec{
+			"LOCK":   {},
+			"UNLOCK": {},
+		},
+		OfflineLimit: 5 * time.Minute, // reports on change, heartbeat is slower than the sensors
 	},
 	"door-sensor": {
 		ExtractOperational: func(payload map[string]interface{}) string {
@@ -83,6 +89,7 @@ var Rules = map[string]DeviceRules{
 			}
 			return "HEALTHY"
 		},
+		OfflineLimit: 5 * time.Minute,
 	},
 
 	"gas-sensor": {
@@ -118,5 +125,16 @@ var Rules = map[string]DeviceRules{
 			}
 			return "HEALTHY"
 		},
+		Commands: map[string]CommandSpec{
+			"SET_STATE": {
+				Params: map[string]ParamSpec{
+					"power":       {Type: ParamString, Enum: []string{"ON", "OFF"}},
+					"target_temp": {Type: ParamNumber, Range: &ParamRange{Min: 16, Max: 30}},
+					"mode":        {Type: ParamString, Enum: []string{"COOLING", "HEATING", "FAN", "DRY", "AUTO"}},
+				},
+				MinParams: 1,
+			},
+		},
+		RatedPowerKW: 1.5,
 	},
 }
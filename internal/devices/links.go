@@ -0,0 +1,68 @@
+package devices
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// LinkTempSensor points an AC at the sensor measuring the temperature it controls
+const LinkTempSensor = "temp_sensor"
+
+// Relation is a kind of link between two devices of the same home
+type Relation struct {
+	From []string // device types the link can be set on
+	To   string   // device type it points at
+}
+
+var Relations = map[string]Relation{
+	LinkTempSensor: {From: []string{"ac-actuator"}, To: "temp-sensor"},
+}
+
+// ValidateLinks checks the links set on state, targets holds the linked devices found in the
+// device's home (a missing one is either unknown or in another home)
+func ValidateLinks(state models.DeviceState, targets map[string]*models.DeviceState) error {
+	for relation, targetID := range state.Links {
+		spec, ok := Relations[relation]
+		if !ok || !slices.Contains(spec.From, state.Type) {
+			return fmt.Errorf("%w: %s can't have a %s link", ErrInvalidDevice, state.Type, relation)
+		}
+		if targetID == state.DeviceID {
+			return fmt.Errorf("%w: a device can't be linked to itself", ErrInvalidDevice)
+		}
+		target := targets[targetID]
+		if !Active(target) {
+			return fmt.Errorf("%w: %s link %s is not a device of this home", ErrInvalidDevice, relation, targetID)
+		}
+		if target.Type != spec.To {
+			return fmt.Errorf("%w: %s link must be a %s", ErrInvalidDevice, relation, spec.To)
+		}
+	}
+	return nil
+}
+
+// TempSensorFor picks the sensor giving an AC its inside temperature: the linked one, or else
+// (no link, or the linked sensor is gone) the most recently seen temp sensor of its room.
+// states are the devices of the AC's home
+func TempSensorFor(ac models.DeviceState, states []models.DeviceState) *models.DeviceState {
+	var roomSensor *models.DeviceState
+	for i := range states {
+		state := &states[i]
+		if state.Type != "temp-sensor" || !Active(state) {
+			continue
+		}
+		if state.DeviceID == ac.Links[LinkTempSensor] {
+			return state
+		}
+		if ac.RoomID != "" && state.RoomID == ac.RoomID && (roomSensor == nil || state.LastSeenAt > roomSensor.LastSeenAt) {
+			roomSensor = state
+		}
+	}
+	return roomSensor
+}
+
+// ConsumesEnergy tells if the device type counts in the energy chart
+func ConsumesEnergy(deviceType string) bool {
+	return Rules[deviceType].RatedPowerKW > 0
+}
@@ -0,0 +1,87 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	StatusProvisioned    = "PROVISIONED"    // registered, no message yet
+	StatusDecommissioned = "DECOMMISSIONED" // archived, ingestion and commands refuse it
+
+	maxNameLength     = 64
+	maxFirmwareLength = 32
+)
+
+// the id is part of the mqtt topics, so no '/', '+' or '#'
+var deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	ErrInvalidDevice  = errors.New("invalid device")
+	ErrDeviceExists   = errors.New("device already registered")
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// Active tells if the device may talk to the backend. devices created by telemetry before the
+// registry existed have no registered_at and stay active until they are decommissioned
+func Active(state *models.DeviceState) bool {
+	return state != nil && state.Status != StatusDecommissioned
+}
+
+// the status shown to the app, ONLINE/OFFLINE come from last_seen_at once the device has reported
+func LiveStatus(state models.DeviceState) string {
+	if state.Status == StatusProvisioned || state.Status == StatusDecommissioned {
+		return state.Status
+	}
+	return ConnectionStatus(state.Type, state.LastSeenAt)
+}
+
+// PrepareRegistration validates a new registration and fills what the registry sets itself.
+// an existing item is adopted: a decommissioned device starts over as PROVISIONED,
+// one that reported before the registry keeps its connection state
+func PrepareRegistration(state *models.DeviceState, existing *models.DeviceState, now time.Time) error {
+	if Active(existing) && existing.RegisteredAt > 0 {
+		return fmt.Errorf("%w: %s", ErrDeviceExists, state.DeviceID)
+	}
+	if !deviceIDPattern.MatchString(state.DeviceID) {
+		return fmt.Errorf("%w: device_id must be 1-64 letters, digits, '-' or '_'", ErrInvalidDevice)
+	}
+	if _, ok := Rules[state.Type]; !ok {
+		return fmt.Errorf("%w: unknown device type %q", ErrInvalidDevice, state.Type)
+	}
+	if err := ValidateInfo(*state, now); err != nil {
+		return err
+	}
+
+	state.RegisteredAt = now.Unix()
+	state.LastUpdated = now.Unix()
+	state.DecommissionedAt = 0
+
+	if Active(existing) {
+		state.Status = existing.Status
+		state.LastSeenAt = existing.LastSeenAt
+		state.OfflineSince = existing.OfflineSince
+		return nil
+	}
+	state.Status = StatusProvisioned
+	state.OfflineSince = 0
+	return nil
+}
+
+// ValidateInfo checks the metadata the owner can edit
+func ValidateInfo(state models.DeviceState, now time.Time) error {
+	if len(state.Name) > maxNameLength {
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidDevice, maxNameLength)
+	}
+	if len(state.Firmware) > maxFirmwareLength {
+		return fmt.Errorf("%w: firmware is longer than %d characters", ErrInvalidDevice, maxFirmwareLength)
+	}
+	if state.InstalledAt < 0 || state.InstalledAt > now.Unix() {
+		return fmt.Errorf("%w: installed_at can't be in the future", ErrInvalidDevice)
+	}
+	return nil
+}
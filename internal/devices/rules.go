@@ -1,6 +1,11 @@
 package devices
 
+import "time"
+
 type DeviceRules struct {
 	ExtractOperational func(payload map[string]interface{}) string
 	EvaluateHealth     func(opState string) string
+	Commands           map[string]CommandSpec // nil for sensor-only types
+	OfflineLimit       time.Duration          // silence before the device counts as offline, 0 = OfflineLimit
+	RatedPowerKW       float64                // drawn while power_state is ON, 0 = left out of the energy chart
 }
@@ -0,0 +1,110 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const (
+	QuarantineUnregistered   = "UNREGISTERED"
+	QuarantineDecommissioned = "DECOMMISSIONED"
+	QuarantineTypeMismatch   = "TYPE_MISMATCH"
+
+	// a device that stops sending drops out of the quarantine after a week
+	quarantineTTL = 7 * 24 * time.Hour
+)
+
+// QuarantineStore keeps one summary per device id ingestion refused, so the devices that keep
+// sending without being registered (or after being decommissioned) can be found
+type QuarantineStore interface {
+	QuarantineMessage(ctx context.Context, entry models.QuarantinedDevice) error
+	ReleaseDevice(ctx context.Context, deviceID string) error
+}
+
+type DynamoQuarantineStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewQuarantineStore() (*DynamoQuarantineStore, error) {
+	tableName := os.Getenv("DYNAMODB_QUARANTINE_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_QUARANTINE_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoQuarantineStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+// counts the refused message and keeps the latest one, first_seen_at is set once
+func (store *DynamoQuarantineStore) QuarantineMessage(ctx context.Context, entry models.QuarantinedDevice) error {
+	payload, err := attributevalue.Marshal(entry.LastPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined payload: %w", err)
+	}
+
+	_, err = store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: entry.DeviceID},
+		},
+		UpdateExpression: aws.String(`
+			SET
+				reason = :reason,
+				message_type = :message_type,
+				#type = :type,
+				last_payload = :payload,
+				first_seen_at = if_not_exists(first_seen_at, :at),
+				last_seen_at = :at,
+				expires_at = :expires_at
+			ADD #count :one
+		`),
+		ExpressionAttributeNames: map[string]string{
+			"#type":  "type",
+			"#count": "count",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":reason":       &types.AttributeValueMemberS{Value: entry.Reason},
+			":message_type": &types.AttributeValueMemberS{Value: entry.MessageType},
+			":type":         &types.AttributeValueMemberS{Value: entry.Type},
+			":payload":      payload,
+			":at":           &types.AttributeValueMemberN{Value: fmt.Sprint(entry.LastSeenAt)},
+			":expires_at":   &types.AttributeValueMemberN{Value: fmt.Sprint(time.Unix(entry.LastSeenAt, 0).Add(quarantineTTL).Unix())},
+			":one":          &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to quarantine device %s: %w", entry.DeviceID, err)
+	}
+	return nil
+}
+
+// called once the device is registered
+func (store *DynamoQuarantineStore) ReleaseDevice(ctx context.Context, deviceID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release quarantined device %s: %w", deviceID, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package devices
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MemoryQuarantineStore is an in-process QuarantineStore used for tests and local demos
+type MemoryQuarantineStore struct {
+	mu      sync.Mutex
+	entries map[string]models.QuarantinedDevice
+}
+
+func NewMemoryQuarantineStore() *MemoryQuarantineStore {
+	return &MemoryQuarantineStore{
+		entries: make(map[string]models.QuarantinedDevice),
+	}
+}
+
+func (store *MemoryQuarantineStore) QuarantineMessage(ctx context.Context, entry models.QuarantinedDevice) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.entries[entry.DeviceID]
+	if !exists {
+		current.DeviceID = entry.DeviceID
+		current.FirstSeenAt = entry.LastSeenAt
+	}
+	current.Reason = entry.Reason
+	current.MessageType = entry.MessageType
+	current.Type = entry.Type
+	current.LastPayload = maps.Clone(entry.LastPayload)
+	current.LastSeenAt = entry.LastSeenAt
+	current.ExpiresAt = time.Unix(entry.LastSeenAt, 0).Add(quarantineTTL).Unix()
+	current.Count++
+
+	store.entries[entry.DeviceID] = current
+	return nil
+}
+
+func (store *MemoryQuarantineStore) ReleaseDevice(ctx context.Context, deviceID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.entries, deviceID)
+	return nil
+}
@@ -0,0 +1,42 @@
+package devices
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// one entry per device: the first refusal is kept, the latest message and reason replace the older ones
+func TestMemoryQuarantine(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryQuarantineStore()
+
+	messages := []models.QuarantinedDevice{
+		{DeviceID: "gas-9", Reason: QuarantineUnregistered, MessageType: "telemetry", Type: "gas-sensor", LastSeenAt: 1000, LastPayload: map[string]interface{}{"gas_level": 100.0}},
+		{DeviceID: "gas-9", Reason: QuarantineTypeMismatch, MessageType: "alerts", Type: "door-lock", LastSeenAt: 1060, LastPayload: map[string]interface{}{"locked": true}},
+	}
+	for _, message := range messages {
+		if err := store.QuarantineMessage(ctx, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry := store.entries["gas-9"]
+	if entry.Count != 2 || entry.FirstSeenAt != 1000 || entry.LastSeenAt != 1060 {
+		t.Errorf("entry = %d messages from %d to %d, want 2 from 1000 to 1060", entry.Count, entry.FirstSeenAt, entry.LastSeenAt)
+	}
+	if entry.Reason != QuarantineTypeMismatch || entry.MessageType != "alerts" || entry.LastPayload["locked"] != true {
+		t.Errorf("entry = %+v, want the latest message kept", entry)
+	}
+	if entry.ExpiresAt != 1060+int64(quarantineTTL.Seconds()) {
+		t.Errorf("expires at %d, want a week after the last message", entry.ExpiresAt)
+	}
+
+	if err := store.ReleaseDevice(ctx, "gas-9"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := store.entries["gas-9"]; exists {
+		t.Error("released device still quarantined")
+	}
+}
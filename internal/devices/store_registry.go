@@ -0,0 +1,156 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// RegistryStore provisions and retires devices, on the same item as their live state
+type RegistryStore interface {
+	RegisterDevice(ctx context.Context, state models.DeviceState) error
+	UpdateDeviceInfo(ctx context.Context, state models.DeviceState) error
+	DecommissionDevice(ctx context.Context, deviceID string, at int64) error
+	DeleteDevice(ctx context.Context, deviceID string) error
+}
+
+// an item is only taken over when it was never registered or is decommissioned,
+// the reported state (payload, last_seen_at...) of an adopted device is kept.
+// a new one gets an empty payload, the handlers add their fields to it
+func (s *DynamoStateStore) RegisterDevice(ctx context.Context, state models.DeviceState) error {
+	_, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: state.DeviceID},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(registered_at) OR #status = :decommissioned"),
+		UpdateExpression: aws.String(`
+			SET
+				#type = :type,
+				#status = :status,
+				#name = :name,
+				room_id = :room_id,
+				links = :links,
+				firmware = :firmware,
+				installed_at = :installed_at,
+				registered_at = :registered_at,
+				offline_since = :offline_since,
+				payload = if_not_exists(payload, :empty_payload),
+				last_seen_at = if_not_exists(last_seen_at, :zero),
+				updated_at = :updated_at
+			REMOVE decommissioned_at
+		`),
+		ExpressionAttributeNames: map[string]string{
+			"#type":   "type",
+			"#status": "status",
+			"#name":   "name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type":           &types.AttributeValueMemberS{Value: state.Type},
+			":status":         &types.AttributeValueMemberS{Value: state.Status},
+			":decommissioned": &types.AttributeValueMemberS{Value: StatusDecommissioned},
+			":name":           &types.AttributeValueMemberS{Value: state.Name},
+			":room_id":        &types.AttributeValueMemberS{Value: state.RoomID},
+			":links":          linksValue(state.Links),
+			":firmware":       &types.AttributeValueMemberS{Value: state.Firmware},
+			":installed_at":   &types.AttributeValueMemberN{Value: fmt.Sprint(state.InstalledAt)},
+			":registered_at":  &types.AttributeValueMemberN{Value: fmt.Sprint(state.RegisteredAt)},
+			":offline_since":  &types.AttributeValueMemberN{Value: fmt.Sprint(state.OfflineSince)},
+			":zero":           &types.AttributeValueMemberN{Value: "0"},
+			":empty_payload":  &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+			":updated_at":     &types.AttributeValueMemberN{Value: fmt.Sprint(state.LastUpdated)},
+		},
+	})
+	if err != nil {
+		if isConditionFailed(err) {
+			return fmt.Errorf("%w: %s", ErrDeviceExists, state.DeviceID)
+		}
+		return fmt.Errorf("failed to register device %s: %w", state.DeviceID, err)
+	}
+	return nil
+}
+
+// only the metadata changes, a decommissioned device is read-only
+func (s *DynamoStateStore) UpdateDeviceInfo(ctx context.Context, state models.DeviceState) error {
+	_, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: state.DeviceID},
+		},
+		ConditionExpression: aws.String("attribute_exists(device_id) AND #status <> :decommissioned"),
+		UpdateExpression:    aws.String("SET #name = :name, room_id = :room_id, links = :links, firmware = :firmware, installed_at = :installed_at, updated_at = :updated_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#name":   "name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":decommissioned": &types.AttributeValueMemberS{Value: StatusDecommissioned},
+			":name":           &types.AttributeValueMemberS{Value: state.Name},
+			":room_id":        &types.AttributeValueMemberS{Value: state.RoomID},
+			":links":          linksValue(state.Links),
+			":firmware":       &types.AttributeValueMemberS{Value: state.Firmware},
+			":installed_at":   &types.AttributeValueMemberN{Value: fmt.Sprint(state.InstalledAt)},
+			":updated_at":     &types.AttributeValueMemberN{Value: fmt.Sprint(state.LastUpdated)},
+		},
+	})
+	if err != nil {
+		if isConditionFailed(err) {
+			return fmt.Errorf("%w: %s", ErrDeviceNotFound, state.DeviceID)
+		}
+		return fmt.Errorf("failed to update device %s: %w", state.DeviceID, err)
+	}
+	return nil
+}
+
+// archives the device: its state stays readable, ingestion and commands refuse it from now on
+func (s *DynamoStateStore) DecommissionDevice(ctx context.Context, deviceID string, at int64) error {
+	_, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+		ConditionExpression: aws.String("attribute_exists(device_id)"),
+		UpdateExpression:    aws.String("SET #status = :decommissioned, decommissioned_at = :at, updated_at = :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":decommissioned": &types.AttributeValueMemberS{Value: StatusDecommissioned},
+			":at":             &types.AttributeValueMemberN{Value: fmt.Sprint(at)},
+		},
+	})
+	if err != nil {
+		if isConditionFailed(err) {
+			return fmt.Errorf("%w: %s", ErrDeviceNotFound, deviceID)
+		}
+		return fmt.Errorf("failed to decommission device %s: %w", deviceID, err)
+	}
+	return nil
+}
+
+func (s *DynamoStateStore) DeleteDevice(ctx context.Context, deviceID string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete device %s: %w", deviceID, err)
+	}
+	return nil
+}
+
+// no links is stored as an empty map, not left out, so an update clears the old ones
+func linksValue(links map[string]string) types.AttributeValue {
+	value := make(map[string]types.AttributeValue, len(links))
+	for relation, deviceID := range links {
+		value[relation] = &types.AttributeValueMemberS{Value: deviceID}
+	}
+	return &types.AttributeValueMemberM{Value: value}
+}
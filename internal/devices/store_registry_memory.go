@@ -0,0 +1,84 @@
+package devices
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// same conditions as the dynamodb registry
+func (store *MemoryStateStore) RegisterDevice(ctx context.Context, state models.DeviceState) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[state.DeviceID]
+	if exists && current.RegisteredAt > 0 && current.Status != StatusDecommissioned {
+		return fmt.Errorf("%w: %s", ErrDeviceExists, state.DeviceID)
+	}
+
+	current.DeviceID = state.DeviceID
+	current.Type = state.Type
+	current.Status = state.Status
+	current.Name = state.Name
+	current.RoomID = state.RoomID
+	current.Links = maps.Clone(state.Links)
+	current.Firmware = state.Firmware
+	current.InstalledAt = state.InstalledAt
+	current.RegisteredAt = state.RegisteredAt
+	current.OfflineSince = state.OfflineSince
+	current.DecommissionedAt = 0
+	current.LastUpdated = state.LastUpdated
+	if current.Payload == nil {
+		current.Payload = make(map[string]interface{})
+	}
+
+	store.states[state.DeviceID] = current
+	return nil
+}
+
+func (store *MemoryStateStore) UpdateDeviceInfo(ctx context.Context, state models.DeviceState) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[state.DeviceID]
+	if !exists || current.Status == StatusDecommissioned {
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, state.DeviceID)
+	}
+
+	current.Name = state.Name
+	current.RoomID = state.RoomID
+	current.Links = maps.Clone(state.Links)
+	current.Firmware = state.Firmware
+	current.InstalledAt = state.InstalledAt
+	current.LastUpdated = state.LastUpdated
+
+	store.states[state.DeviceID] = current
+	return nil
+}
+
+func (store *MemoryStateStore) DecommissionDevice(ctx context.Context, deviceID string, at int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[deviceID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrDeviceNotFound, deviceID)
+	}
+
+	current.Status = StatusDecommissioned
+	current.DecommissionedAt = at
+	current.LastUpdated = at
+
+	store.states[deviceID] = current
+	return nil
+}
+
+func (store *MemoryStateStore) DeleteDevice(ctx context.Context, deviceID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.states, deviceID)
+	return nil
+}
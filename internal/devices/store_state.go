@@ -1,9 +1,12 @@
 package devices
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,14 +20,36 @@ import (
 
 const (
 	OfflineLimit = 2 * time.Minute
+
+	batchGetLimit   = 100 // DynamoDB BatchGetItem hard limit
+	batchGetRetries = 3
+	batchGetBackoff = 100 * time.Millisecond
 )
 
-type StateStore struct {
+// ErrStaleUpdate is returned when an update carries an older last_seen_at than the stored one
+var ErrStaleUpdate = errors.New("stale device state update")
+
+// ErrDeviceDecommissioned is returned when a message raced the decommission of its device,
+// the state is left DECOMMISSIONED
+var ErrDeviceDecommissioned = errors.New("device is decommissioned")
+
+// StateStore keeps the live state of every device (one item per device)
+type StateStore interface {
+	UpdateFromTelemetry(ctx context.Context, tel models.Telemetry) error
+	UpdateHeartbeat(ctx context.Context, deviceID string) error
+	GetAllStates(ctx context.Context) ([]models.DeviceState, error)
+	GetStates(ctx context.Context, deviceIDs []string) ([]models.DeviceState, error)
+	GetStateByID(ctx context.Context, deviceID string) (*models.DeviceState, error)
+	MarkOffline(ctx context.Context, deviceID string, lastSeenAt int64, at int64) (bool, error)
+	MarkRecovered(ctx context.Context, deviceID string, offlineSince int64) (bool, error)
+}
+
+type DynamoStateStore struct {
 	Client    *dynamodb.Client
 	TableName string
 }
 
-func NewStateStore() (*StateStore, error) {
+func NewStateStore() (*DynamoStateStore, error) {
 	tableName := os.Getenv("DYNAMODB_DEVICE_STATE_TABLE")
 	if tableName == "" {
 		return nil, fmt.Errorf("DYNAMODB_DEVICE_STATE_TABLE is not set")
@@ -34,13 +59,13 @@ func NewStateStore() (*StateStore, error) {
 		return nil, fmt.Errorf("dynamodb client not initialized")
 	}
 
-	return &StateStore{
+	return &DynamoStateStore{
 		Client:    db.Client,
 		TableName: tableName,
 	}, nil
 }
 //updates live dashboard
-func (s *StateStore) UpdateFromTelemetry(ctx context.Context,tel models.Telemetry,) error {
+func (s *DynamoStateStore) UpdateFromTelemetry(ctx context.Context,tel models.Telemetry,) error {
 
 	now := time.Now().Unix()
 	
@@ -59,8 +84,9 @@ func (s *StateStore) UpdateFromTelemetry(ctx context.Context,tel models.Telemetr
 			},
 		},
 		ConditionExpression: aws.String(
-            "attribute_not_exists(last_seen_at) OR last_seen_at <= :last_seen",
+            "(attribute_not_exists(last_seen_at) OR last_seen_at <= :last_seen) AND #status <> :decommissioned",
         ),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 		UpdateExpression: aws.String(`
 			SET 
 				#type = :type,
@@ -83,11 +109,15 @@ func (s *StateStore) UpdateFromTelemetry(ctx context.Context,tel models.Telemetr
 			":payload":    payload, // This stores the raw map (temp, gas_level, etc.)
 			":last_seen":  &types.AttributeValueMemberN{Value: fmt.Sprint(tel.Timestamp)},
 			":updated_at": &types.AttributeValueMemberN{Value: fmt.Sprint(now)},
+			":decommissioned": &types.AttributeValueMemberS{Value: StatusDecommissioned},
 		},
 	}
 
 	_, err = s.Client.UpdateItem(ctx, input)
 	if err != nil {
+		if isConditionFailed(err) {
+			return updateRefused(err, tel.DeviceID)
+		}
 		return fmt.Errorf("failed to update device state: %w", err)
 	}
 
@@ -111,7 +141,7 @@ opState := "UNKNOWN"
 	return opState, health
 }
 
-func (s *StateStore) UpdateHeartbeat(ctx context.Context,deviceID string,) error {
+func (s *DynamoStateStore) UpdateHeartbeat(ctx context.Context,deviceID string,) error {
     now := time.Now().Unix()
 
     input := &dynamodb.UpdateItemInput{
@@ -120,8 +150,9 @@ func (s *StateStore) UpdateHeartbeat(ctx context.Context,deviceID string,) error
             "device_id": &types.AttributeValueMemberS{Value: deviceID},
         },
 		ConditionExpression: aws.String(
-            "attribute_not_exists(last_seen_at) OR last_seen_at <= :last_seen",
+            "(attribute_not_exists(last_seen_at) OR last_seen_at <= :last_seen) AND #status <> :decommissioned",
         ),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
         UpdateExpression: aws.String(
             "SET #status = :status, last_seen_at = :last_seen",
         ),
@@ -131,22 +162,106 @@ func (s *StateStore) UpdateHeartbeat(ctx context.Context,deviceID string,) error
         ExpressionAttributeValues: map[string]types.AttributeValue{
             ":status":    &types.AttributeValueMemberS{Value: "ONLINE"},
             ":last_seen": &types.AttributeValueMemberN{Value: fmt.Sprint(now)},
+            ":decommissioned": &types.AttributeValueMemberS{Value: StatusDecommissioned},
         },
     }
 
     _, err := s.Client.UpdateItem(ctx, input)
+    if isConditionFailed(err) {
+        return updateRefused(err, deviceID)
+    }
     return err
 }
 
-func ConnectionStatus(lastSeenAt int64) string {
-	if time.Since(time.Unix(lastSeenAt, 0)) > OfflineLimit {
+func isConditionFailed(err error) bool {
+	var conditionErr *types.ConditionalCheckFailedException
+	return errors.As(err, &conditionErr)
+}
+
+// tells a decommissioned device from a stale update by the item returned with the failed condition
+func updateRefused(err error, deviceID string) error {
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if status, ok := conditionErr.Item["status"].(*types.AttributeValueMemberS); ok && status.Value == StatusDecommissioned {
+			return fmt.Errorf("%w: device %s", ErrDeviceDecommissioned, deviceID)
+		}
+	}
+	return fmt.Errorf("%w: device %s", ErrStaleUpdate, deviceID)
+}
+
+// how long a device of this type can stay silent before it counts as offline
+func OfflineLimitFor(deviceType string) time.Duration {
+	if deviceRules, ok := Rules[deviceType]; ok && deviceRules.OfflineLimit > 0 {
+		return deviceRules.OfflineLimit
+	}
+	return OfflineLimit
+}
+
+func ConnectionStatus(deviceType string, lastSeenAt int64) string {
+	if time.Since(time.Unix(lastSeenAt, 0)) > OfflineLimitFor(deviceType) {
 		return "OFFLINE"
 	}
 	return "ONLINE"
 }
 
+// conditional on last_seen_at so a device that reported since the scan is not marked offline,
+// and on offline_since so two sweeps never report the same outage twice
+func (s *DynamoStateStore) MarkOffline(ctx context.Context, deviceID string, lastSeenAt int64, at int64) (bool, error) {
+	_, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+		ConditionExpression: aws.String("last_seen_at = :last_seen AND (attribute_not_exists(offline_since) OR offline_since = :zero)"),
+		UpdateExpression:    aws.String("SET #status = :offline, offline_since = :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":last_seen": &types.AttributeValueMemberN{Value: fmt.Sprint(lastSeenAt)},
+			":zero":      &types.AttributeValueMemberN{Value: "0"},
+			":offline":   &types.AttributeValueMemberS{Value: "OFFLINE"},
+			":at":        &types.AttributeValueMemberN{Value: fmt.Sprint(at)},
+		},
+	})
+	if err != nil {
+		if isConditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to mark device %s offline: %w", deviceID, err)
+	}
+	return true, nil
+}
+
+// clears the outage once ingestion has set the device back ONLINE, only one sweep wins
+func (s *DynamoStateStore) MarkRecovered(ctx context.Context, deviceID string, offlineSince int64) (bool, error) {
+	_, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.TableName),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+		ConditionExpression: aws.String("offline_since = :since AND #status = :online"),
+		UpdateExpression:    aws.String("SET offline_since = :zero"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":since":  &types.AttributeValueMemberN{Value: fmt.Sprint(offlineSince)},
+			":online": &types.AttributeValueMemberS{Value: "ONLINE"},
+			":zero":   &types.AttributeValueMemberN{Value: "0"},
+		},
+	})
+	if err != nil {
+		if isConditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to mark device %s recovered: %w", deviceID, err)
+	}
+	return true, nil
+}
+
 // retrieve all devices states for the dashboard
-func (store *StateStore) GetAllStates(ctx context.Context) ([]models.DeviceState, error) {
+func (store *DynamoStateStore) GetAllStates(ctx context.Context) ([]models.DeviceState, error) {
 	var states []models.DeviceState
 	
 	var lastEvaluatedKey map[string]types.AttributeValue
@@ -183,8 +298,70 @@ func (store *StateStore) GetAllStates(ctx context.Context) ([]models.DeviceState
 
 
 
+// the states of the listed devices ordered by id, unknown ids are left out. reads them by key
+// 100 at a time instead of scanning the table
+func (store *DynamoStateStore) GetStates(ctx context.Context, deviceIDs []string) ([]models.DeviceState, error) {
+	states := []models.DeviceState{}
+
+	for i := 0; i < len(deviceIDs); i += batchGetLimit {
+		keys := make([]map[string]types.AttributeValue, 0, batchGetLimit)
+		for _, deviceID := range deviceIDs[i:min(i+batchGetLimit, len(deviceIDs))] {
+			keys = append(keys, map[string]types.AttributeValue{
+				"device_id": &types.AttributeValueMemberS{Value: deviceID},
+			})
+		}
+
+		items, err := store.batchGet(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		var page []models.DeviceState
+		if err := attributevalue.UnmarshalListOfMaps(items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal device states: %w", err)
+		}
+		states = append(states, page...)
+	}
+
+	slices.SortFunc(states, func(a, b models.DeviceState) int {
+		return cmp.Compare(a.DeviceID, b.DeviceID)
+	})
+	return states, nil
+}
+
+// retries the unprocessed keys with a growing backoff
+func (store *DynamoStateStore) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	pending := &types.KeysAndAttributes{Keys: keys}
+
+	for attempt := 0; attempt <= batchGetRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(1<<uint(attempt-1)) * batchGetBackoff):
+			}
+		}
+
+		output, err := store.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{store.TableName: *pending},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch get device states: %w", err)
+		}
+		items = append(items, output.Responses[store.TableName]...)
+
+		unprocessed, ok := output.UnprocessedKeys[store.TableName]
+		if !ok || len(unprocessed.Keys) == 0 {
+			return items, nil
+		}
+		pending = &unprocessed
+	}
+
+	return nil, fmt.Errorf("batch get device states: keys still unprocessed after %d retries", batchGetRetries)
+}
+
 // retrieve the device state by id
-func (s *StateStore) GetStateByID(ctx context.Context, deviceID string) (*models.DeviceState, error) {
+func (s *DynamoStateStore) GetStateByID(ctx context.Context, deviceID string) (*models.DeviceState, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(s.TableName),
 		Key: map[string]types.AttributeValue{
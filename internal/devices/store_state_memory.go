@@ -0,0 +1,162 @@
+package devices
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MemoryStateStore is an in-process StateStore used for tests and local demos
+type MemoryStateStore struct {
+	mu     sync.RWMutex
+	states map[string]models.DeviceState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		states: make(map[string]models.DeviceState),
+	}
+}
+
+// same condition as dynamodb: (attribute_not_exists(last_seen_at) OR last_seen_at <= :last_seen)
+// AND #status <> :decommissioned
+func (store *MemoryStateStore) UpdateFromTelemetry(ctx context.Context, tel models.Telemetry) error {
+	opState, health := ExtractState(tel.Type, tel.Payload)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[tel.DeviceID]
+	if err := refuseUpdate(current, exists, tel.Timestamp); err != nil {
+		return err
+	}
+
+	current.DeviceID = tel.DeviceID
+	current.Type = tel.Type
+	current.Status = "ONLINE"
+	current.OperationalState = opState
+	current.Health = health
+	current.Payload = maps.Clone(tel.Payload)
+	current.LastSeenAt = tel.Timestamp
+	current.LastUpdated = time.Now().Unix()
+
+	store.states[tel.DeviceID] = current
+	return nil
+}
+
+func (store *MemoryStateStore) UpdateHeartbeat(ctx context.Context, deviceID string) error {
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[deviceID]
+	if err := refuseUpdate(current, exists, now); err != nil {
+		return err
+	}
+
+	current.DeviceID = deviceID
+	current.Status = "ONLINE"
+	current.LastSeenAt = now
+
+	store.states[deviceID] = current
+	return nil
+}
+
+func (store *MemoryStateStore) GetAllStates(ctx context.Context) ([]models.DeviceState, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	states := make([]models.DeviceState, 0, len(store.states))
+	for _, state := range store.states {
+		states = append(states, copyState(state))
+	}
+
+	slices.SortFunc(states, func(a, b models.DeviceState) int {
+		return cmp.Compare(a.DeviceID, b.DeviceID)
+	})
+
+	return states, nil
+}
+
+func (store *MemoryStateStore) GetStates(ctx context.Context, deviceIDs []string) ([]models.DeviceState, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	states := make([]models.DeviceState, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		if state, exists := store.states[deviceID]; exists {
+			states = append(states, copyState(state))
+		}
+	}
+
+	slices.SortFunc(states, func(a, b models.DeviceState) int {
+		return cmp.Compare(a.DeviceID, b.DeviceID)
+	})
+	return states, nil
+}
+
+// returns nil, nil when the device doesn't exist (same as the dynamodb store)
+func (store *MemoryStateStore) GetStateByID(ctx context.Context, deviceID string) (*models.DeviceState, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	state, exists := store.states[deviceID]
+	if !exists {
+		return nil, nil
+	}
+
+	copied := copyState(state)
+	return &copied, nil
+}
+
+func (store *MemoryStateStore) MarkOffline(ctx context.Context, deviceID string, lastSeenAt int64, at int64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[deviceID]
+	if !exists || current.LastSeenAt != lastSeenAt || current.OfflineSince != 0 {
+		return false, nil
+	}
+
+	current.Status = "OFFLINE"
+	current.OfflineSince = at
+	store.states[deviceID] = current
+	return true, nil
+}
+
+func (store *MemoryStateStore) MarkRecovered(ctx context.Context, deviceID string, offlineSince int64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	current, exists := store.states[deviceID]
+	if !exists || current.OfflineSince != offlineSince || current.Status != "ONLINE" {
+		return false, nil
+	}
+
+	current.OfflineSince = 0
+	store.states[deviceID] = current
+	return true, nil
+}
+
+func refuseUpdate(current models.DeviceState, exists bool, lastSeenAt int64) error {
+	switch {
+	case exists && current.Status == StatusDecommissioned:
+		return fmt.Errorf("%w: device %s", ErrDeviceDecommissioned, current.DeviceID)
+	case exists && current.LastSeenAt > lastSeenAt:
+		return fmt.Errorf("%w: device %s", ErrStaleUpdate, current.DeviceID)
+	}
+	return nil
+}
+
+// handlers write extra fields into the payload, so callers never get the stored map
+func copyState(state models.DeviceState) models.DeviceState {
+	state.Payload = maps.Clone(state.Payload)
+	return state
+}
@@ -0,0 +1,55 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func lockReading(ts int64, lockState string) models.Telemetry {
+	return models.Telemetry{DeviceID: "door-1", Timestamp: ts, Type: "door-actuator", Payload: map[string]interface{}{"lock_state": lockState}}
+}
+
+// same conditions as the dynamodb update: last_seen_at never goes back, a decommissioned device is never updated
+func TestMemoryStateStoreUpdateFromTelemetry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+
+	steps := []struct {
+		name    string
+		reading models.Telemetry
+		want    error
+		state   string // operational state after the step
+		seen    int64  // last_seen_at after the step
+	}{
+		{"first reading", lockReading(1000, "LOCKED"), nil, "LOCKED", 1000},
+		{"newer", lockReading(1010, "UNLOCKED"), nil, "UNLOCKED", 1010},
+		{"same second", lockReading(1010, "LOCKED"), nil, "LOCKED", 1010},
+		{"older", lockReading(1005, "UNLOCKED"), ErrStaleUpdate, "LOCKED", 1010},
+	}
+	for _, step := range steps {
+		err := store.UpdateFromTelemetry(ctx, step.reading)
+		if !errors.Is(err, step.want) || (step.want == nil && err != nil) {
+			t.Errorf("%s: UpdateFromTelemetry = %v, want %v", step.name, err, step.want)
+		}
+		state, _ := store.GetStateByID(ctx, "door-1")
+		if state == nil || state.OperationalState != step.state || state.LastSeenAt != step.seen {
+			t.Errorf("%s: state = %+v, want %s at %d", step.name, state, step.state, step.seen)
+		}
+	}
+
+	if err := store.DecommissionDevice(ctx, "door-1", 1020); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateFromTelemetry(ctx, lockReading(1030, "UNLOCKED")); !errors.Is(err, ErrDeviceDecommissioned) {
+		t.Errorf("reading after decommission = %v, want ErrDeviceDecommissioned", err)
+	}
+	if err := store.UpdateHeartbeat(ctx, "door-1"); !errors.Is(err, ErrDeviceDecommissioned) {
+		t.Errorf("heartbeat after decommission = %v, want ErrDeviceDecommissioned", err)
+	}
+	if state, _ := store.GetStateByID(ctx, "door-1"); state.Status != StatusDecommissioned || state.LastSeenAt != 1010 {
+		t.Errorf("state = %s at %d, want it left decommissioned at 1010", state.Status, state.LastSeenAt)
+	}
+}
@@ -0,0 +1,96 @@
+package homes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// ErrCommandRevoked is returned when the member who set up a schedule or rule may no longer send its command
+var ErrCommandRevoked = errors.New("command no longer allowed")
+
+// DeviceIDs returns the devices of every home the user belongs to, expired guest access is skipped
+func DeviceIDs(ctx context.Context, store HomeStore, userID string) (map[string]bool, error) {
+	memberships, err := store.GetMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	deviceIDs := make(map[string]bool)
+	for _, membership := range memberships {
+		if !Active(membership, now) {
+			continue
+		}
+		deviceList, err := store.GetHomeDevices(ctx, membership.HomeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range deviceList {
+			deviceIDs[device.DeviceID] = true
+		}
+	}
+	return deviceIDs, nil
+}
+
+// ActiveMember returns the user's membership of the home, nil when there is none or it expired
+func ActiveMember(ctx context.Context, store HomeStore, homeID string, userID string) (*models.HomeMember, error) {
+	member, err := store.GetMember(ctx, homeID, userID)
+	if err != nil || member == nil {
+		return nil, err
+	}
+	if !Active(*member, time.Now().Unix()) {
+		return nil, nil
+	}
+	return member, nil
+}
+
+// DeviceMember returns the user's membership of the home holding the device, nil when the user can't see it
+func DeviceMember(ctx context.Context, store HomeStore, userID string, deviceID string) (*models.HomeMember, error) {
+	device, err := store.GetDeviceHome(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, err
+	}
+	return ActiveMember(ctx, store, device.HomeID, userID)
+}
+
+// whether the device is in one of the user's homes
+func CanAccessDevice(ctx context.Context, store HomeStore, userID string, deviceID string) (bool, error) {
+	member, err := DeviceMember(ctx, store, userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+	return member != nil, nil
+}
+
+// AuthorizeCommand checks, each time a schedule or rule fires, that the device is still in homeID and that
+// userID is still an active member allowed to send the action. a refusal wraps ErrCommandRevoked, records
+// saved without home or creator are refused too
+func AuthorizeCommand(ctx context.Context, store HomeStore, homeID string, userID string, deviceID string, deviceType string, action string) error {
+	if homeID == "" || userID == "" {
+		return fmt.Errorf("%w: no home or creator recorded", ErrCommandRevoked)
+	}
+
+	device, err := store.GetDeviceHome(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.HomeID != homeID {
+		return fmt.Errorf("%w: device %s is no longer in home %s", ErrCommandRevoked, deviceID, homeID)
+	}
+
+	member, err := ActiveMember(ctx, store, homeID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("%w: %s is no longer a member of home %s", ErrCommandRevoked, userID, homeID)
+	}
+	if !Allows(*member, CommandPermission(deviceType, action)) {
+		return fmt.Errorf("%w: %s may no longer %s %s", ErrCommandRevoked, userID, action, deviceType)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package homes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestAllows(t *testing.T) {
+	unlock := CommandPermission("door-actuator", "UNLOCK")
+	tests := []struct {
+		name   string
+		member models.HomeMember
+		want   bool
+	}{
+		{"owner", models.HomeMember{Role: RoleOwner, Permissions: []string{}}, true},
+		{"member defaults", models.HomeMember{Role: RoleMember}, true},
+		{"legacy member without role", models.HomeMember{}, true},
+		{"member view only", models.HomeMember{Role: RoleMember, Permissions: []string{}}, false},
+		{"guest defaults", models.HomeMember{Role: RoleGuest}, false},
+		{"guest with the action", models.HomeMember{Role: RoleGuest, Permissions: []string{unlock}}, true},
+		{"guest with the type", models.HomeMember{Role: RoleGuest, Permissions: []string{"door-actuator:*"}}, true},
+		{"guest with another action", models.HomeMember{Role: RoleGuest, Permissions: []string{"door-actuator:LOCK"}}, false},
+	}
+	for _, test := range tests {
+		if got := Allows(test.member, unlock); got != test.want {
+			t.Errorf("%s: Allows = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAuthorizeCommand(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name       string
+		homeID     string
+		userID     string
+		member     *models.HomeMember
+		deviceHome string // home the device is in now
+		want       error
+	}{
+		{"allowed", "home-1", "user-1", &models.HomeMember{Role: RoleMember}, "home-1", nil},
+		{"saved without home", "", "user-1", &models.HomeMember{Role: RoleMember}, "home-1", ErrCommandRevoked},
+		{"saved without creator", "home-1", "", &models.HomeMember{Role: RoleMember}, "home-1", ErrCommandRevoked},
+		{"device moved", "home-1", "user-1", &models.HomeMember{Role: RoleMember}, "home-2", ErrCommandRevoked},
+		{"device released", "home-1", "user-1", &models.HomeMember{Role: RoleMember}, "", ErrCommandRevoked},
+		{"member removed", "home-1", "user-1", nil, "home-1", ErrCommandRevoked},
+		{"guest expired", "home-1", "user-1", &models.HomeMember{Role: RoleGuest, Permissions: []string{"door-actuator:*"}, ExpiresAt: now - 60}, "home-1", ErrCommandRevoked},
+		{"permission taken", "home-1", "user-1", &models.HomeMember{Role: RoleMember, Permissions: []string{"door-actuator:LOCK"}}, "home-1", ErrCommandRevoked},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryHomeStore()
+			if test.deviceHome != "" {
+				if _, err := store.ClaimDevice(ctx, models.HomeDevice{DeviceID: "door-1", HomeID: test.deviceHome}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.member != nil {
+				member := *test.member
+				member.HomeID, member.UserID = "home-1", "user-1"
+				if err := store.SaveMember(ctx, member); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := AuthorizeCommand(ctx, store, test.homeID, test.userID, "door-1", "door-actuator", "UNLOCK")
+			if !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+				t.Errorf("AuthorizeCommand = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestPrepareMemberGuestWildcard(t *testing.T) {
+	guest := models.HomeMember{Role: RoleGuest, Permissions: []string{PermissionAll}}
+	if err := PrepareMember(&guest, time.Now()); !errors.Is(err, ErrInvalidMember) {
+		t.Errorf("guest granted %q: %v, want ErrInvalidMember", PermissionAll, err)
+	}
+}
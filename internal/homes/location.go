@@ -0,0 +1,24 @@
+package homes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+var ErrInvalidLocation = errors.New("invalid location")
+
+// ValidateLocation checks the coordinates of a home, nil means no location
+func ValidateLocation(location *models.Location) error {
+	if location == nil {
+		return nil
+	}
+	if location.Latitude < -90 || location.Latitude > 90 {
+		return fmt.Errorf("%w: latitude must be between -90 and 90", ErrInvalidLocation)
+	}
+	if location.Longitude < -180 || location.Longitude > 180 {
+		return fmt.Errorf("%w: longitude must be between -180 and 180", ErrInvalidLocation)
+	}
+	return nil
+}
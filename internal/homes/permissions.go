@@ -0,0 +1,120 @@
+package homes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	RoleOwner  = "OWNER"
+	RoleMember = "MEMBER"
+	RoleGuest  = "GUEST"
+)
+
+// PermissionAll grants every command, "<device-type>:*" every command of one type
+const PermissionAll = "*"
+
+// guests added without expires_at keep access this long
+const DefaultGuestAccess = 24 * time.Hour
+
+var ErrInvalidMember = errors.New("invalid home member")
+
+// what each role may do when the member has no permissions of its own, everyone can view
+var rolePermissions = map[string][]string{
+	RoleOwner:  {PermissionAll},
+	RoleMember: {PermissionAll},
+	RoleGuest:  {},
+}
+
+// CommandPermission names the right to send one action to one device type, e.g. "door-actuator:UNLOCK"
+func CommandPermission(deviceType string, action string) string {
+	return deviceType + ":" + action
+}
+
+// records saved before roles existed have none, they were full members
+func RoleOf(member models.HomeMember) string {
+	if member.Role == "" {
+		return RoleMember
+	}
+	return member.Role
+}
+
+// guests lose access once ExpiresAt has passed
+func Active(member models.HomeMember, now int64) bool {
+	return member.ExpiresAt == 0 || now < member.ExpiresAt
+}
+
+// Allows tells if the member may use the permission, the owner always can
+func Allows(member models.HomeMember, permission string) bool {
+	role := RoleOf(member)
+	granted := rolePermissions[role]
+	if member.Permissions != nil && role != RoleOwner {
+		granted = member.Permissions
+	}
+
+	deviceType, _, _ := strings.Cut(permission, ":")
+	for _, grant := range granted {
+		if grant == PermissionAll || grant == permission || grant == deviceType+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareMember validates a member added or changed by the owner and fills the guest expiry
+func PrepareMember(member *models.HomeMember, now time.Time) error {
+	if member.Role == "" {
+		member.Role = RoleMember
+	}
+
+	switch member.Role {
+	case RoleMember:
+		if member.ExpiresAt != 0 {
+			return fmt.Errorf("%w: only guests can have expires_at", ErrInvalidMember)
+		}
+	case RoleGuest:
+		if member.ExpiresAt == 0 {
+			member.ExpiresAt = now.Add(DefaultGuestAccess).Unix()
+		}
+		if member.ExpiresAt <= now.Unix() {
+			return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidMember)
+		}
+	default:
+		return fmt.Errorf("%w: role must be MEMBER or GUEST", ErrInvalidMember)
+	}
+
+	for _, permission := range member.Permissions {
+		if err := validatePermission(member.Role, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// "*", "<device-type>:*" or "<device-type>:<ACTION>" of an actuator. guests are granted
+// commands one device type at a time, never "*"
+func validatePermission(role string, permission string) error {
+	if permission == PermissionAll {
+		if role == RoleGuest {
+			return fmt.Errorf("%w: guests can't be granted %q", ErrInvalidMember, PermissionAll)
+		}
+		return nil
+	}
+
+	deviceType, action, found := strings.Cut(permission, ":")
+	if !found || !devices.IsActuator(deviceType) {
+		return fmt.Errorf("%w: unknown permission %q", ErrInvalidMember, permission)
+	}
+	if action == "*" {
+		return nil
+	}
+	if _, ok := devices.Rules[deviceType].Commands[action]; !ok {
+		return fmt.Errorf("%w: %s does not accept %s", ErrInvalidMember, deviceType, action)
+	}
+	return nil
+}
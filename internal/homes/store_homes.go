@@ -0,0 +1,304 @@
+package homes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const homeIndex = "HomeIndex"
+
+// HomeStore keeps the homes (home_id), who belongs to them (user_id + home_id)
+// and which home holds each device (device_id)
+type HomeStore interface {
+	SaveHome(ctx context.Context, home models.Home) error
+	GetHome(ctx context.Context, homeID string) (*models.Home, error)
+	ClaimDevice(ctx context.Context, device models.HomeDevice) (bool, error)
+	ReleaseDevice(ctx context.Context, homeID string, deviceID string) error
+	GetDeviceHome(ctx context.Context, deviceID string) (*models.HomeDevice, error)
+	GetHomeDevices(ctx context.Context, homeID string) ([]models.HomeDevice, error)
+	SaveMember(ctx context.Context, member models.HomeMember) error
+	GetMember(ctx context.Context, homeID string, userID string) (*models.HomeMember, error)
+	DeleteMember(ctx context.Context, homeID string, userID string) error
+	GetMembers(ctx context.Context, homeID string) ([]models.HomeMember, error)
+	GetMemberships(ctx context.Context, userID string) ([]models.HomeMember, error)
+}
+
+type DynamoHomeStore struct {
+	Client       *dynamodb.Client
+	TableName    string
+	MembersTable string
+	DevicesTable string
+}
+
+func NewHomeStore() (*DynamoHomeStore, error) {
+	tableName := os.Getenv("DYNAMODB_HOMES_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_HOMES_TABLE environment variable is not set")
+	}
+
+	membersTable := os.Getenv("DYNAMODB_HOME_MEMBERS_TABLE")
+	if membersTable == "" {
+		return nil, fmt.Errorf("DYNAMODB_HOME_MEMBERS_TABLE environment variable is not set")
+	}
+
+	devicesTable := os.Getenv("DYNAMODB_HOME_DEVICES_TABLE")
+	if devicesTable == "" {
+		return nil, fmt.Errorf("DYNAMODB_HOME_DEVICES_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoHomeStore{
+		Client:       db.Client,
+		TableName:    tableName,
+		MembersTable: membersTable,
+		DevicesTable: devicesTable,
+	}, nil
+}
+
+func memberKey(homeID string, userID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id": &types.AttributeValueMemberS{Value: userID},
+		"home_id": &types.AttributeValueMemberS{Value: homeID},
+	}
+}
+
+func (store *DynamoHomeStore) SaveHome(ctx context.Context, home models.Home) error {
+	item, err := attributevalue.MarshalMap(home)
+	if err != nil {
+		return fmt.Errorf("failed to marshal home: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store home in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoHomeStore) GetHome(ctx context.Context, homeID string) (*models.Home, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"home_id": &types.AttributeValueMemberS{Value: homeID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home %s: %w", homeID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var home models.Home
+	if err = attributevalue.UnmarshalMap(result.Item, &home); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal home %s: %w", homeID, err)
+	}
+
+	return &home, nil
+}
+
+// conditional so a device already in another home can't be taken over, re-adding it to the same home is fine
+func (store *DynamoHomeStore) ClaimDevice(ctx context.Context, device models.HomeDevice) (bool, error) {
+	item, err := attributevalue.MarshalMap(device)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal home device: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(store.DevicesTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(device_id) OR home_id = :home_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":home_id": &types.AttributeValueMemberS{Value: device.HomeID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim device %s for home %s: %w", device.DeviceID, device.HomeID, err)
+	}
+	return true, nil
+}
+
+// only releases the device from homeID, a device of another home is left alone
+func (store *DynamoHomeStore) ReleaseDevice(ctx context.Context, homeID string, deviceID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.DevicesTable),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+		ConditionExpression: aws.String("home_id = :home_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":home_id": &types.AttributeValueMemberS{Value: homeID},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to release device %s from home %s: %w", deviceID, homeID, err)
+	}
+	return nil
+}
+
+// returns nil, nil when the device is in no home
+func (store *DynamoHomeStore) GetDeviceHome(ctx context.Context, deviceID string) (*models.HomeDevice, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.DevicesTable),
+		Key: map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home of device %s: %w", deviceID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var device models.HomeDevice
+	if err = attributevalue.UnmarshalMap(result.Item, &device); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal home of device %s: %w", deviceID, err)
+	}
+
+	return &device, nil
+}
+
+func (store *DynamoHomeStore) GetHomeDevices(ctx context.Context, homeID string) ([]models.HomeDevice, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.DevicesTable),
+		IndexName:              aws.String(homeIndex),
+		KeyConditionExpression: aws.String("home_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: homeID},
+		},
+	}
+
+	deviceList := []models.HomeDevice{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query devices of home %s: %w", homeID, err)
+		}
+
+		var items []models.HomeDevice
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal devices of home %s: %w", homeID, err)
+		}
+		deviceList = append(deviceList, items...)
+	}
+
+	return deviceList, nil
+}
+
+func (store *DynamoHomeStore) SaveMember(ctx context.Context, member models.HomeMember) error {
+	item, err := attributevalue.MarshalMap(member)
+	if err != nil {
+		return fmt.Errorf("failed to marshal home member: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.MembersTable),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store home member in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoHomeStore) GetMember(ctx context.Context, homeID string, userID string) (*models.HomeMember, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.MembersTable),
+		Key:       memberKey(homeID, userID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get member %s of home %s: %w", userID, homeID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var member models.HomeMember
+	if err = attributevalue.UnmarshalMap(result.Item, &member); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal member %s of home %s: %w", userID, homeID, err)
+	}
+
+	return &member, nil
+}
+
+func (store *DynamoHomeStore) DeleteMember(ctx context.Context, homeID string, userID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.MembersTable),
+		Key:       memberKey(homeID, userID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete member %s of home %s: %w", userID, homeID, err)
+	}
+	return nil
+}
+
+func (store *DynamoHomeStore) GetMembers(ctx context.Context, homeID string) ([]models.HomeMember, error) {
+	return store.queryMembers(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(store.MembersTable),
+		IndexName:              aws.String(homeIndex),
+		KeyConditionExpression: aws.String("home_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: homeID},
+		},
+	})
+}
+
+func (store *DynamoHomeStore) GetMemberships(ctx context.Context, userID string) ([]models.HomeMember, error) {
+	return store.queryMembers(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(store.MembersTable),
+		KeyConditionExpression: aws.String("user_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+}
+
+func (store *DynamoHomeStore) queryMembers(ctx context.Context, input *dynamodb.QueryInput) ([]models.HomeMember, error) {
+	memberList := []models.HomeMember{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query home members: %w", err)
+		}
+
+		var items []models.HomeMember
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal home members: %w", err)
+		}
+		memberList = append(memberList, items...)
+	}
+
+	return memberList, nil
+}
@@ -0,0 +1,149 @@
+package homes
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type memberKeyPair struct {
+	HomeID string
+	UserID string
+}
+
+// MemoryHomeStore is an in-process HomeStore used for tests and local demos
+type MemoryHomeStore struct {
+	mu      sync.Mutex
+	homes   map[string]models.Home
+	members map[memberKeyPair]models.HomeMember
+	devices map[string]models.HomeDevice
+}
+
+func NewMemoryHomeStore() *MemoryHomeStore {
+	return &MemoryHomeStore{
+		homes:   make(map[string]models.Home),
+		members: make(map[memberKeyPair]models.HomeMember),
+		devices: make(map[string]models.HomeDevice),
+	}
+}
+
+func (store *MemoryHomeStore) SaveHome(ctx context.Context, home models.Home) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.homes[home.HomeID] = home
+	return nil
+}
+
+func (store *MemoryHomeStore) GetHome(ctx context.Context, homeID string) (*models.Home, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	home, exists := store.homes[homeID]
+	if !exists {
+		return nil, nil
+	}
+	return &home, nil
+}
+
+func (store *MemoryHomeStore) ClaimDevice(ctx context.Context, device models.HomeDevice) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if current, exists := store.devices[device.DeviceID]; exists && current.HomeID != device.HomeID {
+		return false, nil
+	}
+	store.devices[device.DeviceID] = device
+	return true, nil
+}
+
+func (store *MemoryHomeStore) ReleaseDevice(ctx context.Context, homeID string, deviceID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if current, exists := store.devices[deviceID]; exists && current.HomeID == homeID {
+		delete(store.devices, deviceID)
+	}
+	return nil
+}
+
+func (store *MemoryHomeStore) GetDeviceHome(ctx context.Context, deviceID string) (*models.HomeDevice, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	device, exists := store.devices[deviceID]
+	if !exists {
+		return nil, nil
+	}
+	return &device, nil
+}
+
+func (store *MemoryHomeStore) GetHomeDevices(ctx context.Context, homeID string) ([]models.HomeDevice, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deviceList := make([]models.HomeDevice, 0)
+	for _, device := range store.devices {
+		if device.HomeID == homeID {
+			deviceList = append(deviceList, device)
+		}
+	}
+	slices.SortFunc(deviceList, func(a, b models.HomeDevice) int {
+		return cmp.Compare(a.DeviceID, b.DeviceID)
+	})
+	return deviceList, nil
+}
+
+func (store *MemoryHomeStore) SaveMember(ctx context.Context, member models.HomeMember) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.members[memberKeyPair{member.HomeID, member.UserID}] = member
+	return nil
+}
+
+func (store *MemoryHomeStore) GetMember(ctx context.Context, homeID string, userID string) (*models.HomeMember, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	member, exists := store.members[memberKeyPair{homeID, userID}]
+	if !exists {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+func (store *MemoryHomeStore) DeleteMember(ctx context.Context, homeID string, userID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.members, memberKeyPair{homeID, userID})
+	return nil
+}
+
+func (store *MemoryHomeStore) GetMembers(ctx context.Context, homeID string) ([]models.HomeMember, error) {
+	return store.filterMembers(func(member models.HomeMember) bool { return member.HomeID == homeID }), nil
+}
+
+func (store *MemoryHomeStore) GetMemberships(ctx context.Context, userID string) ([]models.HomeMember, error) {
+	return store.filterMembers(func(member models.HomeMember) bool { return member.UserID == userID }), nil
+}
+
+func (store *MemoryHomeStore) filterMembers(keep func(models.HomeMember) bool) []models.HomeMember {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	memberList := make([]models.HomeMember, 0)
+	for _, member := range store.members {
+		if keep(member) {
+			memberList = append(memberList, member)
+		}
+	}
+	slices.SortFunc(memberList, func(a, b models.HomeMember) int {
+		return cmp.Or(cmp.Compare(a.HomeID, b.HomeID), cmp.Compare(a.UserID, b.UserID))
+	})
+	return memberList
+}
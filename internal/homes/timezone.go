@@ -0,0 +1,75 @@
+package homes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+// ValidateTimeZone checks the IANA name of a home's zone (Africa/Cairo), empty means UTC
+func ValidateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("%w: unknown time_zone %q", ErrInvalidTimeZone, name)
+	}
+	return nil
+}
+
+// Zone is where the charts and times of the home are shown, UTC when it has none
+func Zone(home *models.Home) *time.Location {
+	if home == nil || home.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(home.TimeZone)
+	if err != nil {
+		// the zone was valid when saved, a binary without tzdata lands here
+		slog.Error("failed to load home time zone, using UTC", "home_id", home.HomeID, "time_zone", home.TimeZone, "error", err)
+		return time.UTC
+	}
+	return location
+}
+
+// DeviceZone is the zone of the device's home, UTC when it is in none
+func DeviceZone(ctx context.Context, store HomeStore, deviceID string) (*time.Location, error) {
+	homeDevice, err := store.GetDeviceHome(ctx, deviceID)
+	if err != nil || homeDevice == nil {
+		return time.UTC, err
+	}
+	home, err := store.GetHome(ctx, homeDevice.HomeID)
+	if err != nil {
+		return time.UTC, err
+	}
+	return Zone(home), nil
+}
+
+// UserZone is the zone of the first of the user's homes that has one, for the views spanning
+// every home. UTC when none has
+func UserZone(ctx context.Context, store HomeStore, userID string) (*time.Location, error) {
+	memberships, err := store.GetMemberships(ctx, userID)
+	if err != nil {
+		return time.UTC, err
+	}
+
+	now := time.Now().Unix()
+	for _, membership := range memberships {
+		if !Active(membership, now) {
+			continue
+		}
+		home, err := store.GetHome(ctx, membership.HomeID)
+		if err != nil {
+			return time.UTC, err
+		}
+		if home != nil && home.TimeZone != "" {
+			return Zone(home), nil
+		}
+	}
+	return time.UTC, nil
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/automation"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/validation"
@@ -15,9 +18,13 @@ import (
 
 type Service struct {
 	Logger         *slog.Logger
-	TelemetryStore *telemetry.TelemetryStore
-	AlertStore     *alerts.AlertStore
-	StateStore     *devices.StateStore
+	TelemetryStore telemetry.TelemetryStore
+	AlertStore     alerts.AlertStore
+	StateStore     devices.StateStore
+	CommandStore   commands.CommandStore
+	Quarantine     devices.QuarantineStore // optional, nil only logs the refused messages
+	Automations    *automation.Engine // optional, nil disables automations
+	Thresholds     *alerts.Monitor    // optional, nil disables threshold alerts
 }
 
 func (s *Service) HandleRequest(ctx context.Context, event map[string]interface{}) (err error) {
@@ -34,20 +41,69 @@ func (s *Service) HandleRequest(ctx context.Context, event map[string]interface{
 		s.logValidationError(err, envelope.DeviceID)
 		return err
 	}
+
+	state, err := s.admit(ctx, deviceID, messageType, envelope)
+	if err != nil || state == nil {
+		return err
+	}
 	
 	switch messageType {
 	case "telemetry":
-		return s.handleTelemetry(ctx, deviceID, envelope, isBatch)
+		return s.handleTelemetry(ctx, *state, envelope, isBatch)
 
 	case "alerts":
-		return s.handleAlert(ctx, deviceID, envelope)
+		return s.handleAlert(ctx, *state, envelope)
+
+	case "command_ack":
+		return s.handleCommandAck(ctx, deviceID, envelope)
 
 	default:
 		return fmt.Errorf("unknown message type: %s", messageType)
 	}
 }
 
-func (service *Service) handleTelemetry(ctx context.Context, deviceID string, envelope models.MQTTEnvelope, isBatch bool) error {
+// only registered devices of the declared type get through, their state before the message is returned.
+// the rest is quarantined and dropped without an error (nil state), so IoT Core doesn't retry it
+func (service *Service) admit(ctx context.Context, deviceID string, messageType string, envelope models.MQTTEnvelope) (*models.DeviceState, error) {
+	state, err := service.StateStore.GetStateByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reason string
+	switch {
+	case state == nil:
+		reason = devices.QuarantineUnregistered
+	case state.Status == devices.StatusDecommissioned:
+		reason = devices.QuarantineDecommissioned
+	case state.Type != "" && state.Type != envelope.Type:
+		reason = devices.QuarantineTypeMismatch
+	default:
+		return state, nil
+	}
+
+	service.Logger.Warn("message quarantined", "device_id", deviceID, "message_type", messageType, "type", envelope.Type, "reason", reason)
+	if service.Quarantine == nil {
+		return nil, nil
+	}
+
+	err = service.Quarantine.QuarantineMessage(ctx, models.QuarantinedDevice{
+		DeviceID:    deviceID,
+		Reason:      reason,
+		MessageType: messageType,
+		Type:        envelope.Type,
+		LastPayload: envelope.Payload,
+		LastSeenAt:  time.Now().Unix(),
+	})
+	if err != nil {
+		service.Logger.Error("failed to quarantine message", "device_id", deviceID, "error", err)
+	}
+	return nil, nil
+}
+
+// previous is the device state before the message, as admitted
+func (service *Service) handleTelemetry(ctx context.Context, previous models.DeviceState, envelope models.MQTTEnvelope, isBatch bool) error {
+	deviceID := previous.DeviceID
 
 	if isBatch {
 		items, ok := envelope.Payload["items"].([]interface{})
@@ -99,7 +155,12 @@ func (service *Service) handleTelemetry(ctx context.Context, deviceID string, en
 				return err
 			}
 
-			return service.StateStore.UpdateFromTelemetry(ctx, latestReading)
+			if advanced, err := service.updateState(ctx, latestReading); err != nil || !advanced {
+				return err
+			}
+			service.checkThresholds(ctx, deviceID, envelope.Type, telemetryList)
+			service.resolveRecovered(ctx, previous, latestReading)
+			service.runAutomations(ctx, latestReading.DeviceID, latestReading.Type, latestReading.Payload, latestReading.Timestamp)
 		}
 		return nil
 	}
@@ -118,10 +179,50 @@ func (service *Service) handleTelemetry(ctx context.Context, deviceID string, en
 		return err
 	}
 
-	return service.StateStore.UpdateFromTelemetry(ctx, data)
+	if advanced, err := service.updateState(ctx, data); err != nil || !advanced {
+		return err
+	}
+	service.checkThresholds(ctx, deviceID, data.Type, []models.Telemetry{data})
+	service.resolveRecovered(ctx, previous, data)
+	service.runAutomations(ctx, data.DeviceID, data.Type, data.Payload, data.Timestamp)
+	return nil
 }
 
-func (service *Service) handleAlert(ctx context.Context, deviceID string, envelope models.MQTTEnvelope) error {
+// advances the device state to the reading and tells if it did. an out of order reading is already
+// saved and only leaves the state as it is, failing here would make IoT Core retry a message that was
+// stored. a reading that didn't advance the state (stale, or the device was decommissioned since admit)
+// goes no further: thresholds, recovery and automations only follow the latest state
+func (service *Service) updateState(ctx context.Context, reading models.Telemetry) (bool, error) {
+	err := service.StateStore.UpdateFromTelemetry(ctx, reading)
+	switch {
+	case errors.Is(err, devices.ErrStaleUpdate):
+		service.Logger.Info("telemetry saved, state not advanced", "device_id", reading.DeviceID, "timestamp", reading.Timestamp)
+		return false, nil
+	case errors.Is(err, devices.ErrDeviceDecommissioned):
+		service.Logger.Warn("telemetry rejected, device decommissioned", "device_id", reading.DeviceID)
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// same as updateState for messages without a reading, a refused heartbeat is not an error
+func (service *Service) updateHeartbeat(ctx context.Context, deviceID string) error {
+	err := service.StateStore.UpdateHeartbeat(ctx, deviceID)
+	switch {
+	case errors.Is(err, devices.ErrStaleUpdate):
+		return nil
+	case errors.Is(err, devices.ErrDeviceDecommissioned):
+		service.Logger.Warn("heartbeat rejected, device decommissioned", "device_id", deviceID)
+		return nil
+	}
+	return err
+}
+
+// previous is the device state before the alert, as admitted
+func (service *Service) handleAlert(ctx context.Context, previous models.DeviceState, envelope models.MQTTEnvelope) error {
+	deviceID := previous.DeviceID
 	severity, _ := envelope.Payload["severity"].(string)
 
 	alert := models.Alert{
@@ -132,11 +233,108 @@ func (service *Service) handleAlert(ctx context.Context, deviceID string, envelo
 		Payload:   envelope.Payload,
 	}
 
-	if err := service.AlertStore.SaveAlert(ctx, alert); err != nil {
+	_, err := alerts.RaiseAlert(ctx, service.AlertStore, alert)
+	if errors.Is(err, alerts.ErrDuplicateAlert) {
+		// a QoS 1 redelivery, the alert and its automations were handled the first time
+		service.Logger.Info("duplicate alert ignored", "device_id", deviceID, "timestamp", envelope.Timestamp)
+		return service.updateHeartbeat(ctx, deviceID)
+	}
+	if err != nil {
 		return err
 	}
 
-	return service.StateStore.UpdateHeartbeat(ctx, deviceID)
+	// alerts carry a reading too (gas level...), so rules react without waiting for the next telemetry.
+	// one older than the state is out of order, the rules already saw what came after it
+	if envelope.Timestamp >= previous.LastSeenAt {
+		service.runAutomations(ctx, deviceID, envelope.Type, envelope.Payload, envelope.Timestamp)
+	}
+
+	return service.updateHeartbeat(ctx, deviceID)
+}
+
+// raises alerts for readings over the device thresholds, failures are logged and never fail the ingestion
+func (service *Service) checkThresholds(ctx context.Context, deviceID string, deviceType string, readings []models.Telemetry) {
+	if service.Thresholds == nil {
+		return
+	}
+
+	if err := service.Thresholds.Check(ctx, deviceID, deviceType, readings); err != nil {
+		service.Logger.Error("failed to check alert thresholds", "device_id", deviceID, "error", err)
+	}
+}
+
+// a device going from an unhealthy state back to a healthy one (gas-sensor DANGER -> SAFE) resolves the
+// alerts it raised about an unhealthy state. devices that are always healthy never get here
+func (service *Service) resolveRecovered(ctx context.Context, previous models.DeviceState, reading models.Telemetry) {
+	if previous.Health == "" || previous.Health == "HEALTHY" {
+		return
+	}
+	if _, health := devices.ExtractState(reading.Type, reading.Payload); health != "HEALTHY" {
+		return
+	}
+
+	// a reading older than the alert doesn't mean the device recovered, and an alert the device
+	// rules can't read (UNKNOWN) is about something else than its state
+	clears := func(alert models.Alert) bool {
+		if alert.Timestamp > reading.Timestamp || alert.Type != reading.Type || !alerts.RaisedByDevice(alert) {
+			return false
+		}
+		opState, health := devices.ExtractState(alert.Type, alert.Payload)
+		return opState != "UNKNOWN" && health != "HEALTHY"
+	}
+
+	resolved, err := alerts.ResolveMatching(ctx, service.AlertStore, reading.DeviceID, reading.Timestamp, clears)
+	if err != nil {
+		service.Logger.Error("failed to resolve recovered alerts", "device_id", reading.DeviceID, "error", err)
+		return
+	}
+	if resolved > 0 {
+		service.Logger.Info("alerts auto-resolved", "device_id", reading.DeviceID, "count", resolved)
+	}
+}
+
+// evaluates the automation rules watching the device, failures are logged and never fail the ingestion
+func (service *Service) runAutomations(ctx context.Context, deviceID string, deviceType string, payload map[string]interface{}, at int64) {
+	if service.Automations == nil {
+		return
+	}
+
+	opState, _ := devices.ExtractState(deviceType, payload)
+	if opState == "UNKNOWN" {
+		return // a reading the device rules can't read leaves the debounce untouched
+	}
+	if err := service.Automations.Evaluate(ctx, deviceID, opState, at); err != nil {
+		service.Logger.Error("failed to evaluate automation rules", "device_id", deviceID, "error", err)
+	}
+}
+
+// records the device's ack/result for a command sent from the api
+func (service *Service) handleCommandAck(ctx context.Context, deviceID string, envelope models.MQTTEnvelope) error {
+	requestID, _ := envelope.Payload["request_id"].(string)
+	status, _ := envelope.Payload["status"].(string)
+	result, _ := envelope.Payload["result"].(map[string]interface{})
+	errMsg, _ := envelope.Payload["error"].(string)
+
+	update := commands.StatusUpdate{
+		Status: status,
+		At:     envelope.Timestamp,
+		Result: result,
+		Error:  errMsg,
+	}
+
+	err := service.CommandStore.UpdateCommandStatus(ctx, requestID, deviceID, update)
+	switch {
+	case errors.Is(err, commands.ErrCommandNotFound), errors.Is(err, commands.ErrInvalidTransition):
+		// duplicate or late acks (QoS 1) must not make the lambda retry
+		service.Logger.Warn("ignoring command ack", "device_id", deviceID, "request_id", requestID, "status", status, "error", err)
+	case err != nil:
+		service.Logger.Error("failed to update command status", "device_id", deviceID, "request_id", requestID, "error", err)
+		return err
+	default:
+		service.Logger.Info("command status updated", "device_id", deviceID, "request_id", requestID, "status", status)
+	}
+
+	return service.updateHeartbeat(ctx, deviceID)
 }
 
 func (service *Service) logValidationError(err error, deviceID string) {
@@ -0,0 +1,264 @@
+package ingestion
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type recordingQuarantine struct {
+	entries []models.QuarantinedDevice
+}
+
+func (quarantine *recordingQuarantine) QuarantineMessage(ctx context.Context, entry models.QuarantinedDevice) error {
+	quarantine.entries = append(quarantine.entries, entry)
+	return nil
+}
+
+func (quarantine *recordingQuarantine) ReleaseDevice(ctx context.Context, deviceID string) error {
+	return nil
+}
+
+type testService struct {
+	*Service
+	telemetry  *telemetry.MemoryTelemetryStore
+	alerts     *alerts.MemoryAlertStore
+	states     *devices.MemoryStateStore
+	thresholds *alerts.MemoryThresholdStore
+	quarantine *recordingQuarantine
+}
+
+// gas-1 is a registered gas sensor, last seen in DANGER at lastSeen with an open alert it raised
+func newTestService(t *testing.T, lastSeen int64) *testService {
+	t.Helper()
+	ctx := context.Background()
+	service := &testService{
+		telemetry:  telemetry.NewMemoryTelemetryStore(),
+		alerts:     alerts.NewMemoryAlertStore(),
+		states:     devices.NewMemoryStateStore(),
+		thresholds: alerts.NewMemoryThresholdStore(),
+		quarantine: &recordingQuarantine{},
+	}
+	service.Service = &Service{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TelemetryStore: service.telemetry,
+		AlertStore:     service.alerts,
+		StateStore:     service.states,
+		CommandStore:   commands.NewMemoryCommandStore(),
+		Quarantine:     service.quarantine,
+	}
+	service.Thresholds = &alerts.Monitor{Logger: service.Logger, Thresholds: service.thresholds, Alerts: service.alerts}
+
+	if err := service.states.RegisterDevice(ctx, models.DeviceState{DeviceID: "gas-1", Type: "gas-sensor", Status: devices.StatusProvisioned, RegisteredAt: lastSeen - 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.states.UpdateFromTelemetry(ctx, gasReading(lastSeen, true)); err != nil {
+		t.Fatal(err)
+	}
+	alert := models.Alert{DeviceID: "gas-1", Timestamp: lastSeen, Type: "gas-sensor", Severity: "CRITICAL",
+		Payload: map[string]interface{}{"severity": "CRITICAL", "status": "DANGER", "alarm_on": true}}
+	if err := service.alerts.SaveAlert(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	threshold := models.Threshold{DeviceID: "gas-1", ThresholdID: "gas-high", Metric: "gas_level", Operator: alerts.OperatorAbove, Value: 500, ForSeconds: 600, Severity: "CRITICAL", Enabled: true, BreachSince: lastSeen}
+	if err := service.thresholds.SaveThreshold(ctx, threshold); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func gasReading(ts int64, alarm bool) models.Telemetry {
+	level := 100.0
+	if alarm {
+		level = 900
+	}
+	return models.Telemetry{DeviceID: "gas-1", Timestamp: ts, Type: "gas-sensor", Payload: map[string]interface{}{"gas_level": level, "alarm_on": alarm}}
+}
+
+func message(deviceID string, deviceType string, messageType string, ts int64, payload map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"topic": "devices/" + deviceID + "/" + messageType,
+		"payload": map[string]interface{}{
+			"device_id": deviceID,
+			"timestamp": ts,
+			"type":      deviceType,
+			"payload":   payload,
+		},
+	}
+}
+
+func (service *testService) state(t *testing.T) models.DeviceState {
+	t.Helper()
+	state, err := service.states.GetStateByID(context.Background(), "gas-1")
+	if err != nil || state == nil {
+		t.Fatalf("state of gas-1: %v, %v", state, err)
+	}
+	return *state
+}
+
+func (service *testService) openAlerts(t *testing.T) int {
+	t.Helper()
+	open, err := service.alerts.GetAlertsByDeviceStatus(context.Background(), "gas-1", []string{alerts.StatusOpen}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(open)
+}
+
+func (service *testService) breachSince(t *testing.T) int64 {
+	t.Helper()
+	threshold, err := service.thresholds.GetThreshold(context.Background(), "gas-1", "gas-high")
+	if err != nil || threshold == nil {
+		t.Fatalf("threshold: %v, %v", threshold, err)
+	}
+	return threshold.BreachSince
+}
+
+// an out of order SAFE reading is stored but changes nothing else
+func TestStaleReading(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	service := newTestService(t, now-60)
+
+	err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now-120, map[string]interface{}{"gas_level": 100.0, "alarm_on": false}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := service.telemetry.GetTelemetryHistory(ctx, "gas-1", 0, 0)
+	if len(history) != 1 {
+		t.Errorf("stored %d readings, want the stale one stored", len(history))
+	}
+	if state := service.state(t); state.LastSeenAt != now-60 || state.OperationalState != "DANGER" {
+		t.Errorf("state = %s at %d, want DANGER at %d", state.OperationalState, state.LastSeenAt, now-60)
+	}
+	if open := service.openAlerts(t); open != 1 {
+		t.Errorf("%d open alerts, a stale SAFE must not resolve the alert", open)
+	}
+	if since := service.breachSince(t); since != now-60 {
+		t.Errorf("breach since %d, a stale reading must not end the breach", since)
+	}
+}
+
+// the next SAFE reading is the recovery: the alert is resolved and the breach is over
+func TestRecoveryReading(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	service := newTestService(t, now-60)
+
+	err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now, map[string]interface{}{"gas_level": 100.0, "alarm_on": false}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := service.state(t); state.LastSeenAt != now || state.Health != "HEALTHY" {
+		t.Errorf("state = %s at %d, want HEALTHY at %d", state.Health, state.LastSeenAt, now)
+	}
+	if open := service.openAlerts(t); open != 0 {
+		t.Errorf("%d open alerts, want the DANGER alert resolved", open)
+	}
+	if since := service.breachSince(t); since != 0 {
+		t.Errorf("breach since %d, want it over", since)
+	}
+
+	// still healthy: not a recovery, nothing changes
+	if err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now+1, map[string]interface{}{"gas_level": 90.0, "alarm_on": false})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecommissionedDevice(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	service := newTestService(t, now-60)
+	if err := service.states.DecommissionDevice(ctx, "gas-1", now-30); err != nil {
+		t.Fatal(err)
+	}
+
+	err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now, map[string]interface{}{"gas_level": 100.0, "alarm_on": false}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := service.telemetry.GetTelemetryHistory(ctx, "gas-1", 0, 0)
+	if len(history) != 0 {
+		t.Errorf("stored %d readings, want the message dropped", len(history))
+	}
+	if len(service.quarantine.entries) != 1 || service.quarantine.entries[0].Reason != devices.QuarantineDecommissioned {
+		t.Errorf("quarantined %+v, want the message of the decommissioned device", service.quarantine.entries)
+	}
+	if state := service.state(t); state.Status != devices.StatusDecommissioned || state.LastSeenAt != now-60 {
+		t.Errorf("state = %s at %d, want it left decommissioned", state.Status, state.LastSeenAt)
+	}
+}
+
+// a batch out of order: every reading is stored, the state takes the latest one
+func TestBatchReadings(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	service := newTestService(t, now-60)
+
+	items := []interface{}{
+		map[string]interface{}{"ts": float64(now - 10), "gas_level": 950.0, "alarm_on": true},
+		map[string]interface{}{"ts": float64(now - 5), "gas_level": 100.0, "alarm_on": false},
+		map[string]interface{}{"ts": float64(now - 20), "gas_level": 900.0, "alarm_on": true},
+		map[string]interface{}{"broken": true},
+	}
+	if err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now, map[string]interface{}{"items": items})); err != nil {
+		t.Fatal(err)
+	}
+
+	history, _ := service.telemetry.GetTelemetryHistory(ctx, "gas-1", 0, 0)
+	if len(history) != 3 {
+		t.Errorf("stored %d readings, want the 3 valid ones", len(history))
+	}
+	if state := service.state(t); state.LastSeenAt != now-5 || state.OperationalState != "SAFE" {
+		t.Errorf("state = %s at %d, want SAFE at %d", state.OperationalState, state.LastSeenAt, now-5)
+	}
+	if open := service.openAlerts(t); open != 0 {
+		t.Errorf("%d open alerts, the batch ends SAFE", open)
+	}
+
+	// a whole batch older than the state leaves it alone
+	old := []interface{}{map[string]interface{}{"ts": float64(now - 300), "gas_level": 950.0, "alarm_on": true}}
+	if err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "telemetry", now, map[string]interface{}{"items": old})); err != nil {
+		t.Fatal(err)
+	}
+	if state := service.state(t); state.LastSeenAt != now-5 || state.OperationalState != "SAFE" {
+		t.Errorf("state = %s at %d after a stale batch, want SAFE at %d", state.OperationalState, state.LastSeenAt, now-5)
+	}
+	if since := service.breachSince(t); since != 0 {
+		t.Errorf("breach since %d, a stale batch must not start a breach", since)
+	}
+}
+
+// a redelivered alert is ignored, it doesn't reopen the alert resolved in the meantime
+func TestRedeliveredAlert(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	service := newTestService(t, now-60)
+
+	payload := map[string]interface{}{"severity": "CRITICAL", "gas_level": 950.0, "alarm_on": true}
+	if err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "alerts", now, payload)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.alerts.ResolveAlert(ctx, "gas-1", now, "user-1", now+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.HandleRequest(ctx, message("gas-1", "gas-sensor", "alerts", now, payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := service.alerts.GetAlertsByDevice(ctx, "gas-1", 0)
+	if len(all) != 2 || all[0].Status != alerts.StatusResolved {
+		t.Errorf("alerts = %+v, want the redelivered alert still resolved", all)
+	}
+}
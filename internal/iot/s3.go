@@ -0,0 +1,188 @@
+package iot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var ErrChartNotFound = errors.New("chart not found")
+
+// S3Client reads and writes the charts of the rollup job (cmd/chart-rollup) under
+// processed-charts/{device}/, a month per {YYYY-MM}.json and a year per {YYYY}.json
+type S3Client struct {
+	Client *s3.Client
+	Bucket string
+}
+
+// NewS3Client reads the bucket from CHARTS_BUCKET. CHARTS_S3_ENDPOINT points it at an
+// s3 compatible stand-in (minio, localstack) instead of aws, addressed path-style
+func NewS3Client(cfg aws.Config) (*S3Client, error) {
+	bucket := os.Getenv("CHARTS_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("CHARTS_BUCKET environment variable is not set")
+	}
+
+	endpoint := os.Getenv("CHARTS_S3_ENDPOINT")
+	client := s3.NewFromConfig(cfg, func(options *s3.Options) {
+		if endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+			options.UsePathStyle = true
+		}
+	})
+
+	return &S3Client{
+		Client: client,
+		Bucket: bucket,
+	}, nil
+}
+
+// ChartKey is where the chart of a device for the month holding the given time lives
+func ChartKey(deviceID string, month time.Time) string {
+	return fmt.Sprintf("processed-charts/%s/%s.json", deviceID, month.Format("2006-01"))
+}
+
+func YearChartKey(deviceID string, year time.Time) string {
+	return fmt.Sprintf("processed-charts/%s/%s.json", deviceID, year.Format("2006"))
+}
+
+// DayRollupKey is where the rollup job keeps a finished day, the month and year charts are rebuilt from these
+func DayRollupKey(deviceID string, day time.Time) string {
+	return fmt.Sprintf("processed-charts/%s/days/%s.json", deviceID, day.Format("2006-01-02"))
+}
+
+// GetJSON decodes one object into value, ErrChartNotFound when there is none
+func (client *S3Client) GetJSON(ctx context.Context, key string, value interface{}) error {
+	body, err := client.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return nil
+}
+
+// PutJSON replaces the object, writing the same value twice is harmless
+func (client *S3Client) PutJSON(ctx context.Context, key string, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+
+	_, err = client.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(client.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (client *S3Client) get(ctx context.Context, key string) ([]byte, error) {
+	output, err := client.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(client.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrChartNotFound
+		}
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(output.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return body, nil
+}
+
+// GetMonthlyChart downloads the points of one metric, ErrChartNotFound when the month wasn't processed.
+// charts written before the rollup job are a bare array of one metric, returned whatever the metric
+func (client *S3Client) GetMonthlyChart(ctx context.Context, key string, metric string) ([]telemetry.ChartPoint, error) {
+	body, err := client.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var points []telemetry.ChartPoint
+		if err := json.Unmarshal(trimmed, &points); err != nil {
+			return nil, fmt.Errorf("failed to parse chart %s: %w", key, err)
+		}
+		return points, nil
+	}
+
+	var chart telemetry.MonthChart
+	if err := json.Unmarshal(body, &chart); err != nil {
+		return nil, fmt.Errorf("failed to parse chart %s: %w", key, err)
+	}
+	return chart.Metrics[metric], nil
+}
+
+// GetCharts joins the charts of the given months in order, the months with no chart
+// are returned apart so a gap in the processing doesn't fail the whole range
+func (client *S3Client) GetCharts(ctx context.Context, deviceID string, metric string, months []time.Time) ([]telemetry.ChartPoint, []string, error) {
+	points := []telemetry.ChartPoint{}
+	missing := []string{}
+
+	for _, month := range months {
+		chart, err := client.GetMonthlyChart(ctx, ChartKey(deviceID, month), metric)
+		if errors.Is(err, ErrChartNotFound) {
+			missing = append(missing, month.Format("2006-01"))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		points = append(points, chart...)
+	}
+	return points, missing, nil
+}
+
+// GetYearlyCharts is GetCharts with one point per month, read from the year charts
+func (client *S3Client) GetYearlyCharts(ctx context.Context, deviceID string, metric string, months []time.Time) ([]telemetry.ChartPoint, []string, error) {
+	points := []telemetry.ChartPoint{}
+	missing := []string{}
+	years := make(map[int]map[string]telemetry.ChartPoint)
+
+	for _, month := range months {
+		byLabel, loaded := years[month.Year()]
+		if !loaded {
+			var chart telemetry.YearChart
+			err := client.GetJSON(ctx, YearChartKey(deviceID, month), &chart)
+			if err != nil && !errors.Is(err, ErrChartNotFound) {
+				return nil, nil, err
+			}
+			byLabel = make(map[string]telemetry.ChartPoint)
+			for _, point := range chart.Metrics[metric] {
+				byLabel[point.Label] = point
+			}
+			years[month.Year()] = byLabel
+		}
+
+		point, exists := byLabel[telemetry.MonthLabel(month)]
+		if !exists {
+			missing = append(missing, month.Format("2006-01"))
+			continue
+		}
+		points = append(points, point)
+	}
+	return points, missing, nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+)
+
+// HomeAudience sends a device's alerts to the members of the home it is in, guests
+// whose access expired are left out. a device in no home has no audience
+type HomeAudience struct {
+	Homes homes.HomeStore
+}
+
+func (audience *HomeAudience) UsersForDevice(ctx context.Context, deviceID string) ([]string, error) {
+	device, err := audience.Homes.GetDeviceHome(ctx, deviceID)
+	if err != nil || device == nil {
+		return nil, err
+	}
+
+	members, err := audience.Homes.GetMembers(ctx, device.HomeID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	userIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		if homes.Active(member, now) {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// SentMessage is one delivery recorded by FakeProvider
+type SentMessage struct {
+	UserID  string
+	Token   string
+	Message Message
+}
+
+// FakeProvider records messages instead of sending them, used for tests and local demos.
+// Errors maps a token to the error its sends return
+type FakeProvider struct {
+	mu     sync.Mutex
+	sent   []SentMessage
+	Errors map[string]error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		Errors: make(map[string]error),
+	}
+}
+
+func (provider *FakeProvider) Send(ctx context.Context, userID string, token string, msg Message) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if err := provider.Errors[token]; err != nil {
+		return err
+	}
+	provider.sent = append(provider.sent, SentMessage{UserID: userID, Token: token, Message: msg})
+	return nil
+}
+
+func (provider *FakeProvider) Sent() []SentMessage {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	return append([]SentMessage(nil), provider.sent...)
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const defaultFCMEndpoint = "https://fcm.googleapis.com/fcm/send"
+
+// FCMProvider sends through the FCM HTTP API (server key auth)
+type FCMProvider struct {
+	Client    *http.Client
+	Endpoint  string
+	ServerKey string
+}
+
+// returns nil, nil when FCM_SERVER_KEY is not set, push is then disabled
+func NewFCMProvider() (*FCMProvider, error) {
+	serverKey := os.Getenv("FCM_SERVER_KEY")
+	if serverKey == "" {
+		return nil, nil
+	}
+
+	endpoint := os.Getenv("FCM_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultFCMEndpoint
+	}
+
+	return &FCMProvider{
+		Client:    &http.Client{Timeout: 5 * time.Second},
+		Endpoint:  endpoint,
+		ServerKey: serverKey,
+	}, nil
+}
+
+type fcmRequest struct {
+	To           string            `json:"to"`
+	Priority     string            `json:"priority"`
+	Notification Message           `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmResponse struct {
+	Failure int `json:"failure"`
+	Results []struct {
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+func (provider *FCMProvider) Send(ctx context.Context, userID string, token string, msg Message) error {
+	body, err := json.Marshal(fcmRequest{
+		To:           token,
+		Priority:     "high",
+		Notification: Message{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal fcm message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build fcm request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+provider.ServerKey)
+
+	res, err := provider.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus("fcm", res.StatusCode); err != nil {
+		return err
+	}
+
+	var result fcmResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode fcm response: %w", err)
+	}
+	if result.Failure == 0 || len(result.Results) == 0 {
+		return nil
+	}
+
+	switch result.Results[0].Error {
+	case "NotRegistered", "InvalidRegistration", "MismatchSenderId":
+		return fmt.Errorf("%w: fcm %s", ErrInvalidToken, result.Results[0].Error)
+	case "Unavailable", "InternalServerError", "DeviceMessageRateExceeded":
+		return fmt.Errorf("fcm temporary failure: %s", result.Results[0].Error)
+	default:
+		return fmt.Errorf("%w: fcm %s", ErrRejected, result.Results[0].Error)
+	}
+}
+
+// 5xx and 429 are worth retrying, other 4xx are not
+func checkStatus(provider string, status int) error {
+	switch {
+	case status >= 200 && status < 300:
+		return nil
+	case status == http.StatusTooManyRequests || status >= 500:
+		return fmt.Errorf("%s returned status %d", provider, status)
+	default:
+		return fmt.Errorf("%w: %s returned status %d", ErrRejected, provider, status)
+	}
+}
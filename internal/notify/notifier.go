@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	maxSendAttempts = 3
+	baseRetryDelay  = 200 * time.Millisecond
+)
+
+// Audience tells which users should hear about a device's alerts
+type Audience interface {
+	UsersForDevice(ctx context.Context, deviceID string) ([]string, error)
+}
+
+// Notifier pushes alerts to the registered tokens of the users that care about them
+type Notifier struct {
+	Logger      *slog.Logger
+	Tokens      TokenStore
+	Preferences PreferenceStore
+	Providers   map[string]Provider // keyed by PushToken.Provider
+	Audience    Audience            // members of the device's home (HomeAudience)
+}
+
+// sends the alert to every token allowed by its owner's preferences,
+// a failing user or token never stops the others
+func (notifier *Notifier) NotifyAlert(ctx context.Context, alert models.Alert, now time.Time) error {
+	// events saved already resolved (device recovered...) are history, not something to act on
+	if alerts.StatusOf(alert) == alerts.StatusResolved {
+		return nil
+	}
+
+	tokensByUser, err := notifier.recipients(ctx, alert.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	msg := alertMessage(alert)
+	for userID, tokenList := range tokensByUser {
+		preferences, err := notifier.Preferences.GetPreferences(ctx, userID)
+		if err != nil {
+			notifier.Logger.Error("failed to load notification preferences", "user_id", userID, "error", err)
+			continue
+		}
+		if preferences == nil {
+			defaults := DefaultPreferences(userID)
+			preferences = &defaults
+		}
+		if !Allows(*preferences, alert.Severity, now) {
+			continue
+		}
+
+		for _, token := range tokenList {
+			notifier.deliver(ctx, token, msg)
+		}
+	}
+	return nil
+}
+
+func (notifier *Notifier) recipients(ctx context.Context, deviceID string) (map[string][]models.PushToken, error) {
+	tokensByUser := make(map[string][]models.PushToken)
+
+	userIDs, err := notifier.Audience.UsersForDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		tokenList, err := notifier.Tokens.GetTokens(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokenList) > 0 {
+			tokensByUser[userID] = tokenList
+		}
+	}
+	return tokensByUser, nil
+}
+
+// sends with exponential backoff, tokens the provider no longer knows are dropped from the registry
+func (notifier *Notifier) deliver(ctx context.Context, token models.PushToken, msg Message) {
+	provider, exists := notifier.Providers[token.Provider]
+	if !exists {
+		notifier.Logger.Warn("no provider configured for push token", "user_id", token.UserID, "provider", token.Provider)
+		return
+	}
+
+	err := sendWithRetry(ctx, provider, token, msg)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInvalidToken):
+		notifier.Logger.Info("removing invalid push token", "user_id", token.UserID, "provider", token.Provider)
+		if err := notifier.Tokens.DeleteToken(ctx, token.UserID, token.Token); err != nil {
+			notifier.Logger.Error("failed to remove invalid push token", "user_id", token.UserID, "error", err)
+		}
+	default:
+		notifier.Logger.Error("failed to send notification", "user_id", token.UserID, "provider", token.Provider, "error", err)
+	}
+}
+
+func sendWithRetry(ctx context.Context, provider Provider, token models.PushToken, msg Message) error {
+	var err error
+	for attempt := 0; attempt < maxSendAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<(attempt-1)) * baseRetryDelay
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		err = provider.Send(ctx, token.UserID, token.Token, msg)
+		if err == nil || errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrRejected) {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", maxSendAttempts, err)
+}
+
+func alertMessage(alert models.Alert) Message {
+	title := fmt.Sprintf("%s alert: %s", alert.Severity, alert.DeviceID)
+	body := fmt.Sprintf("%s reported %s", alert.DeviceID, alert.Type)
+	if status, ok := alert.Payload["status"].(string); ok && status != "" {
+		body = fmt.Sprintf("%s reported %s (%s)", alert.DeviceID, alert.Type, status)
+	}
+
+	return Message{
+		Title: title,
+		Body:  body,
+		Data: map[string]string{
+			"device_id": alert.DeviceID,
+			"timestamp": fmt.Sprint(alert.Timestamp),
+			"type":      alert.Type,
+			"severity":  alert.Severity,
+		},
+	}
+}
+
+// NotifyingAlertStore notifies users of every alert it saves, so all the alert paths
+// (device alerts, thresholds, offline sweeps) share one trigger
+type NotifyingAlertStore struct {
+	alerts.AlertStore
+	Notifier *Notifier
+}
+
+// a failed notification is logged, the alert itself is already stored
+func (store *NotifyingAlertStore) SaveAlert(ctx context.Context, alert models.Alert) error {
+	if err := store.AlertStore.SaveAlert(ctx, alert); err != nil {
+		return err
+	}
+	if err := store.Notifier.NotifyAlert(ctx, alert, time.Now()); err != nil {
+		store.Notifier.Logger.Error("failed to notify alert", "device_id", alert.DeviceID, "error", err)
+	}
+	return nil
+}
+
+// providers enabled by the environment, none means tokens are kept but nothing is sent
+func NewProvidersFromEnv() (map[string]Provider, error) {
+	providers := make(map[string]Provider)
+
+	fcm, err := NewFCMProvider()
+	if err != nil {
+		return nil, err
+	}
+	if fcm != nil {
+		providers[ProviderFCM] = fcm
+	}
+
+	webhook, err := NewWebhookProvider()
+	if err != nil {
+		return nil, err
+	}
+	if webhook != nil {
+		providers[ProviderWebhook] = webhook
+	}
+	return providers, nil
+}
+
+// notifier backed by the dynamodb registries and the providers enabled by the environment,
+// alerts reach the members of the device's home
+func NewNotifier(logger *slog.Logger) (*Notifier, error) {
+	tokenStore, err := NewTokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	homeStore, err := homes.NewHomeStore()
+	if err != nil {
+		return nil, err
+	}
+
+	preferenceStore, err := NewPreferenceStore()
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := NewProvidersFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		Logger:      logger,
+		Tokens:      tokenStore,
+		Preferences: preferenceStore,
+		Providers:   providers,
+		Audience:    &HomeAudience{Homes: homeStore},
+	}, nil
+}
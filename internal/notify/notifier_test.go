@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// gas-1 is in home-1 with an owner, a member and an expired guest, user-4 lives in home-2
+func newTestNotifier(t *testing.T) (*Notifier, *FakeProvider) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	homeStore := homes.NewMemoryHomeStore()
+	if _, err := homeStore.ClaimDevice(ctx, models.HomeDevice{DeviceID: "gas-1", HomeID: "home-1"}); err != nil {
+		t.Fatal(err)
+	}
+	members := []models.HomeMember{
+		{UserID: "user-1", HomeID: "home-1", Role: homes.RoleOwner},
+		{UserID: "user-2", HomeID: "home-1", Role: homes.RoleMember},
+		{UserID: "user-3", HomeID: "home-1", Role: homes.RoleGuest, ExpiresAt: now - 60},
+		{UserID: "user-4", HomeID: "home-2", Role: homes.RoleOwner},
+	}
+	tokens := NewMemoryTokenStore()
+	for _, member := range members {
+		if err := homeStore.SaveMember(ctx, member); err != nil {
+			t.Fatal(err)
+		}
+		if err := tokens.SaveToken(ctx, models.PushToken{UserID: member.UserID, Token: "token-" + member.UserID, Provider: ProviderWebhook}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provider := NewFakeProvider()
+	return &Notifier{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Tokens:      tokens,
+		Preferences: NewMemoryPreferenceStore(),
+		Providers:   map[string]Provider{ProviderWebhook: provider},
+		Audience:    &HomeAudience{Homes: homeStore},
+	}, provider
+}
+
+func TestNotifyAlertReachesHomeMembersOnly(t *testing.T) {
+	notifier, provider := newTestNotifier(t)
+	alert := models.Alert{DeviceID: "gas-1", Timestamp: 1000, Type: "GAS_LEAK", Severity: "CRITICAL"}
+	if err := notifier.NotifyAlert(context.Background(), alert, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []string
+	for _, sent := range provider.Sent() {
+		users = append(users, sent.UserID)
+	}
+	slices.Sort(users)
+	if !slices.Equal(users, []string{"user-1", "user-2"}) {
+		t.Errorf("notified %v, want the active members of home-1 only", users)
+	}
+}
+
+func TestNotifyAlertDeviceWithoutHome(t *testing.T) {
+	notifier, provider := newTestNotifier(t)
+	alert := models.Alert{DeviceID: "stray-1", Timestamp: 1000, Type: "GAS_LEAK", Severity: "CRITICAL"}
+	if err := notifier.NotifyAlert(context.Background(), alert, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := provider.Sent(); len(sent) != 0 {
+		t.Errorf("notified %v, want nobody for a device in no home", sent)
+	}
+}
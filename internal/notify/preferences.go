@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const DefaultMinSeverity = "CRITICAL"
+
+var (
+	ErrInvalidPreferences = errors.New("invalid notification preferences")
+
+	// ordered from least to most severe
+	severities = []string{"LOW", "MEDIUM", "CRITICAL"}
+)
+
+// preferences of a user who never saved any: critical alerts only, no quiet hours
+func DefaultPreferences(userID string) models.NotificationPreferences {
+	return models.NotificationPreferences{
+		UserID:      userID,
+		MinSeverity: DefaultMinSeverity,
+	}
+}
+
+// checks the preferences sent by the app and fills the defaults
+func PreparePreferences(preferences *models.NotificationPreferences) error {
+	if preferences.MinSeverity == "" {
+		preferences.MinSeverity = DefaultMinSeverity
+	}
+	if !slices.Contains(severities, preferences.MinSeverity) {
+		return fmt.Errorf("%w: min_severity must be LOW, MEDIUM or CRITICAL", ErrInvalidPreferences)
+	}
+
+	quietHours := preferences.QuietHours
+	if quietHours == nil {
+		return nil
+	}
+	if _, err := parseClock(quietHours.Start); err != nil {
+		return fmt.Errorf("%w: quiet_hours.start %v", ErrInvalidPreferences, err)
+	}
+	if _, err := parseClock(quietHours.End); err != nil {
+		return fmt.Errorf("%w: quiet_hours.end %v", ErrInvalidPreferences, err)
+	}
+	if quietHours.TimeZone == "" {
+		quietHours.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(quietHours.TimeZone); err != nil {
+		return fmt.Errorf("%w: unknown time_zone %q", ErrInvalidPreferences, quietHours.TimeZone)
+	}
+	return nil
+}
+
+// whether an alert of this severity should reach the user at now
+func Allows(preferences models.NotificationPreferences, severity string, now time.Time) bool {
+	if slices.Index(severities, severity) < slices.Index(severities, preferences.MinSeverity) {
+		return false
+	}
+
+	quietHours := preferences.QuietHours
+	if quietHours == nil || (severity == "CRITICAL" && !quietHours.MuteCritical) {
+		return true
+	}
+	return !inQuietHours(*quietHours, now)
+}
+
+func inQuietHours(quietHours models.QuietHours, now time.Time) bool {
+	start, err := parseClock(quietHours.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(quietHours.End)
+	if err != nil {
+		return false
+	}
+	location, err := time.LoadLocation(quietHours.TimeZone)
+	if err != nil {
+		// validated when saved, a binary without tzdata lands here
+		slog.Error("failed to load quiet hours time zone, using UTC", "time_zone", quietHours.TimeZone, "error", err)
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	// window wraps midnight, e.g. 22:00 -> 07:00
+	return minute >= start || minute < end
+}
+
+// "HH:MM" to minutes since midnight
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("must be HH:MM")
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	ProviderFCM     = "fcm"
+	ProviderWebhook = "webhook"
+)
+
+var (
+	// ErrInvalidToken means the provider will never accept this token again, it is removed from the registry
+	ErrInvalidToken = errors.New("push token is no longer valid")
+	// ErrRejected means the provider refused the message itself, retrying won't help
+	ErrRejected = errors.New("notification rejected by provider")
+)
+
+// Message is what a user sees on the phone, Data is passed to the app untouched
+type Message struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+// Provider delivers a message to one registered token.
+// any error other than ErrInvalidToken / ErrRejected is treated as temporary and retried
+type Provider interface {
+	Send(ctx context.Context, userID string, token string, msg Message) error
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type tokenKeyPair struct {
+	UserID string
+	Token  string
+}
+
+// MemoryTokenStore is an in-process TokenStore used for tests and local demos
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[tokenKeyPair]models.PushToken
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[tokenKeyPair]models.PushToken),
+	}
+}
+
+func (store *MemoryTokenStore) SaveToken(ctx context.Context, token models.PushToken) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.tokens[tokenKeyPair{token.UserID, token.Token}] = token
+	return nil
+}
+
+func (store *MemoryTokenStore) DeleteToken(ctx context.Context, userID string, token string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.tokens, tokenKeyPair{userID, token})
+	return nil
+}
+
+func (store *MemoryTokenStore) GetTokens(ctx context.Context, userID string) ([]models.PushToken, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	tokenList := make([]models.PushToken, 0)
+	for key, token := range store.tokens {
+		if key.UserID == userID {
+			tokenList = append(tokenList, token)
+		}
+	}
+	sortTokens(tokenList)
+	return tokenList, nil
+}
+
+func sortTokens(tokenList []models.PushToken) {
+	slices.SortFunc(tokenList, func(a, b models.PushToken) int {
+		return cmp.Or(cmp.Compare(a.UserID, b.UserID), cmp.Compare(a.Token, b.Token))
+	})
+}
+
+// MemoryPreferenceStore is an in-process PreferenceStore used for tests and local demos
+type MemoryPreferenceStore struct {
+	mu          sync.Mutex
+	preferences map[string]models.NotificationPreferences
+}
+
+func NewMemoryPreferenceStore() *MemoryPreferenceStore {
+	return &MemoryPreferenceStore{
+		preferences: make(map[string]models.NotificationPreferences),
+	}
+}
+
+func (store *MemoryPreferenceStore) GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	preferences, exists := store.preferences[userID]
+	if !exists {
+		return nil, nil
+	}
+	if preferences.QuietHours != nil {
+		quietHours := *preferences.QuietHours
+		preferences.QuietHours = &quietHours
+	}
+	return &preferences, nil
+}
+
+func (store *MemoryPreferenceStore) SavePreferences(ctx context.Context, preferences models.NotificationPreferences) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if preferences.QuietHours != nil {
+		quietHours := *preferences.QuietHours
+		preferences.QuietHours = &quietHours
+	}
+	store.preferences[preferences.UserID] = preferences
+	return nil
+}
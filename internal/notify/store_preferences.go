@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// PreferenceStore keeps one NotificationPreferences item per user
+type PreferenceStore interface {
+	GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error)
+	SavePreferences(ctx context.Context, preferences models.NotificationPreferences) error
+}
+
+type DynamoPreferenceStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewPreferenceStore() (*DynamoPreferenceStore, error) {
+	tableName := os.Getenv("DYNAMODB_NOTIFICATION_PREFERENCES_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_NOTIFICATION_PREFERENCES_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoPreferenceStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+// returns nil, nil when the user never saved preferences
+func (store *DynamoPreferenceStore) GetPreferences(ctx context.Context, userID string) (*models.NotificationPreferences, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences of user %s: %w", userID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var preferences models.NotificationPreferences
+	if err = attributevalue.UnmarshalMap(result.Item, &preferences); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notification preferences of user %s: %w", userID, err)
+	}
+
+	return &preferences, nil
+}
+
+func (store *DynamoPreferenceStore) SavePreferences(ctx context.Context, preferences models.NotificationPreferences) error {
+	item, err := attributevalue.MarshalMap(preferences)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification preferences: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store notification preferences in dynamodb: %w", err)
+	}
+
+	return nil
+}
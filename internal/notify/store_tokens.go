@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// TokenStore is the registry of push tokens (user_id + token)
+type TokenStore interface {
+	SaveToken(ctx context.Context, token models.PushToken) error
+	DeleteToken(ctx context.Context, userID string, token string) error
+	GetTokens(ctx context.Context, userID string) ([]models.PushToken, error)
+}
+
+type DynamoTokenStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewTokenStore() (*DynamoTokenStore, error) {
+	tableName := os.Getenv("DYNAMODB_PUSH_TOKENS_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_PUSH_TOKENS_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoTokenStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+func (store *DynamoTokenStore) SaveToken(ctx context.Context, token models.PushToken) error {
+	item, err := attributevalue.MarshalMap(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal push token: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store push token in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoTokenStore) DeleteToken(ctx context.Context, userID string, token string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+			"token":   &types.AttributeValueMemberS{Value: token},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete push token of user %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (store *DynamoTokenStore) GetTokens(ctx context.Context, userID string) ([]models.PushToken, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		KeyConditionExpression: aws.String("user_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: userID},
+		},
+	}
+
+	tokenList := []models.PushToken{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query push tokens of user %s: %w", userID, err)
+		}
+
+		var items []models.PushToken
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal push tokens of user %s: %w", userID, err)
+		}
+		tokenList = append(tokenList, items...)
+	}
+
+	return tokenList, nil
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const signatureHeader = "X-Fleexa-Signature"
+
+// WebhookProvider posts every notification to one configured URL, the token tells the
+// receiving service which of its channels (chat room, ntfy topic...) the user picked
+type WebhookProvider struct {
+	Client *http.Client
+	URL    string
+	Secret string // optional, signs the body with HMAC-SHA256
+}
+
+// returns nil, nil when NOTIFY_WEBHOOK_URL is not set, webhooks are then disabled
+func NewWebhookProvider() (*WebhookProvider, error) {
+	url := os.Getenv("NOTIFY_WEBHOOK_URL")
+	if url == "" {
+		return nil, nil
+	}
+
+	return &WebhookProvider{
+		Client: &http.Client{Timeout: 5 * time.Second},
+		URL:    url,
+		Secret: os.Getenv("NOTIFY_WEBHOOK_SECRET"),
+	}, nil
+}
+
+type webhookPayload struct {
+	UserID string `json:"user_id"`
+	Token  string `json:"token"`
+	Message
+	SentAt int64 `json:"sent_at"`
+}
+
+func (provider *WebhookProvider) Send(ctx context.Context, userID string, token string, msg Message) error {
+	body, err := json.Marshal(webhookPayload{
+		UserID:  userID,
+		Token:   token,
+		Message: msg,
+		SentAt:  time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if provider.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(provider.Secret))
+		mac.Write(body)
+		req.Header.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := provider.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusGone {
+		return fmt.Errorf("%w: webhook channel is gone", ErrInvalidToken)
+	}
+	return checkStatus("webhook", res.StatusCode)
+}
@@ -0,0 +1,145 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// alert payload statuses written by the sweeper
+const (
+	StatusDeviceOffline   = "DEVICE_OFFLINE"
+	StatusDeviceRecovered = "DEVICE_RECOVERED"
+)
+
+// Sweeper persists ONLINE -> OFFLINE transitions and tells the user about them, it is invoked once a minute
+type Sweeper struct {
+	Logger     *slog.Logger
+	StateStore devices.StateStore
+	AlertStore alerts.AlertStore
+	Thresholds *alerts.Monitor // optional, nil leaves duration thresholds to the next reading
+}
+
+func (sweeper *Sweeper) Sweep(ctx context.Context, now time.Time) error {
+	states, err := sweeper.StateStore.GetAllStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	offline, recovered := 0, 0
+	for _, state := range states {
+		var changed bool
+		var err error
+
+		switch {
+		case state.Status == devices.StatusProvisioned || state.Status == devices.StatusDecommissioned:
+			// never reported yet, or retired: there is no outage to tell about
+		case state.OfflineSince == 0 && devices.ConnectionStatus(state.Type, state.LastSeenAt) == "OFFLINE":
+			changed, err = sweeper.markOffline(ctx, state, now)
+			if changed {
+				offline++
+			}
+		case state.OfflineSince > 0 && state.Status == "ONLINE":
+			// ingestion sets the status back to ONLINE on the first message after an outage
+			changed, err = sweeper.markRecovered(ctx, state, now)
+			if changed {
+				recovered++
+			}
+		}
+
+		if err != nil {
+			// one broken device must not block the others
+			sweeper.Logger.Error("offline sweep failed for device", "device_id", state.DeviceID, "error", err)
+		}
+		sweeper.checkPending(ctx, state, now)
+	}
+
+	sweeper.Logger.Info("offline sweep done", "devices", len(states), "offline", offline, "recovered", recovered)
+	return nil
+}
+
+// a breach of a device that stays silent (door left unlocked) is only over when a reading says so,
+// its state is still the last reading. the state of an offline device is unknown, it is skipped
+func (sweeper *Sweeper) checkPending(ctx context.Context, state models.DeviceState, now time.Time) {
+	if sweeper.Thresholds == nil || state.Status != "ONLINE" || state.OfflineSince > 0 {
+		return
+	}
+	if devices.ConnectionStatus(state.Type, state.LastSeenAt) == "OFFLINE" {
+		return
+	}
+
+	if err := sweeper.Thresholds.CheckPending(ctx, state, now.Unix()); err != nil {
+		sweeper.Logger.Error("failed to check pending thresholds", "device_id", state.DeviceID, "error", err)
+	}
+}
+
+func (sweeper *Sweeper) markOffline(ctx context.Context, state models.DeviceState, now time.Time) (bool, error) {
+	at := now.Unix()
+
+	marked, err := sweeper.StateStore.MarkOffline(ctx, state.DeviceID, state.LastSeenAt, at)
+	if err != nil || !marked {
+		return false, err // not marked: the device reported since the scan, or another sweep got it
+	}
+
+	alert := models.Alert{
+		DeviceID:  state.DeviceID,
+		Timestamp: at,
+		Type:      state.Type,
+		Severity:  "MEDIUM",
+		Payload: map[string]interface{}{
+			"status":        StatusDeviceOffline,
+			"last_seen_at":  state.LastSeenAt,
+			"offline_limit": int64(devices.OfflineLimitFor(state.Type).Seconds()),
+		},
+	}
+	if _, err := alerts.RaiseAlert(ctx, sweeper.AlertStore, alert); err != nil && !errors.Is(err, alerts.ErrDuplicateAlert) {
+		return true, err
+	}
+
+	sweeper.Logger.Warn("device went offline", "device_id", state.DeviceID, "last_seen_at", state.LastSeenAt)
+	return true, nil
+}
+
+// resolves the DEVICE_OFFLINE alert and records the recovery. the alert is found by its status, it
+// is saved at offline_since unless another alert of the device had that second
+func (sweeper *Sweeper) markRecovered(ctx context.Context, state models.DeviceState, now time.Time) (bool, error) {
+	marked, err := sweeper.StateStore.MarkRecovered(ctx, state.DeviceID, state.OfflineSince)
+	if err != nil || !marked {
+		return false, err
+	}
+
+	at := now.Unix()
+	_, err = alerts.ResolveMatching(ctx, sweeper.AlertStore, state.DeviceID, at, func(alert models.Alert) bool {
+		return alert.Payload["status"] == StatusDeviceOffline
+	})
+	if err != nil {
+		return true, err
+	}
+
+	// an event rather than a problem, so it is saved already resolved
+	event := models.Alert{
+		DeviceID:   state.DeviceID,
+		Timestamp:  at,
+		Type:       state.Type,
+		Severity:   "LOW",
+		Status:     alerts.StatusResolved,
+		ResolvedBy: alerts.ResolvedByAuto,
+		ResolvedAt: at,
+		Payload: map[string]interface{}{
+			"status":        StatusDeviceRecovered,
+			"offline_since": state.OfflineSince,
+			"last_seen_at":  state.LastSeenAt,
+		},
+	}
+	if _, err := alerts.RaiseAlert(ctx, sweeper.AlertStore, event); err != nil && !errors.Is(err, alerts.ErrDuplicateAlert) {
+		return true, err
+	}
+
+	sweeper.Logger.Info("device recovered", "device_id", state.DeviceID, "offline_since", state.OfflineSince)
+	return true, nil
+}
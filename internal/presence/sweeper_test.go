@@ -0,0 +1,129 @@
+package presence
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func newTestSweeper() (*Sweeper, *devices.MemoryStateStore, *alerts.MemoryAlertStore) {
+	states := devices.NewMemoryStateStore()
+	alertStore := alerts.NewMemoryAlertStore()
+	sweeper := &Sweeper{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StateStore: states,
+		AlertStore: alertStore,
+	}
+	return sweeper, states, alertStore
+}
+
+func reading(deviceID string, ts int64) models.Telemetry {
+	return models.Telemetry{DeviceID: deviceID, Timestamp: ts, Type: "gas-sensor", Payload: map[string]interface{}{"gas_level": 100.0, "alarm_on": false}}
+}
+
+func byStatus(alertList []models.Alert, status string) []models.Alert {
+	var matching []models.Alert
+	for _, alert := range alertList {
+		if alert.Payload["status"] == status {
+			matching = append(matching, alert)
+		}
+	}
+	return matching
+}
+
+func TestSweepOffline(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lastSeen := now.Add(-24 * time.Hour).Unix()
+	sweeper, states, alertStore := newTestSweeper()
+
+	if err := states.UpdateFromTelemetry(ctx, reading("gas-1", lastSeen)); err != nil {
+		t.Fatal(err)
+	}
+	if err := states.RegisterDevice(ctx, models.DeviceState{DeviceID: "gas-2", Type: "gas-sensor", Status: devices.StatusProvisioned, RegisteredAt: lastSeen}); err != nil {
+		t.Fatal(err)
+	}
+	if err := states.UpdateFromTelemetry(ctx, reading("gas-3", lastSeen)); err != nil {
+		t.Fatal(err)
+	}
+	if err := states.DecommissionDevice(ctx, "gas-3", lastSeen+1); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second sweep finds the outage already reported
+	for range 2 {
+		if err := sweeper.Sweep(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, _ := states.GetStateByID(ctx, "gas-1")
+	if state.Status != "OFFLINE" || state.OfflineSince != now.Unix() {
+		t.Errorf("gas-1 = %s since %d, want OFFLINE since %d", state.Status, state.OfflineSince, now.Unix())
+	}
+	all, _ := alertStore.GetAlertsByDevice(ctx, "gas-1", 0)
+	if offline := byStatus(all, StatusDeviceOffline); len(all) != 1 || len(offline) != 1 || offline[0].Status != alerts.StatusOpen {
+		t.Errorf("gas-1 alerts = %+v, want one open %s alert", all, StatusDeviceOffline)
+	}
+
+	for _, deviceID := range []string{"gas-2", "gas-3"} {
+		state, _ := states.GetStateByID(ctx, deviceID)
+		if state.OfflineSince != 0 {
+			t.Errorf("%s marked offline since %d, provisioned and decommissioned devices are skipped", deviceID, state.OfflineSince)
+		}
+		if skipped, _ := alertStore.GetAlertsByDevice(ctx, deviceID, 0); len(skipped) != 0 {
+			t.Errorf("%s alerts = %+v, want none", deviceID, skipped)
+		}
+	}
+}
+
+// the first reading after the outage sets the device ONLINE, the next sweep closes the outage
+func TestSweepRecovered(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	sweeper, states, alertStore := newTestSweeper()
+
+	if err := states.UpdateFromTelemetry(ctx, reading("gas-1", now.Add(-24*time.Hour).Unix())); err != nil {
+		t.Fatal(err)
+	}
+	if err := sweeper.Sweep(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	// an alert raised by the device itself is not part of the outage
+	danger := models.Alert{DeviceID: "gas-1", Timestamp: now.Add(-30 * time.Minute).Unix(), Type: "gas-sensor", Severity: "CRITICAL",
+		Payload: map[string]interface{}{"severity": "CRITICAL", "status": "DANGER"}}
+	if err := alertStore.SaveAlert(ctx, danger); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := states.UpdateFromTelemetry(ctx, reading("gas-1", now.Unix())); err != nil {
+		t.Fatal(err)
+	}
+	if err := sweeper.Sweep(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	state, _ := states.GetStateByID(ctx, "gas-1")
+	if state.Status != "ONLINE" || state.OfflineSince != 0 {
+		t.Errorf("gas-1 = %s offline since %d, want ONLINE with the outage closed", state.Status, state.OfflineSince)
+	}
+
+	all, _ := alertStore.GetAlertsByDevice(ctx, "gas-1", 0)
+	offline := byStatus(all, StatusDeviceOffline)
+	if len(offline) != 1 || offline[0].Status != alerts.StatusResolved || offline[0].ResolvedBy != alerts.ResolvedByAuto {
+		t.Errorf("offline alerts = %+v, want it resolved automatically", offline)
+	}
+	recovered := byStatus(all, StatusDeviceRecovered)
+	if len(recovered) != 1 || recovered[0].Status != alerts.StatusResolved || recovered[0].Payload["offline_since"] != now.Add(-time.Hour).Unix() {
+		t.Errorf("recovered events = %+v, want one saved resolved", recovered)
+	}
+	if stillOpen := byStatus(all, "DANGER"); len(stillOpen) != 1 || stillOpen[0].Status != alerts.StatusOpen {
+		t.Errorf("device alerts = %+v, the recovery must leave them open", stillOpen)
+	}
+}
@@ -0,0 +1,195 @@
+package rollup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+)
+
+// ChartStore holds the rolled up days and the charts built from them, iot.S3Client in production
+type ChartStore interface {
+	GetJSON(ctx context.Context, key string, value interface{}) error // iot.ErrChartNotFound when missing
+	PutJSON(ctx context.Context, key string, value interface{}) error
+}
+
+// Job turns the raw readings into charts before they expire from DynamoDB. Every finished
+// day is rolled up once per device and kept, the month and year charts are rebuilt from
+// those days on every run, so a run that stopped halfway is finished by the next one
+type Job struct {
+	Logger     *slog.Logger
+	StateStore devices.StateStore
+	Telemetry  telemetry.TelemetryStore
+	Charts     ChartStore
+	Homes      homes.HomeStore // optional, days are cut in UTC without it
+}
+
+func (job *Job) Run(ctx context.Context, now time.Time) error {
+	states, err := job.StateStore.GetAllStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	rolled, failed := 0, 0
+	for _, state := range states {
+		if state.Status == devices.StatusProvisioned {
+			continue // never reported, nothing to roll up
+		}
+
+		days, err := job.RollupDevice(ctx, state.DeviceID, now)
+		rolled += days
+		if err != nil {
+			// one broken device must not block the others
+			job.Logger.Error("rollup failed for device", "device_id", state.DeviceID, "error", err)
+			failed++
+		}
+	}
+
+	job.Logger.Info("rollup done", "devices", len(states), "days_rolled_up", rolled, "failed", failed)
+	if failed > 0 {
+		// failing the invocation gets it retried, the devices already done are skipped then
+		return fmt.Errorf("rollup failed for %d devices", failed)
+	}
+	return nil
+}
+
+// RollupDevice rolls up the finished days of the device still fully inside the telemetry
+// retention, then rebuilds the charts of their months and years. it returns how many days
+// were rolled up, the days kept by an earlier run are left as they are. days start at
+// midnight in the zone of the device's home
+func (job *Job) RollupDevice(ctx context.Context, deviceID string, now time.Time) (int, error) {
+	location := time.UTC
+	if job.Homes != nil {
+		var err error
+		if location, err = homes.DeviceZone(ctx, job.Homes, deviceID); err != nil {
+			return 0, err
+		}
+	}
+	now = now.In(location)
+
+	today := startOfDay(now)
+	// the day TelemetryTTL ago has already lost its first readings
+	first := startOfDay(now.Add(-telemetry.TelemetryTTL)).AddDate(0, 0, 1)
+
+	var pending []time.Time
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		var kept telemetry.DayRollup
+		err := job.Charts.GetJSON(ctx, iot.DayRollupKey(deviceID, day), &kept)
+		if errors.Is(err, iot.ErrChartNotFound) {
+			pending = append(pending, day)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(pending) > 0 {
+		history, err := job.Telemetry.GetTelemetryHistory(ctx, deviceID, 0, pending[0].Unix())
+		if err != nil {
+			return 0, err
+		}
+
+		for i, day := range pending {
+			rollup := telemetry.RollupDay(deviceID, history, day)
+			rollup.CreatedAt = now.Unix()
+			if err := job.Charts.PutJSON(ctx, iot.DayRollupKey(deviceID, day), rollup); err != nil {
+				return i, err
+			}
+		}
+	}
+
+	// the charts of every month the window touches, even with nothing pending,
+	// in case the last run stopped between a day and its month
+	var months []time.Time
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		month := startOfMonth(day)
+		if len(months) == 0 || !months[len(months)-1].Equal(month) {
+			months = append(months, month)
+		}
+	}
+
+	for _, month := range months {
+		if err := job.buildMonth(ctx, deviceID, month, today, now); err != nil {
+			return len(pending), err
+		}
+	}
+	for i, month := range months {
+		if i > 0 && months[i-1].Year() == month.Year() {
+			continue
+		}
+		if err := job.buildYear(ctx, deviceID, month, now); err != nil {
+			return len(pending), err
+		}
+	}
+	return len(pending), nil
+}
+
+func (job *Job) buildMonth(ctx context.Context, deviceID string, month time.Time, today time.Time, now time.Time) error {
+	var days []telemetry.DayRollup
+	for day := month; day.Before(today) && day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+		var rollup telemetry.DayRollup
+		err := job.Charts.GetJSON(ctx, iot.DayRollupKey(deviceID, day), &rollup)
+		if errors.Is(err, iot.ErrChartNotFound) {
+			continue // before the device existed, or expired before a run got to it
+		}
+		if err != nil {
+			return err
+		}
+		days = append(days, rollup)
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	chart := telemetry.BuildMonthChart(deviceID, month, days)
+	chart.UpdatedAt = now.Unix()
+	return job.Charts.PutJSON(ctx, iot.ChartKey(deviceID, month), chart)
+}
+
+func (job *Job) buildYear(ctx context.Context, deviceID string, year time.Time, now time.Time) error {
+	var months []telemetry.MonthChart
+	for month := time.Date(year.Year(), time.January, 1, 0, 0, 0, 0, year.Location()); month.Year() == year.Year(); month = month.AddDate(0, 1, 0) {
+		var raw json.RawMessage
+		err := job.Charts.GetJSON(ctx, iot.ChartKey(deviceID, month), &raw)
+		if errors.Is(err, iot.ErrChartNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+			continue // written before the rollup job, a bare array without totals
+		}
+
+		var chart telemetry.MonthChart
+		if err := json.Unmarshal(raw, &chart); err != nil {
+			return fmt.Errorf("failed to parse month chart %s: %w", month.Format("2006-01"), err)
+		}
+		months = append(months, chart)
+	}
+	if len(months) == 0 {
+		return nil
+	}
+
+	chart := telemetry.BuildYearChart(deviceID, year, months)
+	chart.UpdatedAt = now.Unix()
+	return job.Charts.PutJSON(ctx, iot.YearChartKey(deviceID, year), chart)
+}
+
+// midnight in the location of at, what the hot tier labels of the home use too
+func startOfDay(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+}
+
+func startOfMonth(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+}
@@ -0,0 +1,225 @@
+package rollup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/iot"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+var errStoreDown = errors.New("store down")
+
+// memoryCharts keeps the objects as JSON like S3. writes to keys containing failOn fail
+// once failAfter of them went through, failAfter < 0 never fails
+type memoryCharts struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	puts      int
+	failOn    string
+	failAfter int
+}
+
+func newMemoryCharts() *memoryCharts {
+	return &memoryCharts{objects: make(map[string][]byte), failAfter: -1}
+}
+
+func (charts *memoryCharts) GetJSON(ctx context.Context, key string, value interface{}) error {
+	charts.mu.Lock()
+	defer charts.mu.Unlock()
+
+	data, exists := charts.objects[key]
+	if !exists {
+		return iot.ErrChartNotFound
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (charts *memoryCharts) PutJSON(ctx context.Context, key string, value interface{}) error {
+	charts.mu.Lock()
+	defer charts.mu.Unlock()
+
+	if charts.failAfter >= 0 && strings.Contains(key, charts.failOn) {
+		if charts.failAfter == 0 {
+			return errStoreDown
+		}
+		charts.failAfter--
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	charts.objects[key] = data
+	charts.puts++
+	return nil
+}
+
+func (charts *memoryCharts) count(prefix string) int {
+	charts.mu.Lock()
+	defer charts.mu.Unlock()
+
+	count := 0
+	for key := range charts.objects {
+		if strings.HasPrefix(key, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// the window of 3 June 2026 is 28 May to 2 June, two months of the same year
+var rollupNow = time.Date(2026, time.June, 3, 10, 0, 0, 0, time.UTC)
+
+func newTestJob(t *testing.T, charts *memoryCharts, deviceIDs ...string) *Job {
+	t.Helper()
+	ctx := context.Background()
+	states := devices.NewMemoryStateStore()
+	store := telemetry.NewMemoryTelemetryStore()
+
+	for _, deviceID := range deviceIDs {
+		for day := 1; day <= 8; day++ {
+			at := time.Date(2026, time.June, 3-day, 12, 0, 0, 0, time.UTC).Unix()
+			reading := models.Telemetry{DeviceID: deviceID, Timestamp: at, Type: "temp-sensor", Payload: map[string]interface{}{"temp": 20.0 + float64(day)}}
+			if err := store.SaveTelemetry(ctx, reading); err != nil {
+				t.Fatal(err)
+			}
+			if err := states.UpdateFromTelemetry(ctx, reading); err != nil && !errors.Is(err, devices.ErrStaleUpdate) {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return &Job{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StateStore: states,
+		Telemetry:  store,
+		Charts:     charts,
+	}
+}
+
+func TestRollupDevice(t *testing.T) {
+	ctx := context.Background()
+	charts := newMemoryCharts()
+	job := newTestJob(t, charts, "temp-1")
+
+	days, err := job.RollupDevice(ctx, "temp-1", rollupNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if days != 6 || charts.count("processed-charts/temp-1/days/") != 6 {
+		t.Errorf("rolled up %d days, %d kept, want the 6 finished days inside the retention", days, charts.count("processed-charts/temp-1/days/"))
+	}
+
+	var day telemetry.DayRollup
+	if err := charts.GetJSON(ctx, iot.DayRollupKey("temp-1", time.Date(2026, time.May, 31, 0, 0, 0, 0, time.UTC)), &day); err != nil {
+		t.Fatal(err)
+	}
+	if total := day.Metrics["temp"].Total; total.Count != 1 || total.Sum != 23 {
+		t.Errorf("31 May temp = %+v, want the one reading of 23", total)
+	}
+
+	for _, key := range []string{"processed-charts/temp-1/2026-05.json", "processed-charts/temp-1/2026-06.json"} {
+		var month telemetry.MonthChart
+		if err := charts.GetJSON(ctx, key, &month); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+	var year telemetry.YearChart
+	if err := charts.GetJSON(ctx, "processed-charts/temp-1/2026.json", &year); err != nil {
+		t.Fatal(err)
+	}
+	if len(year.Months) != 2 || year.Months[0] != "2026-05" || year.Months[1] != "2026-06" {
+		t.Errorf("year months = %v, want May and June", year.Months)
+	}
+
+	// a second run keeps the days, only the charts are rebuilt
+	puts := charts.puts
+	if days, err := job.RollupDevice(ctx, "temp-1", rollupNow); err != nil || days != 0 {
+		t.Errorf("second run rolled up %d days (%v), want none", days, err)
+	}
+	if rebuilt := charts.puts - puts; rebuilt != 3 {
+		t.Errorf("second run wrote %d objects, want the 2 months and the year", rebuilt)
+	}
+}
+
+// a run that stops halfway is finished by the next one
+func TestRollupDeviceResume(t *testing.T) {
+	ctx := context.Background()
+	charts := newMemoryCharts()
+	charts.failOn, charts.failAfter = "/days/", 2
+	job := newTestJob(t, charts, "temp-1")
+
+	days, err := job.RollupDevice(ctx, "temp-1", rollupNow)
+	if !errors.Is(err, errStoreDown) || days != 2 {
+		t.Fatalf("interrupted run = %d days, %v, want 2 days and the store error", days, err)
+	}
+	if charts.count("processed-charts/temp-1/2026") != 0 {
+		t.Error("charts written by the interrupted run, want them left to the next one")
+	}
+
+	charts.failAfter = -1
+	if days, err = job.RollupDevice(ctx, "temp-1", rollupNow); err != nil || days != 4 {
+		t.Fatalf("next run = %d days, %v, want the 4 days left", days, err)
+	}
+	var month telemetry.MonthChart
+	if err := charts.GetJSON(ctx, "processed-charts/temp-1/2026-05.json", &month); err != nil {
+		t.Fatal(err)
+	}
+	if len(month.Days) != 4 {
+		t.Errorf("May chart days = %v, want 28 to 31 May and nothing missing", month.Days)
+	}
+}
+
+// month charts written before the job are bare arrays, the year chart leaves them out
+func TestRollupYearSkipsLegacyMonths(t *testing.T) {
+	ctx := context.Background()
+	charts := newMemoryCharts()
+	charts.objects["processed-charts/temp-1/2026-01.json"] = []byte(`[{"label":"Jan 01","value":21}]`)
+	march := telemetry.MonthChart{DeviceID: "temp-1", Month: "2026-03", Totals: map[string]telemetry.Total{"temp": {Count: 1, Sum: 22}}}
+	if err := charts.PutJSON(ctx, "processed-charts/temp-1/2026-03.json", march); err != nil {
+		t.Fatal(err)
+	}
+	job := newTestJob(t, charts, "temp-1")
+
+	if _, err := job.RollupDevice(ctx, "temp-1", rollupNow); err != nil {
+		t.Fatal(err)
+	}
+	var year telemetry.YearChart
+	if err := charts.GetJSON(ctx, "processed-charts/temp-1/2026.json", &year); err != nil {
+		t.Fatal(err)
+	}
+	if len(year.Months) != 3 || year.Months[0] != "2026-03" {
+		t.Errorf("year months = %v, want March, May and June without the legacy January", year.Months)
+	}
+}
+
+// one failing device fails the run, the others are rolled up and provisioned ones are skipped
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	charts := newMemoryCharts()
+	charts.failOn, charts.failAfter = "/temp-2/", 0
+	job := newTestJob(t, charts, "temp-1", "temp-2")
+	states := job.StateStore.(*devices.MemoryStateStore)
+	if err := states.RegisterDevice(ctx, models.DeviceState{DeviceID: "temp-3", Type: "temp-sensor", Status: devices.StatusProvisioned, RegisteredAt: rollupNow.Unix()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := job.Run(ctx, rollupNow); err == nil {
+		t.Error("run succeeded, want it failed for temp-2")
+	}
+	if days := charts.count("processed-charts/temp-1/days/"); days != 6 {
+		t.Errorf("temp-1 has %d days, want it rolled up despite temp-2", days)
+	}
+	if charts.count("processed-charts/temp-3/") != 0 {
+		t.Error("temp-3 never reported, want nothing written for it")
+	}
+}
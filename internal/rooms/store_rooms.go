@@ -0,0 +1,128 @@
+package rooms
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const homeIndex = "HomeIndex"
+
+// RoomStore keeps the rooms of every home (room_id), the devices point at their room
+type RoomStore interface {
+	SaveRoom(ctx context.Context, room models.Room) error
+	GetRoom(ctx context.Context, roomID string) (*models.Room, error)
+	GetRooms(ctx context.Context, homeID string) ([]models.Room, error)
+	DeleteRoom(ctx context.Context, roomID string) error
+}
+
+type DynamoRoomStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewRoomStore() (*DynamoRoomStore, error) {
+	tableName := os.Getenv("DYNAMODB_ROOMS_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_ROOMS_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoRoomStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+func (store *DynamoRoomStore) SaveRoom(ctx context.Context, room models.Room) error {
+	item, err := attributevalue.MarshalMap(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store room in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+// returns nil, nil when the room doesn't exist
+func (store *DynamoRoomStore) GetRoom(ctx context.Context, roomID string) (*models.Room, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"room_id": &types.AttributeValueMemberS{Value: roomID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room %s: %w", roomID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var room models.Room
+	if err = attributevalue.UnmarshalMap(result.Item, &room); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal room %s: %w", roomID, err)
+	}
+
+	return &room, nil
+}
+
+func (store *DynamoRoomStore) GetRooms(ctx context.Context, homeID string) ([]models.Room, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		IndexName:              aws.String(homeIndex),
+		KeyConditionExpression: aws.String("home_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: homeID},
+		},
+	}
+
+	roomList := []models.Room{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query rooms of home %s: %w", homeID, err)
+		}
+
+		var items []models.Room
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rooms of home %s: %w", homeID, err)
+		}
+		roomList = append(roomList, items...)
+	}
+
+	return roomList, nil
+}
+
+func (store *DynamoRoomStore) DeleteRoom(ctx context.Context, roomID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"room_id": &types.AttributeValueMemberS{Value: roomID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete room %s: %w", roomID, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package rooms
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MemoryRoomStore is an in-process RoomStore used for tests and local demos
+type MemoryRoomStore struct {
+	mu    sync.Mutex
+	rooms map[string]models.Room
+}
+
+func NewMemoryRoomStore() *MemoryRoomStore {
+	return &MemoryRoomStore{
+		rooms: make(map[string]models.Room),
+	}
+}
+
+func (store *MemoryRoomStore) SaveRoom(ctx context.Context, room models.Room) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.rooms[room.RoomID] = room
+	return nil
+}
+
+func (store *MemoryRoomStore) GetRoom(ctx context.Context, roomID string) (*models.Room, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	room, exists := store.rooms[roomID]
+	if !exists {
+		return nil, nil
+	}
+	return &room, nil
+}
+
+func (store *MemoryRoomStore) GetRooms(ctx context.Context, homeID string) ([]models.Room, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	roomList := make([]models.Room, 0)
+	for _, room := range store.rooms {
+		if room.HomeID == homeID {
+			roomList = append(roomList, room)
+		}
+	}
+	slices.SortFunc(roomList, func(a, b models.Room) int {
+		return cmp.Compare(a.RoomID, b.RoomID)
+	})
+	return roomList, nil
+}
+
+func (store *MemoryRoomStore) DeleteRoom(ctx context.Context, roomID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.rooms, roomID)
+	return nil
+}
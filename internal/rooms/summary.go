@@ -0,0 +1,157 @@
+package rooms
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/telemetry"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const mixed = "MIXED"
+
+// Summary merges the live state of a room's devices, "Living room: 24.5, door locked, light normal".
+// the safety related fields report the worst device: one unlocked door makes the room UNLOCKED
+type Summary struct {
+	Devices     int      `json:"devices"`
+	Online      int      `json:"online"`
+	Health      string   `json:"health,omitempty"`      // DEGRADED as soon as one device is
+	Temperature *float64 `json:"temperature,omitempty"` // average of the temp sensors' last readings
+	Light       string   `json:"light,omitempty"`       // BRIGHT, NORMAL or DARK, MIXED when the sensors disagree
+	Lock        string   `json:"lock,omitempty"`        // LOCKED or UNLOCKED
+	Door        string   `json:"door,omitempty"`        // CLOSED or OPEN
+	Gas         string   `json:"gas,omitempty"`         // SAFE or DANGER
+	AC          string   `json:"ac,omitempty"`          // OFF or ON
+}
+
+// Insights are the room level analytics, computed from the history of its devices
+type Insights struct {
+	Temperature   *telemetry.TempState   `json:"temperature_24h,omitempty"`
+	ACUsage       []telemetry.ChartPoint `json:"ac_usage_7d,omitempty"`
+	ACRunningTime string                 `json:"ac_running_time_24h,omitempty"`
+}
+
+func Summarize(states []models.DeviceState) Summary {
+	summary := Summary{Devices: len(states)}
+
+	tempSum, tempCount := 0.0, 0
+	for _, state := range states {
+		if devices.LiveStatus(state) == "ONLINE" {
+			summary.Online++
+		}
+		if summary.Health == "" || state.Health == "DEGRADED" {
+			summary.Health = state.Health
+		}
+
+		switch state.Type {
+		case "temp-sensor":
+			if temp, ok := state.Payload["temp"].(float64); ok {
+				tempSum += temp
+				tempCount++
+			}
+		case "light-sensor":
+			summary.Light = agree(summary.Light, state.OperationalState)
+		case "door-actuator":
+			summary.Lock = worst(summary.Lock, state.OperationalState, "UNLOCKED")
+		case "door-sensor":
+			summary.Door = worst(summary.Door, state.OperationalState, "OPEN")
+		case "gas-sensor":
+			summary.Gas = worst(summary.Gas, state.OperationalState, "DANGER")
+		case "ac-actuator":
+			summary.AC = worst(summary.AC, state.OperationalState, "ON")
+		}
+	}
+
+	if tempCount > 0 {
+		average := math.Round(tempSum/float64(tempCount)*10) / 10
+		summary.Temperature = &average
+	}
+	return summary
+}
+
+// BuildInsights runs the device analytics over the whole room: the temp sensors' readings are
+// merged into one series, the ACs are computed one by one and added up.
+// histories holds the last 7 days of every device, newest first. days are cut in location
+func BuildInsights(states []models.DeviceState, histories map[string][]models.Telemetry, now int64, location *time.Location) Insights {
+	var insights Insights
+	var temps []models.Telemetry
+	var runningSeconds int64
+	acCount := 0
+	usage := make(map[int64]telemetry.ChartPoint) // summed per bucket start, labels repeat across weeks
+
+	for _, state := range states {
+		history := histories[state.DeviceID]
+		switch state.Type {
+		case "temp-sensor":
+			temps = append(temps, history...)
+		case "ac-actuator":
+			acCount++
+			runningSeconds += telemetry.CalculateACRunTime(since(history, telemetry.PeriodCutoff(now, "24h")), now)
+			for _, point := range telemetry.CalculateACUsage(history, now, "7d", location) {
+				total := usage[point.Timestamp]
+				total.Label, total.Timestamp = point.Label, point.Timestamp
+				total.Value += point.Value
+				usage[point.Timestamp] = total
+			}
+		}
+	}
+
+	if len(temps) > 0 {
+		// CalculateTempState stops at the first reading older than 24h, so the series must stay sorted
+		slices.SortStableFunc(temps, func(a, b models.Telemetry) int {
+			return cmp.Compare(b.Timestamp, a.Timestamp)
+		})
+		if stats, err := telemetry.CalculateTempState(temps, "temp", now); err == nil {
+			insights.Temperature = &stats
+		}
+	}
+
+	if acCount > 0 {
+		insights.ACUsage = make([]telemetry.ChartPoint, 0, len(usage))
+		for _, total := range usage {
+			total.Value = math.Round(total.Value*10) / 10
+			insights.ACUsage = append(insights.ACUsage, total)
+		}
+		slices.SortFunc(insights.ACUsage, func(a, b telemetry.ChartPoint) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		insights.ACRunningTime = telemetry.FormatACTime(runningSeconds)
+	}
+	return insights
+}
+
+// the devices that report history the insights use
+func NeedsHistory(deviceType string) bool {
+	return deviceType == "temp-sensor" || deviceType == "ac-actuator"
+}
+
+// the same state for every device, MIXED otherwise
+func agree(current string, next string) string {
+	if next == "" || next == "UNKNOWN" || current == next {
+		return current
+	}
+	if current == "" {
+		return next
+	}
+	return mixed
+}
+
+// the alarming state wins over the others
+func worst(current string, next string, alarming string) string {
+	if next == "" || next == "UNKNOWN" || current == alarming {
+		return current
+	}
+	return next
+}
+
+// the readings at or after cutoff of a newest first history
+func since(history []models.Telemetry, cutoff int64) []models.Telemetry {
+	end := 0
+	for end < len(history) && history[end].Timestamp >= cutoff {
+		end++
+	}
+	return history[:end]
+}
@@ -0,0 +1,103 @@
+package rooms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestSummarize(t *testing.T) {
+	now := time.Now().Unix()
+	device := func(deviceType string, state string, payload map[string]interface{}) models.DeviceState {
+		return models.DeviceState{Type: deviceType, Status: "ONLINE", OperationalState: state, Health: "HEALTHY", LastSeenAt: now, Payload: payload}
+	}
+
+	tests := []struct {
+		name   string
+		states []models.DeviceState
+		check  func(Summary) bool
+	}{
+		{"empty room", nil, func(summary Summary) bool {
+			return summary.Devices == 0 && summary.Temperature == nil && summary.Health == ""
+		}},
+		{"temperature averaged", []models.DeviceState{
+			device("temp-sensor", "NORMAL", map[string]interface{}{"temp": 24.0}),
+			device("temp-sensor", "NORMAL", map[string]interface{}{"temp": 25.0}),
+			device("temp-sensor", "UNKNOWN", map[string]interface{}{}),
+		}, func(summary Summary) bool {
+			return summary.Devices == 3 && summary.Temperature != nil && *summary.Temperature == 24.5
+		}},
+		{"lights agree", []models.DeviceState{
+			device("light-sensor", "BRIGHT", nil),
+			device("light-sensor", "UNKNOWN", nil),
+			device("light-sensor", "BRIGHT", nil),
+		}, func(summary Summary) bool { return summary.Light == "BRIGHT" }},
+		{"lights disagree", []models.DeviceState{
+			device("light-sensor", "BRIGHT", nil),
+			device("light-sensor", "DARK", nil),
+		}, func(summary Summary) bool { return summary.Light == mixed }},
+		{"one unlocked door", []models.DeviceState{
+			device("door-actuator", "UNLOCKED", nil),
+			device("door-actuator", "LOCKED", nil),
+		}, func(summary Summary) bool { return summary.Lock == "UNLOCKED" }},
+		{"gas danger wins", []models.DeviceState{
+			device("gas-sensor", "SAFE", nil),
+			device("gas-sensor", "DANGER", nil),
+			device("gas-sensor", "SAFE", nil),
+		}, func(summary Summary) bool { return summary.Gas == "DANGER" }},
+		{"degraded health wins", []models.DeviceState{
+			{Type: "door-sensor", Status: "ONLINE", OperationalState: "CLOSED", Health: "DEGRADED", LastSeenAt: now},
+			device("door-sensor", "CLOSED", nil),
+		}, func(summary Summary) bool { return summary.Health == "DEGRADED" && summary.Door == "CLOSED" }},
+		{"online devices", []models.DeviceState{
+			device("ac-actuator", "ON", nil),
+			{Type: "ac-actuator", Status: "ONLINE", OperationalState: "OFF", LastSeenAt: now - 86400},
+			{Type: "ac-actuator", Status: devices.StatusProvisioned},
+		}, func(summary Summary) bool { return summary.Online == 1 && summary.AC == "ON" }},
+	}
+	for _, test := range tests {
+		if summary := Summarize(test.states); !test.check(summary) {
+			t.Errorf("%s: got %+v", test.name, summary)
+		}
+	}
+}
+
+func TestBuildInsights(t *testing.T) {
+	now := time.Now().Unix()
+	reading := func(ts int64, payload map[string]interface{}) models.Telemetry {
+		return models.Telemetry{Timestamp: ts, Payload: payload}
+	}
+
+	states := []models.DeviceState{
+		{DeviceID: "temp-1", Type: "temp-sensor"},
+		{DeviceID: "temp-2", Type: "temp-sensor"},
+		{DeviceID: "ac-1", Type: "ac-actuator"},
+		{DeviceID: "ac-2", Type: "ac-actuator"},
+		{DeviceID: "door-1", Type: "door-sensor"},
+	}
+	// newest first per device. temp-1's old reading must not hide temp-2's recent one
+	histories := map[string][]models.Telemetry{
+		"temp-1": {reading(now-100, map[string]interface{}{"temp": 20.0}), reading(now-90000, map[string]interface{}{"temp": 5.0})},
+		"temp-2": {reading(now-50, map[string]interface{}{"temp": 30.0})},
+		"ac-1":   {reading(now-1800, map[string]interface{}{"power_state": "OFF"}), reading(now-3600, map[string]interface{}{"power_state": "ON"})},
+		"ac-2":   {reading(now-1800, map[string]interface{}{"power_state": "OFF"}), reading(now-3600, map[string]interface{}{"power_state": "ON"})},
+	}
+
+	insights := BuildInsights(states, histories, now, time.UTC)
+
+	if insights.Temperature == nil || insights.Temperature.Min != 20 || insights.Temperature.Max != 30 {
+		t.Errorf("temperature = %+v, want 20 to 30 over both sensors", insights.Temperature)
+	}
+	if insights.ACRunningTime != "1h 0m" {
+		t.Errorf("ac running time = %q, want both ACs' half hour added up", insights.ACRunningTime)
+	}
+	if len(insights.ACUsage) == 0 {
+		t.Error("no ac usage, want the 7 day chart")
+	}
+
+	if empty := BuildInsights(states[4:], histories, now, time.UTC); empty.Temperature != nil || empty.ACUsage != nil || empty.ACRunningTime != "" {
+		t.Errorf("insights without temp sensors or ACs = %+v, want none", empty)
+	}
+}
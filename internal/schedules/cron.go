@@ -0,0 +1,207 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// CronSpec is a parsed 5-field cron expression: minute hour day-of-month month day-of-week
+type CronSpec struct {
+	minutes  [60]bool
+	hours    [24]bool
+	days     [32]bool // 1-31
+	months   [13]bool // 1-12
+	weekdays [7]bool  // 0 = Sunday
+
+	anyDay     bool
+	anyWeekday bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField  = cronField{min: 0, max: 59}
+	hourField    = cronField{min: 0, max: 23}
+	dayField     = cronField{min: 1, max: 31}
+	monthField   = cronField{min: 1, max: 12, names: map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}}
+	weekdayField = cronField{min: 0, max: 7, names: map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}}
+)
+
+// supports *, lists (1,3), ranges (1-5), steps (*/15, 0-30/10) and JAN/MON style names
+func ParseCron(expr string) (*CronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidCron, len(fields))
+	}
+
+	spec := &CronSpec{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+
+	if err := parseField(fields[0], minuteField, spec.minutes[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(fields[1], hourField, spec.hours[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(fields[2], dayField, spec.days[:]); err != nil {
+		return nil, err
+	}
+	if err := parseField(fields[3], monthField, spec.months[:]); err != nil {
+		return nil, err
+	}
+
+	var weekdays [8]bool // 7 is also Sunday
+	if err := parseField(fields[4], weekdayField, weekdays[:]); err != nil {
+		return nil, err
+	}
+	copy(spec.weekdays[:], weekdays[:7])
+	spec.weekdays[0] = spec.weekdays[0] || weekdays[7]
+
+	return spec, nil
+}
+
+func parseField(raw string, field cronField, set []bool) error {
+	for _, part := range strings.Split(raw, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			parsedStep, err := strconv.Atoi(part[idx+1:])
+			if err != nil || parsedStep <= 0 {
+				return fmt.Errorf("%w: bad step in %q", ErrInvalidCron, part)
+			}
+			step = parsedStep
+		}
+
+		low, high := field.min, field.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = field.value(bounds[0]); err != nil {
+				return err
+			}
+			if high, err = field.value(bounds[1]); err != nil {
+				return err
+			}
+			if low > high {
+				return fmt.Errorf("%w: range %q is reversed", ErrInvalidCron, rangePart)
+			}
+		default:
+			value, err := field.value(rangePart)
+			if err != nil {
+				return err
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func (field cronField) value(raw string) (int, error) {
+	if v, ok := field.names[strings.ToUpper(raw)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("%w: %q out of range %d-%d", ErrInvalidCron, raw, field.min, field.max)
+	}
+	return v, nil
+}
+
+// Next returns the first matching minute strictly after `after`, read in loc's wall clock.
+// a run inside a mid-day DST gap moves to the first valid minute after it, a run inside the
+// repeated hour when clocks go back only happens once
+func (spec *CronSpec) Next(after time.Time, loc *time.Location) (time.Time, error) {
+	t := after.In(loc)
+	afterWall := wallClock(t)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !spec.months[t.Month()] {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !spec.dayMatches(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !spec.hours[t.Hour()] {
+			wantedHour := t.Hour() + 1
+			next := time.Date(t.Year(), t.Month(), t.Day(), wantedHour, 0, 0, 0, loc)
+			endOfHour := t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			if wantedHour < 24 && next.Hour() != wantedHour && spec.hours[wantedHour] {
+				// the whole hour doesn't exist today (DST gap), run as soon as the clock allows
+				return endOfHour.Add(time.Duration(spec.firstMinute()) * time.Minute), nil
+			}
+			if !next.After(t) {
+				next = endOfHour
+			}
+			t = next
+			continue
+		}
+		if !spec.minutes[t.Minute()] || wallClock(t) <= afterWall {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: no run time within 5 years", ErrInvalidCron)
+}
+
+// zones with a DST jump at midnight normalize some dates backwards, never step back
+func forward(current time.Time, next time.Time) time.Time {
+	if next.After(current) {
+		return next
+	}
+	return current.Add(time.Minute)
+}
+
+func (spec *CronSpec) firstMinute() int {
+	for minute, ok := range spec.minutes {
+		if ok {
+			return minute
+		}
+	}
+	return 0
+}
+
+// wall clock reading as a comparable number, ignoring the zone offset
+func wallClock(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Unix()
+}
+
+// standard cron rule: when both day fields are restricted either one may match
+func (spec *CronSpec) dayMatches(t time.Time) bool {
+	dayOK := spec.days[t.Day()]
+	weekdayOK := spec.weekdays[t.Weekday()]
+	switch {
+	case spec.anyDay && spec.anyWeekday:
+		return true
+	case spec.anyDay:
+		return weekdayOK
+	case spec.anyWeekday:
+		return dayOK
+	default:
+		return dayOK || weekdayOK
+	}
+}
@@ -0,0 +1,186 @@
+package schedules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0-30/10 8-18 * * MON-FRI",
+		"0 7 1,15 JAN,jul *",
+		"30 6 * * 7", // 7 is Sunday too
+	}
+	for _, expr := range valid {
+		if _, err := ParseCron(expr); err != nil {
+			t.Errorf("ParseCron(%q) = %v, want no error", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"30-10 * * * *",
+		"* * * FOO *",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("ParseCron(%q) = %v, want ErrInvalidCron", expr, err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"next minute", "* * * * *", time.Date(2026, 5, 4, 10, 15, 30, 0, time.UTC), time.Date(2026, 5, 4, 10, 16, 0, 0, time.UTC)},
+		{"strictly after", "0 9 * * *", time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC), time.Date(2026, 5, 5, 9, 0, 0, 0, time.UTC)},
+		{"step", "*/20 * * * *", time.Date(2026, 5, 4, 10, 41, 0, 0, time.UTC), time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC)},
+		{"weekdays only", "0 8 * * MON-FRI", time.Date(2026, 5, 8, 9, 0, 0, 0, time.UTC), time.Date(2026, 5, 11, 8, 0, 0, 0, time.UTC)}, // friday -> monday
+		{"day or weekday", "0 0 13 * FRI", time.Date(2026, 5, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 12 29 FEB *", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"read in the zone", "0 7 * * *", time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC), time.Date(2026, 5, 5, 7, 0, 0, 0, ny)},
+
+		// clocks skip 02:00-03:00 on 2026-03-08, the run moves past the gap instead of waiting a day
+		{"DST gap", "30 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, ny), time.Date(2026, 3, 8, 3, 30, 0, 0, ny)},
+		{"DST gap steps", "*/15 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, ny), time.Date(2026, 3, 8, 3, 0, 0, 0, ny)},
+		{"after DST gap", "30 2 * * *", time.Date(2026, 3, 8, 3, 30, 0, 0, ny), time.Date(2026, 3, 9, 2, 30, 0, 0, ny)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := ParseCron(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc := test.want.Location()
+			got, err := spec.Next(test.after, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("Next(%s) = %s, want %s", test.after, got.In(loc), test.want)
+			}
+		})
+	}
+}
+
+// clocks go back from 02:00 EDT to 01:00 EST on 2026-11-01, 01:30 happens twice
+func TestCronNextRepeatedHour(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	spec, err := ParseCron("30 1 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := spec.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, ny), ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC); !first.Equal(want) {
+		t.Fatalf("first run = %s, want %s (01:30 EDT)", first, want)
+	}
+
+	second, err := spec.Next(first, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC); !second.Equal(want) {
+		t.Errorf("run after 01:30 EDT = %s, want the next day %s, not 01:30 EST", second, want)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2026, 5, 4, 10, 0, 30, 0, time.UTC)
+	hourly := models.Schedule{Cron: "0 * * * *", TimeZone: "UTC", CatchUp: CatchUpSkip}
+
+	onTime := hourly
+	onTime.NextRunAt = time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC).Unix()
+	decision, err := Plan(onTime, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decision.Run || decision.Missed != 0 || !decision.Enabled || decision.NextRunAt != time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("on time: got %+v", decision)
+	}
+
+	// the runner was down for three hours, the latest run is outside the grace period
+	late := hourly
+	late.NextRunAt = time.Date(2026, 5, 4, 7, 0, 0, 0, time.UTC).Unix()
+	decision, err = Plan(late, now.Add(20*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Run || decision.Missed != 4 {
+		t.Errorf("SKIP: got %+v, want no run and 4 missed", decision)
+	}
+
+	late.CatchUp = CatchUpRunOnce
+	decision, err = Plan(late, now.Add(20*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decision.Run || decision.Missed != 3 {
+		t.Errorf("RUN_ONCE: got %+v, want one run and 3 missed", decision)
+	}
+
+	oneShot := models.Schedule{RunAt: now.Unix() - 60, NextRunAt: now.Unix() - 60, CatchUp: CatchUpSkip}
+	decision, err = Plan(oneShot, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decision.Run || decision.Enabled || decision.NextRunAt != 0 {
+		t.Errorf("one-shot: got %+v, want a last run", decision)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	schedule := models.Schedule{Cron: "0 7 * * *", TimeZone: "Asia/Kolkata"}
+	if err := Prepare(&schedule, now); err != nil {
+		t.Fatal(err)
+	}
+	if schedule.CatchUp != CatchUpSkip || schedule.NextRunAt != time.Date(2026, 5, 5, 1, 30, 0, 0, time.UTC).Unix() {
+		t.Errorf("got catch_up %s next run %d", schedule.CatchUp, schedule.NextRunAt)
+	}
+
+	invalid := []models.Schedule{
+		{},
+		{Cron: "0 7 * * *", RunAt: now.Unix() + 60},
+		{RunAt: now.Unix()},
+		{Cron: "0 7 * * *", TimeZone: "Mars/Olympus"},
+		{Cron: "0 7 * *"},
+		{Cron: "0 7 * * *", CatchUp: "ALWAYS"},
+	}
+	for _, schedule := range invalid {
+		if err := Prepare(&schedule, now); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("Prepare(%+v) = %v, want ErrInvalidSchedule", schedule, err)
+		}
+	}
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
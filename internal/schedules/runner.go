@@ -0,0 +1,125 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// the device can't take the command until someone changes it, retrying the run would not help
+var errDeviceInactive = errors.New("device not found or decommissioned")
+
+// Runner sends the commands of every due schedule, it is invoked once a minute
+type Runner struct {
+	Logger     *slog.Logger
+	Schedules  ScheduleStore
+	StateStore devices.StateStore
+	Homes      homes.HomeStore
+	Dispatcher *commands.Dispatcher
+}
+
+func (runner *Runner) RunDue(ctx context.Context, now time.Time) error {
+	due, err := runner.Schedules.GetDueSchedules(ctx, now.Unix())
+	if err != nil {
+		return err
+	}
+
+	runner.Logger.Info("running due schedules", "count", len(due))
+
+	for _, schedule := range due {
+		if err := runner.runSchedule(ctx, schedule, now); err != nil {
+			// one broken schedule must not block the others
+			runner.Logger.Error("schedule run failed", "schedule_id", schedule.ScheduleID, "device_id", schedule.DeviceID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (runner *Runner) runSchedule(ctx context.Context, schedule models.Schedule, now time.Time) error {
+	decision, err := Plan(schedule, now)
+	if err != nil {
+		// a schedule that can't compute its next run is disabled instead of retried every minute
+		decision = Decision{}
+		runner.Logger.Warn("disabling schedule with invalid timing", "schedule_id", schedule.ScheduleID, "error", err)
+	}
+
+	claim := Claim{
+		ExpectedNextRunAt: schedule.NextRunAt,
+		NextRunAt:         decision.NextRunAt,
+		Enabled:           decision.Enabled,
+		RunAt:             now.Unix(),
+	}
+	claimed, claimErr := runner.Schedules.ClaimRun(ctx, schedule.ScheduleID, claim)
+	if claimErr != nil {
+		return claimErr
+	}
+	if !claimed {
+		runner.Logger.Info("schedule already claimed by another run", "schedule_id", schedule.ScheduleID)
+		return nil
+	}
+
+	if err != nil {
+		return runner.Schedules.RecordRun(ctx, schedule.ScheduleID, "", err.Error())
+	}
+
+	if !decision.Run {
+		runner.Logger.Warn("skipping missed schedule runs", "schedule_id", schedule.ScheduleID, "missed", decision.Missed, "catch_up", schedule.CatchUp)
+		return runner.Schedules.RecordRun(ctx, schedule.ScheduleID, "", fmt.Sprintf("skipped %d missed run(s)", decision.Missed))
+	}
+
+	cmd, dispatchErr := runner.dispatch(ctx, schedule)
+	if errors.Is(dispatchErr, homes.ErrCommandRevoked) {
+		// the creator lost the permission or the device changed home, the schedule stops for good
+		runner.Logger.Warn("disabling schedule, command no longer allowed", "schedule_id", schedule.ScheduleID, "device_id", schedule.DeviceID, "created_by", schedule.CreatedBy, "error", dispatchErr)
+		return runner.Schedules.DisableSchedule(ctx, schedule.ScheduleID, dispatchErr.Error(), now.Unix())
+	}
+	if dispatchErr != nil {
+		runner.Logger.Error("failed to dispatch scheduled command", "schedule_id", schedule.ScheduleID, "device_id", schedule.DeviceID, "error", dispatchErr)
+		if retryable(dispatchErr) {
+			// the run is given back, the next tick sends it again within the catch_up rule
+			if _, err := runner.Schedules.ReleaseRun(ctx, schedule.ScheduleID, claim); err != nil {
+				runner.Logger.Error("failed to release schedule run", "schedule_id", schedule.ScheduleID, "error", err)
+			}
+		}
+		return runner.Schedules.RecordRun(ctx, schedule.ScheduleID, cmd.RequestID, dispatchErr.Error())
+	}
+
+	runner.Logger.Info("scheduled command dispatched", "schedule_id", schedule.ScheduleID, "device_id", schedule.DeviceID, "request_id", cmd.RequestID, "missed", decision.Missed)
+	return runner.Schedules.RecordRun(ctx, schedule.ScheduleID, cmd.RequestID, "")
+}
+
+func (runner *Runner) dispatch(ctx context.Context, schedule models.Schedule) (models.Command, error) {
+	state, err := runner.StateStore.GetStateByID(ctx, schedule.DeviceID)
+	if err != nil {
+		return models.Command{}, err
+	}
+	if !devices.Active(state) {
+		return models.Command{}, fmt.Errorf("%w: %s", errDeviceInactive, schedule.DeviceID)
+	}
+	err = homes.AuthorizeCommand(ctx, runner.Homes, schedule.HomeID, schedule.CreatedBy, schedule.DeviceID, state.Type, schedule.Action)
+	if err != nil {
+		return models.Command{}, err
+	}
+
+	return runner.Dispatcher.Dispatch(ctx, commands.Request{
+		DeviceID:   schedule.DeviceID,
+		DeviceType: state.Type,
+		Action:     schedule.Action,
+		Parameters: schedule.Parameters,
+		IssuedBy:   "schedule:" + schedule.ScheduleID,
+	})
+}
+
+// a failed publish or store read is worth another try, an invalid command or a retired device is not
+func retryable(err error) bool {
+	var validationErr *devices.CommandValidationError
+	return !errors.As(err, &validationErr) && !errors.Is(err, errDeviceInactive) && !errors.Is(err, homes.ErrCommandRevoked)
+}
@@ -0,0 +1,202 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+type fakePublisher struct {
+	err    error
+	topics []string
+}
+
+func (publisher *fakePublisher) Publish(ctx context.Context, topic string, payload interface{}) error {
+	publisher.topics = append(publisher.topics, topic)
+	return publisher.err
+}
+
+type runnerFixture struct {
+	runner    *Runner
+	schedules *MemoryScheduleStore
+	homes     *homes.MemoryHomeStore
+	publisher *fakePublisher
+	schedule  models.Schedule
+}
+
+// a door lock in home-1 with a daily 22:00 LOCK saved by user-1
+func newRunnerFixture(t *testing.T, now time.Time) *runnerFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	states := devices.NewMemoryStateStore()
+	if err := states.RegisterDevice(ctx, models.DeviceState{DeviceID: "door-1", Type: "door-actuator", Status: devices.StatusProvisioned}); err != nil {
+		t.Fatal(err)
+	}
+
+	homeStore := homes.NewMemoryHomeStore()
+	if _, err := homeStore.ClaimDevice(ctx, models.HomeDevice{DeviceID: "door-1", HomeID: "home-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := homeStore.SaveMember(ctx, models.HomeMember{UserID: "user-1", HomeID: "home-1", Role: homes.RoleMember}); err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := models.Schedule{
+		ScheduleID: "sched-1",
+		DeviceID:   "door-1",
+		HomeID:     "home-1",
+		CreatedBy:  "user-1",
+		Action:     "LOCK",
+		Cron:       "0 22 * * *",
+		TimeZone:   "UTC",
+		CatchUp:    CatchUpSkip,
+		Enabled:    true,
+		NextRunAt:  now.Truncate(time.Hour).Unix(),
+	}
+	scheduleStore := NewMemoryScheduleStore()
+	if err := scheduleStore.SaveSchedule(ctx, schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := &fakePublisher{}
+	return &runnerFixture{
+		runner: &Runner{
+			Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Schedules:  scheduleStore,
+			StateStore: states,
+			Homes:      homeStore,
+			Dispatcher: &commands.Dispatcher{Publisher: publisher, Store: commands.NewMemoryCommandStore()},
+		},
+		schedules: scheduleStore,
+		homes:     homeStore,
+		publisher: publisher,
+		schedule:  schedule,
+	}
+}
+
+func (fixture *runnerFixture) stored(t *testing.T) models.Schedule {
+	t.Helper()
+	schedule, err := fixture.schedules.GetSchedule(context.Background(), fixture.schedule.ScheduleID)
+	if err != nil || schedule == nil {
+		t.Fatalf("GetSchedule: %v, %v", schedule, err)
+	}
+	return *schedule
+}
+
+var runnerNow = time.Date(2026, 5, 4, 22, 0, 20, 0, time.UTC)
+
+func TestRunDueDispatches(t *testing.T) {
+	fixture := newRunnerFixture(t, runnerNow)
+	if err := fixture.runner.RunDue(context.Background(), runnerNow); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fixture.publisher.topics) != 1 || fixture.publisher.topics[0] != "devices/door-1/command" {
+		t.Fatalf("published %v, want one command to door-1", fixture.publisher.topics)
+	}
+	schedule := fixture.stored(t)
+	if schedule.NextRunAt != time.Date(2026, 5, 5, 22, 0, 0, 0, time.UTC).Unix() || !schedule.Enabled {
+		t.Errorf("next run %d enabled %v, want tomorrow 22:00", schedule.NextRunAt, schedule.Enabled)
+	}
+	if schedule.LastRequestID == "" || schedule.LastError != "" {
+		t.Errorf("last request %q error %q, want a request and no error", schedule.LastRequestID, schedule.LastError)
+	}
+}
+
+func TestRunDueReleasesFailedPublish(t *testing.T) {
+	fixture := newRunnerFixture(t, runnerNow)
+	fixture.publisher.err = errors.New("broker unavailable")
+
+	if err := fixture.runner.RunDue(context.Background(), runnerNow); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run is given back, the next tick tries it again
+	schedule := fixture.stored(t)
+	if schedule.NextRunAt != fixture.schedule.NextRunAt || !schedule.Enabled || schedule.LastError == "" {
+		t.Fatalf("next run %d enabled %v error %q, want the run released with the error", schedule.NextRunAt, schedule.Enabled, schedule.LastError)
+	}
+
+	fixture.publisher.err = nil
+	if err := fixture.runner.RunDue(context.Background(), runnerNow.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(fixture.publisher.topics) != 2 {
+		t.Errorf("published %d times, want the retry to publish again", len(fixture.publisher.topics))
+	}
+	if schedule := fixture.stored(t); schedule.NextRunAt == fixture.schedule.NextRunAt || schedule.LastError != "" {
+		t.Errorf("after the retry next run %d error %q, want the run done", schedule.NextRunAt, schedule.LastError)
+	}
+}
+
+func TestRunDueDisablesRevokedSchedule(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(ctx context.Context, fixture *runnerFixture) error
+	}{
+		{"member removed", func(ctx context.Context, fixture *runnerFixture) error {
+			return fixture.homes.DeleteMember(ctx, "home-1", "user-1")
+		}},
+		{"permission taken", func(ctx context.Context, fixture *runnerFixture) error {
+			return fixture.homes.SaveMember(ctx, models.HomeMember{UserID: "user-1", HomeID: "home-1", Role: homes.RoleMember, Permissions: []string{}})
+		}},
+		{"device moved", func(ctx context.Context, fixture *runnerFixture) error {
+			if err := fixture.homes.ReleaseDevice(ctx, "home-1", "door-1"); err != nil {
+				return err
+			}
+			_, err := fixture.homes.ClaimDevice(ctx, models.HomeDevice{DeviceID: "door-1", HomeID: "home-2"})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			fixture := newRunnerFixture(t, runnerNow)
+			if err := test.revoke(ctx, fixture); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fixture.runner.RunDue(ctx, runnerNow); err != nil {
+				t.Fatal(err)
+			}
+			if len(fixture.publisher.topics) != 0 {
+				t.Errorf("published %v, want nothing sent", fixture.publisher.topics)
+			}
+			if schedule := fixture.stored(t); schedule.Enabled || schedule.LastError == "" {
+				t.Errorf("enabled %v error %q, want the schedule disabled with the reason", schedule.Enabled, schedule.LastError)
+			}
+		})
+	}
+}
+
+func TestRunDueKeepsInvalidCommandClaimed(t *testing.T) {
+	fixture := newRunnerFixture(t, runnerNow)
+	ctx := context.Background()
+	broken := fixture.schedule
+	broken.Action = "OPEN_WIDE"
+	if err := fixture.schedules.SaveSchedule(ctx, broken); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.homes.SaveMember(ctx, models.HomeMember{UserID: "user-1", HomeID: "home-1", Role: homes.RoleOwner}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fixture.runner.RunDue(ctx, runnerNow); err != nil {
+		t.Fatal(err)
+	}
+
+	// retrying would fail the same way, the run stays consumed
+	schedule := fixture.stored(t)
+	if schedule.NextRunAt == broken.NextRunAt || schedule.LastError == "" {
+		t.Errorf("next run %d error %q, want the run consumed with the error", schedule.NextRunAt, schedule.LastError)
+	}
+}
@@ -0,0 +1,148 @@
+package schedules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// what the runner does with runs it missed (runner down, lambda throttled...)
+const (
+	CatchUpSkip    = "SKIP"     // only run if the latest missed run is within GracePeriod
+	CatchUpRunOnce = "RUN_ONCE" // run once no matter how late, older missed runs are dropped
+
+	GracePeriod = 5 * time.Minute
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// fills defaults and checks the timing fields, then sets NextRunAt from now
+func Prepare(schedule *models.Schedule, now time.Time) error {
+	if (schedule.RunAt == 0) == (schedule.Cron == "") {
+		return fmt.Errorf("%w: exactly one of run_at or cron is required", ErrInvalidSchedule)
+	}
+
+	if schedule.TimeZone == "" {
+		schedule.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		return fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedule, schedule.TimeZone)
+	}
+
+	switch schedule.CatchUp {
+	case "":
+		schedule.CatchUp = CatchUpSkip
+	case CatchUpSkip, CatchUpRunOnce:
+	default:
+		return fmt.Errorf("%w: catch_up must be SKIP or RUN_ONCE", ErrInvalidSchedule)
+	}
+
+	if schedule.Cron != "" {
+		if _, err := ParseCron(schedule.Cron); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+	} else if schedule.RunAt <= now.Unix() {
+		return fmt.Errorf("%w: run_at must be in the future", ErrInvalidSchedule)
+	}
+
+	next, err := NextRun(*schedule, now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	schedule.NextRunAt = next
+
+	return nil
+}
+
+// first run strictly after `after`, 0 when a one-shot schedule has nothing left to run
+func NextRun(schedule models.Schedule, after time.Time) (int64, error) {
+	next, err := nextRunFunc(schedule)
+	if err != nil {
+		return 0, err
+	}
+	return next(after)
+}
+
+// parses the cron and zone once, Plan calls it in a loop
+func nextRunFunc(schedule models.Schedule) (func(after time.Time) (int64, error), error) {
+	if schedule.Cron == "" {
+		return func(after time.Time) (int64, error) {
+			if schedule.RunAt > after.Unix() {
+				return schedule.RunAt, nil
+			}
+			return 0, nil
+		}, nil
+	}
+
+	spec, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(after time.Time) (int64, error) {
+		next, err := spec.Next(after, loc)
+		if err != nil {
+			return 0, err
+		}
+		return next.Unix(), nil
+	}, nil
+}
+
+// Decision is what the runner should do with a due schedule
+type Decision struct {
+	Run       bool  // send the command now
+	Missed    int   // runs dropped by the catch-up rule
+	NextRunAt int64 // 0 = nothing left to run
+	Enabled   bool
+}
+
+// applies the catch-up rule to a schedule whose NextRunAt has passed
+func Plan(schedule models.Schedule, now time.Time) (Decision, error) {
+	if schedule.Cron == "" {
+		run := schedule.CatchUp == CatchUpRunOnce || now.Unix()-schedule.NextRunAt <= int64(GracePeriod.Seconds())
+		decision := Decision{Run: run}
+		if !run {
+			decision.Missed = 1
+		}
+		return decision, nil
+	}
+
+	nextRun, err := nextRunFunc(schedule)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// walk to the latest run that should already have happened
+	latest := schedule.NextRunAt
+	missed := 0
+	for i := 0; i < 10000; i++ {
+		next, err := nextRun(time.Unix(latest, 0))
+		if err != nil {
+			return Decision{}, err
+		}
+		if next > now.Unix() {
+			break
+		}
+		latest = next
+		missed++
+	}
+
+	next, err := nextRun(now)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	decision := Decision{NextRunAt: next, Enabled: true, Missed: missed}
+	if schedule.CatchUp == CatchUpRunOnce || now.Unix()-latest <= int64(GracePeriod.Seconds()) {
+		decision.Run = true
+	} else {
+		decision.Missed++
+	}
+	return decision, nil
+}
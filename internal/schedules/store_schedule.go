@@ -0,0 +1,287 @@
+package schedules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+const deviceIndex = "DeviceIndex"
+
+// Claim moves a due schedule to its next run, only one runner can claim a given run
+type Claim struct {
+	ExpectedNextRunAt int64
+	NextRunAt         int64
+	Enabled           bool
+	RunAt             int64
+}
+
+// ScheduleStore keeps scheduled device commands (keyed by schedule_id)
+type ScheduleStore interface {
+	SaveSchedule(ctx context.Context, schedule models.Schedule) error
+	GetSchedule(ctx context.Context, scheduleID string) (*models.Schedule, error)
+	GetSchedulesByDevice(ctx context.Context, deviceID string) ([]models.Schedule, error)
+	DeleteSchedule(ctx context.Context, scheduleID string) error
+	GetDueSchedules(ctx context.Context, now int64) ([]models.Schedule, error)
+	ClaimRun(ctx context.Context, scheduleID string, claim Claim) (bool, error)
+	ReleaseRun(ctx context.Context, scheduleID string, claim Claim) (bool, error)
+	RecordRun(ctx context.Context, scheduleID string, requestID string, runErr string) error
+	DisableSchedule(ctx context.Context, scheduleID string, reason string, at int64) error
+}
+
+type DynamoScheduleStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewScheduleStore() (*DynamoScheduleStore, error) {
+	tableName := os.Getenv("DYNAMODB_SCHEDULES_TABLE")
+	if tableName == "" {
+		return nil, fmt.Errorf("DYNAMODB_SCHEDULES_TABLE environment variable is not set")
+	}
+
+	if db.Client == nil {
+		return nil, fmt.Errorf("dynamodb client is not initialized")
+	}
+
+	return &DynamoScheduleStore{
+		Client:    db.Client,
+		TableName: tableName,
+	}, nil
+}
+
+func (store *DynamoScheduleStore) SaveSchedule(ctx context.Context, schedule models.Schedule) error {
+	item, err := attributevalue.MarshalMap(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule: %w", err)
+	}
+
+	_, err = store.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.TableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store schedule in dynamodb: %w", err)
+	}
+
+	return nil
+}
+
+func (store *DynamoScheduleStore) GetSchedule(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	result, err := store.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule %s: %w", scheduleID, err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var schedule models.Schedule
+	if err = attributevalue.UnmarshalMap(result.Item, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schedule %s: %w", scheduleID, err)
+	}
+
+	return &schedule, nil
+}
+
+// all schedules of a device from the DeviceIndex GSI (device_id + next_run_at)
+func (store *DynamoScheduleStore) GetSchedulesByDevice(ctx context.Context, deviceID string) ([]models.Schedule, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		IndexName:              aws.String(deviceIndex),
+		KeyConditionExpression: aws.String("device_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: deviceID},
+		},
+	}
+
+	scheduleList := []models.Schedule{}
+	paginator := dynamodb.NewQueryPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query schedules for device %s: %w", deviceID, err)
+		}
+
+		var items []models.Schedule
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schedules for device %s: %w", deviceID, err)
+		}
+		scheduleList = append(scheduleList, items...)
+	}
+
+	return scheduleList, nil
+}
+
+func (store *DynamoScheduleStore) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	_, err := store.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule %s: %w", scheduleID, err)
+	}
+	return nil
+}
+
+// enabled schedules whose next run has passed, the table is small so a scan is enough
+func (store *DynamoScheduleStore) GetDueSchedules(ctx context.Context, now int64) ([]models.Schedule, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(store.TableName),
+		FilterExpression: aws.String("#enabled = :enabled AND next_run_at <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#enabled": "enabled",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":enabled": &types.AttributeValueMemberBOOL{Value: true},
+			":now":     &types.AttributeValueMemberN{Value: fmt.Sprint(now)},
+		},
+	}
+
+	due := []models.Schedule{}
+	paginator := dynamodb.NewScanPaginator(store.Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan due schedules: %w", err)
+		}
+
+		var items []models.Schedule
+		if err = attributevalue.UnmarshalListOfMaps(page.Items, &items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal due schedules: %w", err)
+		}
+		due = append(due, items...)
+	}
+
+	return due, nil
+}
+
+// conditional on next_run_at so two overlapping runner invocations never send the same run twice
+func (store *DynamoScheduleStore) ClaimRun(ctx context.Context, scheduleID string, claim Claim) (bool, error) {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+		ConditionExpression: aws.String("next_run_at = :expected AND #enabled = :true"),
+		UpdateExpression:    aws.String("SET next_run_at = :next, #enabled = :enabled, last_run_at = :run_at, updated_at = :run_at"),
+		ExpressionAttributeNames: map[string]string{
+			"#enabled": "enabled",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expected": &types.AttributeValueMemberN{Value: fmt.Sprint(claim.ExpectedNextRunAt)},
+			":true":     &types.AttributeValueMemberBOOL{Value: true},
+			":next":     &types.AttributeValueMemberN{Value: fmt.Sprint(claim.NextRunAt)},
+			":enabled":  &types.AttributeValueMemberBOOL{Value: claim.Enabled},
+			":run_at":   &types.AttributeValueMemberN{Value: fmt.Sprint(claim.RunAt)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim schedule %s: %w", scheduleID, err)
+	}
+	return true, nil
+}
+
+// gives a claimed run back when its command could not be sent, the next runner invocation retries it.
+// only the claim itself is undone, not a schedule edited or claimed again since
+func (store *DynamoScheduleStore) ReleaseRun(ctx context.Context, scheduleID string, claim Claim) (bool, error) {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+		ConditionExpression: aws.String("next_run_at = :next AND #enabled = :enabled AND last_run_at = :run_at"),
+		UpdateExpression:    aws.String("SET next_run_at = :expected, #enabled = :true"),
+		ExpressionAttributeNames: map[string]string{
+			"#enabled": "enabled",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":next":     &types.AttributeValueMemberN{Value: fmt.Sprint(claim.NextRunAt)},
+			":enabled":  &types.AttributeValueMemberBOOL{Value: claim.Enabled},
+			":run_at":   &types.AttributeValueMemberN{Value: fmt.Sprint(claim.RunAt)},
+			":expected": &types.AttributeValueMemberN{Value: fmt.Sprint(claim.ExpectedNextRunAt)},
+			":true":     &types.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to release schedule %s: %w", scheduleID, err)
+	}
+	return true, nil
+}
+
+// stores the outcome of the last run, a schedule deleted in the meantime is left deleted
+func (store *DynamoScheduleStore) RecordRun(ctx context.Context, scheduleID string, requestID string, runErr string) error {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+		ConditionExpression: aws.String("attribute_exists(schedule_id)"),
+		UpdateExpression:    aws.String("SET last_request_id = :request_id, last_error = :error"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":request_id": &types.AttributeValueMemberS{Value: requestID},
+			":error":      &types.AttributeValueMemberS{Value: runErr},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to record run of schedule %s: %w", scheduleID, err)
+	}
+	return nil
+}
+
+// turns the schedule off with the reason as its last error, a schedule deleted in the meantime is left deleted
+func (store *DynamoScheduleStore) DisableSchedule(ctx context.Context, scheduleID string, reason string, at int64) error {
+	_, err := store.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(store.TableName),
+		Key: map[string]types.AttributeValue{
+			"schedule_id": &types.AttributeValueMemberS{Value: scheduleID},
+		},
+		ConditionExpression: aws.String("attribute_exists(schedule_id)"),
+		UpdateExpression:    aws.String("SET #enabled = :false, last_error = :error, updated_at = :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#enabled": "enabled",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":false": &types.AttributeValueMemberBOOL{Value: false},
+			":error": &types.AttributeValueMemberS{Value: reason},
+			":at":    &types.AttributeValueMemberN{Value: fmt.Sprint(at)},
+		},
+	})
+	if err != nil {
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to disable schedule %s: %w", scheduleID, err)
+	}
+	return nil
+}
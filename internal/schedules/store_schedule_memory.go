@@ -0,0 +1,148 @@
+package schedules
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MemoryScheduleStore is an in-process ScheduleStore used for tests and local demos
+type MemoryScheduleStore struct {
+	mu        sync.Mutex
+	schedules map[string]models.Schedule
+}
+
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{
+		schedules: make(map[string]models.Schedule),
+	}
+}
+
+func (store *MemoryScheduleStore) SaveSchedule(ctx context.Context, schedule models.Schedule) error {
+	schedule.Parameters = maps.Clone(schedule.Parameters)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.schedules[schedule.ScheduleID] = schedule
+	return nil
+}
+
+func (store *MemoryScheduleStore) GetSchedule(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[scheduleID]
+	if !exists {
+		return nil, nil
+	}
+	schedule.Parameters = maps.Clone(schedule.Parameters)
+	return &schedule, nil
+}
+
+func (store *MemoryScheduleStore) GetSchedulesByDevice(ctx context.Context, deviceID string) ([]models.Schedule, error) {
+	return store.collect(func(schedule models.Schedule) bool {
+		return schedule.DeviceID == deviceID
+	}), nil
+}
+
+func (store *MemoryScheduleStore) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.schedules, scheduleID)
+	return nil
+}
+
+func (store *MemoryScheduleStore) GetDueSchedules(ctx context.Context, now int64) ([]models.Schedule, error) {
+	return store.collect(func(schedule models.Schedule) bool {
+		return schedule.Enabled && schedule.NextRunAt <= now
+	}), nil
+}
+
+func (store *MemoryScheduleStore) ClaimRun(ctx context.Context, scheduleID string, claim Claim) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[scheduleID]
+	if !exists || !schedule.Enabled || schedule.NextRunAt != claim.ExpectedNextRunAt {
+		return false, nil
+	}
+
+	schedule.NextRunAt = claim.NextRunAt
+	schedule.Enabled = claim.Enabled
+	schedule.LastRunAt = claim.RunAt
+	schedule.UpdatedAt = claim.RunAt
+	store.schedules[scheduleID] = schedule
+	return true, nil
+}
+
+func (store *MemoryScheduleStore) ReleaseRun(ctx context.Context, scheduleID string, claim Claim) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[scheduleID]
+	if !exists || schedule.NextRunAt != claim.NextRunAt || schedule.Enabled != claim.Enabled || schedule.LastRunAt != claim.RunAt {
+		return false, nil
+	}
+
+	schedule.NextRunAt = claim.ExpectedNextRunAt
+	schedule.Enabled = true
+	store.schedules[scheduleID] = schedule
+	return true, nil
+}
+
+func (store *MemoryScheduleStore) RecordRun(ctx context.Context, scheduleID string, requestID string, runErr string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[scheduleID]
+	if !exists {
+		return nil
+	}
+
+	schedule.LastRequestID = requestID
+	schedule.LastError = runErr
+	store.schedules[scheduleID] = schedule
+	return nil
+}
+
+func (store *MemoryScheduleStore) DisableSchedule(ctx context.Context, scheduleID string, reason string, at int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[scheduleID]
+	if !exists {
+		return nil
+	}
+
+	schedule.Enabled = false
+	schedule.LastError = reason
+	schedule.UpdatedAt = at
+	store.schedules[scheduleID] = schedule
+	return nil
+}
+
+// matching schedules ordered by next run, like the DeviceIndex sort key
+func (store *MemoryScheduleStore) collect(match func(models.Schedule) bool) []models.Schedule {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	scheduleList := make([]models.Schedule, 0)
+	for _, schedule := range store.schedules {
+		if !match(schedule) {
+			continue
+		}
+		schedule.Parameters = maps.Clone(schedule.Parameters)
+		scheduleList = append(scheduleList, schedule)
+	}
+
+	slices.SortFunc(scheduleList, func(a, b models.Schedule) int {
+		return cmp.Compare(a.NextRunAt, b.NextRunAt)
+	})
+	return scheduleList
+}
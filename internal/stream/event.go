@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+const (
+	EventDeviceState = "device_state"
+	EventAlert       = "alert"
+	EventCommand     = "command"
+)
+
+var eventTypes = []string{EventDeviceState, EventAlert, EventCommand}
+
+// Event is one change pushed to the stream subscribers, Data is the full record (device state, alert, command)
+type Event struct {
+	ID        uint64      `json:"id,omitempty"` // set by the hub, increases per api instance
+	Type      string      `json:"type"`
+	DeviceID  string      `json:"device_id"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Publisher hands events to whatever fans them out, the Hub in the api or a Relay in the lambdas
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Filter selects the events a subscriber gets. nil Devices matches every device,
+// an empty Types matches every type
+type Filter struct {
+	Devices map[string]bool
+	Types   []string
+}
+
+func (filter Filter) Matches(event Event) bool {
+	if filter.Devices != nil && !filter.Devices[event.DeviceID] {
+		return false
+	}
+	if len(filter.Types) > 0 && !slices.Contains(filter.Types, event.Type) {
+		return false
+	}
+	return true
+}
+
+func IsEventType(eventType string) bool {
+	return slices.Contains(eventTypes, eventType)
+}
+
+// splits a comma separated query value ("a,b"), dropping blanks
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
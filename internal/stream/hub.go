@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"context"
+	"sync"
+)
+
+const subscriberBuffer = 64
+
+// Subscription receives the matching events on Events until the hub drops it or it unsubscribes.
+// Events is closed when a slow subscriber falls a full buffer behind, clients then reconnect
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	filter Filter
+}
+
+// Hub fans events out to the stream subscribers of one api instance
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (hub *Hub) Subscribe(filter Filter) *Subscription {
+	events := make(chan Event, subscriberBuffer)
+	subscription := &Subscription{Events: events, events: events, filter: filter}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+func (hub *Hub) Unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.remove(subscription)
+}
+
+// never blocks on a subscriber, a full buffer drops that subscriber instead of stalling ingestion
+func (hub *Hub) Publish(ctx context.Context, event Event) error {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.lastID++
+	event.ID = hub.lastID
+
+	for subscription := range hub.subscribers {
+		if !subscription.filter.Matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			hub.remove(subscription)
+		}
+	}
+	return nil
+}
+
+func (hub *Hub) Subscribers() int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	return len(hub.subscribers)
+}
+
+// callers hold mu
+func (hub *Hub) remove(subscription *Subscription) {
+	if _, exists := hub.subscribers[subscription]; !exists {
+		return
+	}
+	delete(hub.subscribers, subscription)
+	close(subscription.events)
+}
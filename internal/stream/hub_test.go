@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHubFilter(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub()
+	all := hub.Subscribe(Filter{})
+	gasAlerts := hub.Subscribe(Filter{Devices: map[string]bool{"gas-1": true}, Types: []string{EventAlert}})
+
+	events := []Event{
+		{Type: EventAlert, DeviceID: "gas-1"},
+		{Type: EventDeviceState, DeviceID: "gas-1"},
+		{Type: EventAlert, DeviceID: "door-1"},
+	}
+	for _, event := range events {
+		if err := hub.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(all.Events) != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3", len(all.Events))
+	}
+	if len(gasAlerts.Events) != 1 {
+		t.Fatalf("filtered subscriber got %d events, want 1", len(gasAlerts.Events))
+	}
+	if event := <-gasAlerts.Events; event.ID != 1 || event.DeviceID != "gas-1" || event.Type != EventAlert {
+		t.Errorf("filtered subscriber got %+v, want the first gas-1 alert", event)
+	}
+	var last uint64
+	for range 3 {
+		event := <-all.Events
+		if event.ID <= last {
+			t.Errorf("event id %d after %d, want increasing ids", event.ID, last)
+		}
+		last = event.ID
+	}
+}
+
+// a subscriber a full buffer behind is dropped, the others keep getting events
+func TestHubSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub()
+	slow := hub.Subscribe(Filter{})
+	fast := hub.Subscribe(Filter{})
+
+	for range subscriberBuffer + 1 {
+		if err := hub.Publish(ctx, Event{Type: EventDeviceState, DeviceID: "gas-1"}); err != nil {
+			t.Fatal(err)
+		}
+		<-fast.Events
+	}
+
+	if hub.Subscribers() != 1 {
+		t.Errorf("%d subscribers, want the slow one dropped", hub.Subscribers())
+	}
+	received := 0
+	for range slow.Events {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("slow subscriber drained %d events before the close, want %d", received, subscriberBuffer)
+	}
+
+	hub.Unsubscribe(fast)
+	hub.Unsubscribe(fast)
+	if _, open := <-fast.Events; open || hub.Subscribers() != 0 {
+		t.Errorf("after unsubscribe: open=%v, %d subscribers", open, hub.Subscribers())
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte(`{"type":"alert","device_id":"gas-1"}`)
+	signature := Sign("secret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"signed body", "secret", body, signature, true},
+		{"other secret", "other", body, signature, false},
+		{"changed body", "secret", []byte(`{"type":"alert","device_id":"gas-2"}`), signature, false},
+		{"missing signature", "secret", body, "", false},
+	}
+	for _, test := range tests {
+		if got := ValidSignature(test.secret, test.body, test.signature); got != test.want {
+			t.Errorf("%s: ValidSignature = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
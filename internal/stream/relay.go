@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const SignatureHeader = "X-Fleexa-Signature"
+
+// Relay forwards the events of a lambda (ingestion, sweeper, schedule runner) to the api,
+// which publishes them to its hub
+type Relay struct {
+	Client *http.Client
+	URL    string
+	Secret string
+}
+
+// returns nil, nil when STREAM_RELAY_URL is not set, the lambda then streams nothing
+func NewRelayFromEnv() (*Relay, error) {
+	url := os.Getenv("STREAM_RELAY_URL")
+	if url == "" {
+		return nil, nil
+	}
+
+	secret := os.Getenv("STREAM_RELAY_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("STREAM_RELAY_SECRET environment variable is not set")
+	}
+
+	return &Relay{
+		Client: &http.Client{Timeout: 2 * time.Second},
+		URL:    url,
+		Secret: secret,
+	}, nil
+}
+
+func (relay *Relay) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, relay.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build relay request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(relay.Secret, body))
+
+	res, err := relay.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("relay request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("relay returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// HMAC-SHA256 of the body, "sha256=<hex>"
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
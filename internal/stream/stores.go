@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/alerts"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
+	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// the decorators below publish every change their store makes, so each write path
+// (ingestion, sweeper, runners, api) streams without knowing about the hub.
+// a failed publish is logged, the write itself already succeeded
+
+// PublishingStateStore streams the device state after each change
+type PublishingStateStore struct {
+	devices.StateStore
+	Logger    *slog.Logger
+	Publisher Publisher
+}
+
+func (store *PublishingStateStore) UpdateFromTelemetry(ctx context.Context, tel models.Telemetry) error {
+	if err := store.StateStore.UpdateFromTelemetry(ctx, tel); err != nil {
+		return err
+	}
+	store.publishState(ctx, tel.DeviceID)
+	return nil
+}
+
+// a heartbeat brings an offline device back ONLINE, subscribers see the presence change
+func (store *PublishingStateStore) UpdateHeartbeat(ctx context.Context, deviceID string) error {
+	if err := store.StateStore.UpdateHeartbeat(ctx, deviceID); err != nil {
+		return err
+	}
+	store.publishState(ctx, deviceID)
+	return nil
+}
+
+func (store *PublishingStateStore) MarkOffline(ctx context.Context, deviceID string, lastSeenAt int64, at int64) (bool, error) {
+	marked, err := store.StateStore.MarkOffline(ctx, deviceID, lastSeenAt, at)
+	if marked {
+		store.publishState(ctx, deviceID)
+	}
+	return marked, err
+}
+
+func (store *PublishingStateStore) MarkRecovered(ctx context.Context, deviceID string, offlineSince int64) (bool, error) {
+	marked, err := store.StateStore.MarkRecovered(ctx, deviceID, offlineSince)
+	if marked {
+		store.publishState(ctx, deviceID)
+	}
+	return marked, err
+}
+
+// the update expressions don't return the item, so the merged state is read back
+func (store *PublishingStateStore) publishState(ctx context.Context, deviceID string) {
+	state, err := store.StateStore.GetStateByID(ctx, deviceID)
+	if err != nil || state == nil {
+		store.Logger.Warn("failed to read device state to stream", "device_id", deviceID, "error", err)
+		return
+	}
+	publish(ctx, store.Logger, store.Publisher, Event{
+		Type:      EventDeviceState,
+		DeviceID:  deviceID,
+		Timestamp: time.Now().Unix(),
+		Data:      state,
+	})
+}
+
+// PublishingAlertStore streams new alerts and their status changes
+type PublishingAlertStore struct {
+	alerts.AlertStore
+	Logger    *slog.Logger
+	Publisher Publisher
+}
+
+func (store *PublishingAlertStore) SaveAlert(ctx context.Context, alert models.Alert) error {
+	if err := store.AlertStore.SaveAlert(ctx, alert); err != nil {
+		return err
+	}
+	alert.Status = alerts.StatusOf(alert)
+	store.publishAlert(ctx, &alert)
+	return nil
+}
+
+func (store *PublishingAlertStore) AcknowledgeAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	alert, err := store.AlertStore.AcknowledgeAlert(ctx, deviceID, timestamp, by, at)
+	if err == nil {
+		store.publishAlert(ctx, alert)
+	}
+	return alert, err
+}
+
+func (store *PublishingAlertStore) ResolveAlert(ctx context.Context, deviceID string, timestamp int64, by string, at int64) (*models.Alert, error) {
+	alert, err := store.AlertStore.ResolveAlert(ctx, deviceID, timestamp, by, at)
+	if err == nil {
+		store.publishAlert(ctx, alert)
+	}
+	return alert, err
+}
+
+func (store *PublishingAlertStore) publishAlert(ctx context.Context, alert *models.Alert) {
+	if alert == nil {
+		return
+	}
+	publish(ctx, store.Logger, store.Publisher, Event{
+		Type:      EventAlert,
+		DeviceID:  alert.DeviceID,
+		Timestamp: time.Now().Unix(),
+		Data:      alert,
+	})
+}
+
+// PublishingCommandStore streams commands when they are sent and on every ack/result
+type PublishingCommandStore struct {
+	commands.CommandStore
+	Logger    *slog.Logger
+	Publisher Publisher
+}
+
+func (store *PublishingCommandStore) SaveCommand(ctx context.Context, cmd models.Command) error {
+	if err := store.CommandStore.SaveCommand(ctx, cmd); err != nil {
+		return err
+	}
+	store.publishCommand(ctx, &cmd)
+	return nil
+}
+
+func (store *PublishingCommandStore) UpdateCommandStatus(ctx context.Context, requestID string, deviceID string, update commands.StatusUpdate) error {
+	if err := store.CommandStore.UpdateCommandStatus(ctx, requestID, deviceID, update); err != nil {
+		return err
+	}
+
+	cmd, err := store.CommandStore.GetCommand(ctx, requestID)
+	if err != nil || cmd == nil {
+		store.Logger.Warn("failed to read command to stream", "request_id", requestID, "error", err)
+		return nil
+	}
+	store.publishCommand(ctx, cmd)
+	return nil
+}
+
+func (store *PublishingCommandStore) publishCommand(ctx context.Context, cmd *models.Command) {
+	publish(ctx, store.Logger, store.Publisher, Event{
+		Type:      EventCommand,
+		DeviceID:  cmd.DeviceID,
+		Timestamp: time.Now().Unix(),
+		Data:      cmd,
+	})
+}
+
+func publish(ctx context.Context, logger *slog.Logger, publisher Publisher, event Event) {
+	if err := publisher.Publish(ctx, event); err != nil {
+		logger.Warn("failed to publish stream event", "type", event.Type, "device_id", event.DeviceID, "error", err)
+	}
+}
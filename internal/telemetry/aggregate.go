@@ -0,0 +1,172 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// MaxBuckets caps one aggregation, a day of 1m buckets
+const MaxBuckets = 1440
+
+var ErrInvalidBucket = errors.New("bucket must be 1m, 5m, 1h or 1d")
+
+// ReadRange reads every reading of a device holding metric between from and to (inclusive), oldest first
+func ReadRange(ctx context.Context, store TelemetryStore, deviceID string, metric string, from int64, to int64) ([]models.Telemetry, error) {
+	query := HistoryQuery{
+		DeviceID:  deviceID,
+		From:      from,
+		To:        to,
+		Metrics:   []string{metric},
+		Ascending: true,
+		Limit:     maxQueryLimit,
+	}
+
+	var readings []models.Telemetry
+	for {
+		page, err := store.QueryTelemetry(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, page.Readings...)
+		if page.NextCursor == "" {
+			return readings, nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+var bucketSizes = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+func ParseBucket(raw string) (time.Duration, error) {
+	size, exists := bucketSizes[raw]
+	if !exists {
+		return 0, ErrInvalidBucket
+	}
+	return size, nil
+}
+
+// Bucket holds the readings of a metric from Timestamp for one bucket size,
+// everything but Count is null when there were none
+type Bucket struct {
+	Timestamp int64    `json:"timestamp"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+	Avg       *float64 `json:"avg"`
+	Count     int      `json:"count"`
+	Last      *float64 `json:"last"`
+
+	lastAt int64
+	sum    float64
+}
+
+// Sum of the readings, for ON/OFF metrics how many were ON
+func (bucket Bucket) Sum() float64 {
+	return bucket.sum
+}
+
+// BucketStart is the start of the bucket holding ts. buckets line up on the wall clock of
+// location: 1d buckets start at local midnight and last 23 or 25 hours across a DST change
+func BucketStart(ts int64, size time.Duration, location *time.Location) int64 {
+	local := time.Unix(ts, 0).In(location)
+	if size >= 24*time.Hour {
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location).Unix()
+	}
+
+	step := int64(size.Seconds())
+	_, offset := local.Zone()
+	wall := ts + int64(offset)
+	return ts - ((wall%step)+step)%step
+}
+
+func nextBucket(start int64, size time.Duration, location *time.Location) int64 {
+	if size >= 24*time.Hour {
+		local := time.Unix(start, 0).In(location)
+		return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, location).Unix()
+	}
+	return start + int64(size.Seconds())
+}
+
+// ReadingValue turns a payload value into a number: numbers as they are,
+// ON/OFF and booleans as 1/0. anything else doesn't count
+func ReadingValue(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	case bool:
+		if typed {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		switch typed {
+		case "ON":
+			return 1, true
+		case "OFF":
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// Aggregate buckets the readings of metric between from and to (inclusive unix seconds),
+// oldest first. every bucket of the range is there, the empty ones with nulls. history
+// can be in any order and hold readings outside the range
+func Aggregate(history []models.Telemetry, metric string, from int64, to int64, size time.Duration, location *time.Location) []Bucket {
+	if to < from {
+		return []Bucket{}
+	}
+
+	buckets := make([]Bucket, 0)
+	index := make(map[int64]int)
+	for start := BucketStart(from, size, location); start <= to; start = nextBucket(start, size, location) {
+		index[start] = len(buckets)
+		buckets = append(buckets, Bucket{Timestamp: start})
+	}
+
+	for _, record := range history {
+		if record.Timestamp < from || record.Timestamp > to {
+			continue
+		}
+		value, ok := ReadingValue(record.Payload[metric])
+		if !ok {
+			continue
+		}
+
+		i, exists := index[BucketStart(record.Timestamp, size, location)]
+		if !exists {
+			continue
+		}
+		bucket := &buckets[i]
+		if bucket.Count == 0 || value < *bucket.Min {
+			bucket.Min = &value
+		}
+		if bucket.Count == 0 || value > *bucket.Max {
+			bucket.Max = &value
+		}
+		if bucket.Count == 0 || record.Timestamp >= bucket.lastAt {
+			bucket.Last = &value
+			bucket.lastAt = record.Timestamp
+		}
+		bucket.sum += value
+		bucket.Count++
+	}
+
+	for i := range buckets {
+		if buckets[i].Count > 0 {
+			average := math.Round(buckets[i].sum/float64(buckets[i].Count)*100) / 100
+			buckets[i].Avg = &average
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,219 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+func reading(ts time.Time, metric string, value interface{}) models.Telemetry {
+	return models.Telemetry{DeviceID: "dev-1", Timestamp: ts.Unix(), Payload: map[string]interface{}{metric: value}}
+}
+
+func TestBucketStart(t *testing.T) {
+	tests := []struct {
+		name string
+		ts   time.Time
+		size time.Duration
+		want time.Time
+	}{
+		{"1m", time.Date(2026, 5, 4, 10, 17, 42, 0, time.UTC), time.Minute, time.Date(2026, 5, 4, 10, 17, 0, 0, time.UTC)},
+		{"5m", time.Date(2026, 5, 4, 10, 17, 42, 0, time.UTC), 5 * time.Minute, time.Date(2026, 5, 4, 10, 15, 0, 0, time.UTC)},
+		{"1h", time.Date(2026, 5, 4, 10, 17, 42, 0, time.UTC), time.Hour, time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)},
+		{"1d", time.Date(2026, 5, 4, 23, 59, 59, 0, time.UTC), 24 * time.Hour, time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)},
+		{"on the boundary", time.Date(2026, 5, 4, 10, 15, 0, 0, time.UTC), 5 * time.Minute, time.Date(2026, 5, 4, 10, 15, 0, 0, time.UTC)},
+		{"before 1970", time.Date(1969, 12, 31, 23, 58, 0, 0, time.UTC), 5 * time.Minute, time.Date(1969, 12, 31, 23, 55, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := BucketStart(test.ts.Unix(), test.size, time.UTC); got != test.want.Unix() {
+				t.Errorf("BucketStart(%s) = %s, want %s", test.ts, time.Unix(got, 0).UTC(), test.want)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	base := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	history := []models.Telemetry{
+		reading(base.Add(50*time.Second), "temp", 24.0), // out of order on purpose
+		reading(base.Add(10*time.Second), "temp", 20.0),
+		reading(base.Add(30*time.Second), "temp", 22.5),
+		reading(base.Add(2*time.Minute+5*time.Second), "temp", 19.0),
+		reading(base.Add(40*time.Second), "humidity", 55.0),
+		reading(base.Add(20*time.Second), "temp", "broken"),
+		reading(base.Add(-time.Minute), "temp", 99.0), // before from
+	}
+
+	buckets := Aggregate(history, "temp", base.Unix(), base.Add(3*time.Minute-time.Second).Unix(), time.Minute, time.UTC)
+	if len(buckets) != 3 {
+		t.Fatalf("got %d buckets, want 3", len(buckets))
+	}
+
+	first := buckets[0]
+	if first.Timestamp != base.Unix() || first.Count != 3 || *first.Min != 20 || *first.Max != 24 || *first.Avg != 22.17 || *first.Last != 24 {
+		t.Errorf("first bucket = %+v", first)
+	}
+	if first.Sum() != 66.5 {
+		t.Errorf("first bucket sum = %v, want 66.5", first.Sum())
+	}
+
+	empty := buckets[1]
+	if empty.Count != 0 || empty.Min != nil || empty.Max != nil || empty.Avg != nil || empty.Last != nil {
+		t.Errorf("empty bucket = %+v, want nulls", empty)
+	}
+	if buckets[2].Count != 1 || *buckets[2].Avg != 19 {
+		t.Errorf("last bucket = %+v", buckets[2])
+	}
+}
+
+func TestAggregateOnOff(t *testing.T) {
+	base := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	history := []models.Telemetry{
+		reading(base, "power", "ON"),
+		reading(base.Add(time.Minute), "power", "OFF"),
+		reading(base.Add(2*time.Minute), "power", true),
+		reading(base.Add(3*time.Minute), "power", "STANDBY"),
+	}
+
+	buckets := Aggregate(history, "power", base.Unix(), base.Add(time.Hour-time.Second).Unix(), time.Hour, time.UTC)
+	if len(buckets) != 1 || buckets[0].Count != 3 || buckets[0].Sum() != 2 {
+		t.Errorf("got %+v, want 3 readings with 2 ON", buckets)
+	}
+}
+
+// buckets line up on the wall clock of the home, not on UTC
+func TestBucketStartInZone(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	kolkata := mustLocation(t, "Asia/Kolkata")
+
+	tests := []struct {
+		name     string
+		ts       time.Time
+		size     time.Duration
+		location *time.Location
+		want     time.Time
+	}{
+		{"1h on a half hour zone", time.Date(2026, 5, 4, 10, 17, 0, 0, kolkata), time.Hour, kolkata, time.Date(2026, 5, 4, 10, 0, 0, 0, kolkata)},
+		{"1d local midnight", time.Date(2026, 5, 4, 23, 59, 0, 0, kolkata), 24 * time.Hour, kolkata, time.Date(2026, 5, 4, 0, 0, 0, 0, kolkata)},
+		{"1d on the DST start day", time.Date(2026, 3, 8, 22, 0, 0, 0, newYork), 24 * time.Hour, newYork, time.Date(2026, 3, 8, 0, 0, 0, 0, newYork)},
+		{"1h after the DST start", time.Date(2026, 3, 8, 3, 40, 0, 0, newYork), time.Hour, newYork, time.Date(2026, 3, 8, 3, 0, 0, 0, newYork)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := BucketStart(test.ts.Unix(), test.size, test.location)
+			if got != test.want.Unix() {
+				t.Errorf("BucketStart(%s) = %s, want %s", test.ts, time.Unix(got, 0).In(test.location), test.want)
+			}
+		})
+	}
+}
+
+// 2026-03-08 has 23 hours in New York, 2026-11-01 has 25
+func TestAggregateDailyAcrossDST(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	tests := []struct {
+		name  string
+		day   time.Time
+		hours []int
+	}{
+		{"clocks forward", time.Date(2026, 3, 7, 0, 0, 0, 0, newYork), []int{24, 23, 24}},
+		{"clocks back", time.Date(2026, 10, 31, 0, 0, 0, 0, newYork), []int{24, 25, 24}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from := test.day
+			to := test.day.AddDate(0, 0, 3).Add(-time.Second)
+
+			// a reading at 23:30 local every day, it must land in its own day
+			history := make([]models.Telemetry, 0)
+			for i := range 3 {
+				day := test.day.AddDate(0, 0, i)
+				history = append(history, reading(time.Date(day.Year(), day.Month(), day.Day(), 23, 30, 0, 0, newYork), "temp", float64(i)))
+			}
+
+			buckets := Aggregate(history, "temp", from.Unix(), to.Unix(), 24*time.Hour, newYork)
+			if len(buckets) != 3 {
+				t.Fatalf("got %d buckets, want 3", len(buckets))
+			}
+			for i, bucket := range buckets {
+				start := time.Unix(bucket.Timestamp, 0).In(newYork)
+				if start.Hour() != 0 || start.Minute() != 0 || start.Day() != test.day.AddDate(0, 0, i).Day() {
+					t.Errorf("bucket %d starts at %s, want local midnight", i, start)
+				}
+				end := to.Unix() + 1
+				if i+1 < len(buckets) {
+					end = buckets[i+1].Timestamp
+				}
+				if hours := int((end - bucket.Timestamp) / 3600); hours != test.hours[i] {
+					t.Errorf("bucket %d lasts %dh, want %dh", i, hours, test.hours[i])
+				}
+				if bucket.Count != 1 || *bucket.Last != float64(i) {
+					t.Errorf("bucket %d = %+v, want the reading of its own day", i, bucket)
+				}
+			}
+		})
+	}
+}
+
+// the repeated 01:00 hour of 2026-11-01 is two buckets, one per offset
+func TestAggregateHourlyRepeatedHour(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	from := time.Date(2026, 11, 1, 0, 0, 0, 0, newYork)
+	to := from.Add(5*time.Hour - time.Second)
+
+	firstOne := time.Date(2026, 11, 1, 5, 15, 0, 0, time.UTC)  // 01:15 EDT
+	secondOne := time.Date(2026, 11, 1, 6, 15, 0, 0, time.UTC) // 01:15 EST
+	history := []models.Telemetry{reading(firstOne, "temp", 1.0), reading(secondOne, "temp", 2.0)}
+
+	buckets := Aggregate(history, "temp", from.Unix(), to.Unix(), time.Hour, newYork)
+	if len(buckets) != 5 {
+		t.Fatalf("got %d buckets, want 5 for 00, 01 EDT, 01 EST, 02 and 03", len(buckets))
+	}
+	for i, bucket := range buckets {
+		if want := from.Unix() + int64(i)*3600; bucket.Timestamp != want {
+			t.Errorf("bucket %d starts at %d, want %d", i, bucket.Timestamp, want)
+		}
+	}
+	if buckets[1].Count != 1 || *buckets[1].Last != 1 || buckets[2].Count != 1 || *buckets[2].Last != 2 {
+		t.Errorf("repeated hour buckets = %+v and %+v, want one reading each", buckets[1], buckets[2])
+	}
+}
+
+// more readings than a page, bounded on both sides
+func TestReadRange(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTelemetryStore()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var history []models.Telemetry
+	for i := range 2500 {
+		history = append(history, reading(base.Add(time.Duration(i)*time.Second), "temp", float64(i)))
+	}
+	history[150] = reading(base.Add(150*time.Second), "humidity", 40.0) // without the metric
+	if err := store.SaveTelemetryBatch(ctx, history); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := base.Add(100*time.Second).Unix(), base.Add(2199*time.Second).Unix()
+	readings, err := ReadRange(ctx, store, "dev-1", "temp", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2099 {
+		t.Fatalf("read %d readings, want 2099", len(readings))
+	}
+	if readings[0].Timestamp != from || readings[len(readings)-1].Timestamp != to {
+		t.Errorf("read %d to %d, want %d to %d", readings[0].Timestamp, readings[len(readings)-1].Timestamp, from, to)
+	}
+}
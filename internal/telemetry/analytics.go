@@ -14,8 +14,9 @@ import (
 )
 
 type ChartPoint struct {
-	Label string  `json:"label"` // x-axis
-	Value float64 `json:"value"` // y-axis
+	Label     string  `json:"label"`               // x-axis
+	Timestamp int64   `json:"timestamp,omitempty"` // start of the bucket, labels repeat across days and weeks
+	Value     float64 `json:"value"`               // y-axis
 }
 
 // temp min max avg state
@@ -53,67 +54,56 @@ func GetTimeFormat(period string) string {
 	}
 }
 
-func FilterTime(history []models.Telemetry, metric string, period string, now int64) []ChartPoint {
-    cutoff := PeriodCutoff(now, period)
-	timeFormat := GetTimeFormat(period)
-
-    var mapCapacity int
-    switch period {
-    case "24h":
-        mapCapacity = 24
-    case "7d":
-        mapCapacity = 7
-    case "1m":
-        mapCapacity = 30
-    default:
-        mapCapacity = 30
-    }
-
-    groupedData := make(map[string]float64, mapCapacity)
-    countMap := make(map[string]int, mapCapacity)
-
-    for _, record := range history {
-        if cutoff > 0 && record.Timestamp < cutoff {
-            break 
-        }
-
-        if val, exists := record.Payload[metric]; exists {
-            recordTime := time.Unix(record.Timestamp, 0)
-            timeLabel := recordTime.Format(timeFormat)
+// the bucket size behind the chart of each period
+func PeriodBucket(period string) time.Duration {
+	switch period {
+	case "1h":
+		return 5 * time.Minute
+	case "24h":
+		return time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
 
-            if strVal, ok := val.(string); ok && strVal == "ON" {
-                groupedData[timeLabel] += 0.083
-            }
+// FilterTime charts the average of metric per bucket of the period (PeriodBucket), oldest first,
+// buckets and labels in location. for on/off metrics every ON report counts as 5 minutes (0.083 h) of run time instead
+func FilterTime(history []models.Telemetry, metric string, period string, now int64, location *time.Location) []ChartPoint {
+    cutoff := chartStart(history, period, now)
+	timeFormat := GetTimeFormat(period)
+    onOff := isOnOffMetric(history, metric)
 
-            if floatVal, ok := val.(float64); ok {
-                groupedData[timeLabel] += floatVal
-                countMap[timeLabel]++
-            } else if intVal, ok := val.(int); ok {
-                groupedData[timeLabel] += float64(intVal)
-                countMap[timeLabel]++
-            }
+    chartResult := make([]ChartPoint, 0)
+    for _, bucket := range Aggregate(history, metric, cutoff, now, PeriodBucket(period), location) {
+        if bucket.Count == 0 {
+            continue
         }
-    }
 
-    chartResult := make([]ChartPoint, 0, len(groupedData))
-    for label, total := range groupedData {
-        finalValue := total
-        if count, wasSensor := countMap[label]; wasSensor && count > 0 {
-            finalValue = total / float64(count)
+        value := *bucket.Avg
+        if onOff {
+            value = bucket.Sum() * 0.083
         }
         chartResult = append(chartResult, ChartPoint{
-            Label: label,
-            Value: math.Round(finalValue*10) / 10,
+            Label:     time.Unix(bucket.Timestamp, 0).In(location).Format(timeFormat),
+            Timestamp: bucket.Timestamp,
+            Value:     math.Round(value*10) / 10,
         })
     }
 
-    slices.SortFunc(chartResult, func(a, b ChartPoint) int {
-        return cmp.Compare(a.Label, b.Label)
-    })
-
     return chartResult
 }
 
+// whether metric is reported as ON/OFF, judged by its newest reading
+func isOnOffMetric(history []models.Telemetry, metric string) bool {
+    for _, record := range history {
+        if value, exists := record.Payload[metric]; exists {
+            _, isString := value.(string)
+            return isString
+        }
+    }
+    return false
+}
+
 func TimeAgo(ts int64, now int64) string {
 	diff := now - ts
 	if diff < 60 {
@@ -240,7 +230,8 @@ func CalculateAvgUnlock(history []models.Telemetry, now int64) float64 {
 	return math.Round(avgMinutes*10) / 10
 }
 
-func FormatDoorEvents(history []models.Telemetry) []map[string]interface{} {
+// times are given in location
+func FormatDoorEvents(history []models.Telemetry, location *time.Location) []map[string]interface{} {
 	formatted := make([]map[string]interface{}, 0, len(history))
 	
 	for _, record := range history {
@@ -258,7 +249,7 @@ func FormatDoorEvents(history []models.Telemetry) []map[string]interface{} {
 		}
 
 		// Format the time string (e.g., "8:49 PM")
-		t := time.Unix(record.Timestamp, 0)
+		t := time.Unix(record.Timestamp, 0).In(location)
 		timeStr := t.Format("3:04 PM")
 
 		formatted = append(formatted, map[string]interface{}{
@@ -270,7 +261,7 @@ func FormatDoorEvents(history []models.Telemetry) []map[string]interface{} {
 	return formatted
 }
 
-func FormatACEvents(history []models.Telemetry) []map[string]interface{} {
+func FormatACEvents(history []models.Telemetry, location *time.Location) []map[string]interface{} {
 	formatted := make([]map[string]interface{}, 0, len(history))
 	
 	for _, record := range history {
@@ -281,7 +272,7 @@ func FormatACEvents(history []models.Telemetry) []map[string]interface{} {
 		
 		label := "A/C turned " + state
 
-		t := time.Unix(record.Timestamp, 0)
+		t := time.Unix(record.Timestamp, 0).In(location)
 		timeStr := t.Format("3:04 PM")
 
 		formatted = append(formatted, map[string]interface{}{
@@ -293,13 +284,14 @@ func FormatACEvents(history []models.Telemetry) []map[string]interface{} {
 	return formatted
 }
 
-//calculating the total used hours for the last 5 days
-func CalculateACUsage(history []models.Telemetry, now int64, period string) []ChartPoint {
+//calculating the total used hours for the last 5 days, per day of location
+func CalculateACUsage(history []models.Telemetry, now int64, period string, location *time.Location) []ChartPoint {
 	if len(history) == 0 {
 		return []ChartPoint{}
 	}
 	timeFormat := GetTimeFormat(period)
-	dailyUsage := make(map[string]float64)
+	bucketSize := PeriodBucket(period)
+	dailyUsage := make(map[int64]float64) // bucket start -> seconds
 	var onTime int64
 	
 	for i := len(history) - 1; i >= 0; i-- {  //get used intervals
@@ -314,8 +306,7 @@ func CalculateACUsage(history []models.Telemetry, now int64, period string) []Ch
 		} else if state == "OFF" && onTime > 0 {
 			duration := record.Timestamp - onTime
 			if duration > 0 {
-				dayLabel := time.Unix(onTime, 0).Format(timeFormat) 
-				dailyUsage[dayLabel] += float64(duration)
+				dailyUsage[BucketStart(onTime, bucketSize, location)] += float64(duration)
 			}
 			onTime = 0
 		}
@@ -325,19 +316,20 @@ func CalculateACUsage(history []models.Telemetry, now int64, period string) []Ch
 	if onTime > 0 {              // if AC is still on
 		duration := now - onTime
 		if duration > 0 {
-			dayLabel := time.Unix(onTime, 0).Format(timeFormat) 
-			dailyUsage[dayLabel] += float64(duration)
+			dailyUsage[BucketStart(onTime, bucketSize, location)] += float64(duration)
 		}
 	}
 	
 	var chartResult []ChartPoint
-	for label, totalSeconds := range dailyUsage {  //convert to hours
+	for start, totalSeconds := range dailyUsage {  //convert to hours
 		hours := totalSeconds / 3600.0
 		chartResult = append(chartResult, ChartPoint{
-			Label: label,
-			Value: math.Round(hours*10) / 10, 
+			Label:     time.Unix(start, 0).In(location).Format(timeFormat),
+			Timestamp: start,
+			Value:     math.Round(hours*10) / 10, 
 		})
 	}
+	slices.SortFunc(chartResult, compareTimestamps)
 
 	return chartResult
 }
@@ -396,28 +388,27 @@ func CalculateACRunTime(history []models.Telemetry, now int64) int64 {
 
 
 //get alerts by time and severity for entire system (system overview part)
-func GetAlerts(alertList []models.Alert, period string) map[string][]ChartPoint {
+func GetAlerts(alertList []models.Alert, period string, location *time.Location) map[string][]ChartPoint {
 	timeFormat := GetTimeFormat(period)
-	warningMap := make(map[string]float64)
-	criticalMap := make(map[string]float64)
+	bucketSize := PeriodBucket(period)
+	warningMap := make(map[int64]float64) // bucket start -> alerts
+	criticalMap := make(map[int64]float64)
 
 	for _, alert := range alertList {
-		label := time.Unix(alert.Timestamp, 0).Format(timeFormat)
+		start := BucketStart(alert.Timestamp, bucketSize, location)
 		if alert.Severity == "WARNING" || alert.Severity == "warning" {
-			warningMap[label]++
+			warningMap[start]++
 		} else if alert.Severity == "CRITICAL" || alert.Severity == "critical"{
-			criticalMap[label]++
+			criticalMap[start]++
 		}
 	}
 
-	mapToSortedChart := func(m map[string]float64) []ChartPoint {
+	mapToSortedChart := func(m map[int64]float64) []ChartPoint {
 		var chart []ChartPoint
-		for k, v := range m {
-			chart = append(chart, ChartPoint{Label: k, Value: v})
+		for start, v := range m {
+			chart = append(chart, ChartPoint{Label: time.Unix(start, 0).In(location).Format(timeFormat), Timestamp: start, Value: v})
 		}
-		slices.SortFunc(chart, func(a, b ChartPoint) int {
-			return cmp.Compare(a.Label, b.Label)
-		})
+		slices.SortFunc(chart, compareTimestamps)
 		return chart
 	}
 
@@ -428,22 +419,41 @@ func GetAlerts(alertList []models.Alert, period string) map[string][]ChartPoint
 }
 
 
-func CalculateEnergy(acUsage []ChartPoint) []ChartPoint {
+func compareTimestamps(a, b ChartPoint) int {
+	return cmp.Compare(a.Timestamp, b.Timestamp)
+}
+
+// the hours a device ran per bucket (CalculateACUsage) and the power it draws meanwhile
+type DeviceUsage struct {
+	Usage   []ChartPoint
+	PowerKW float64
+}
+
+//kWh per bucket summed over every device, plus the standby draw of the system
+func CalculateEnergy(usages []DeviceUsage) []ChartPoint {
 	const dailyPower = 0.132
-	const acPower = 1.5
+
+	totals := make(map[int64]ChartPoint) // bucket start -> kWh
+	for _, device := range usages {
+		for _, point := range device.Usage {
+			total := totals[point.Timestamp]
+			total.Label, total.Timestamp = point.Label, point.Timestamp
+			total.Value += point.Value * device.PowerKW
+			totals[point.Timestamp] = total
+		}
+	}
 
 	var energyChart []ChartPoint
-	
-	for _, point := range acUsage {
-		dailyAC := point.Value * acPower
-		
-		totalConsumption := dailyAC+ dailyPower
+	for _, total := range totals {
+		totalConsumption := total.Value + dailyPower
 
 		energyChart = append(energyChart, ChartPoint{
-			Label: point.Label,
-			Value: math.Round(totalConsumption*10) / 10,
+			Label:     total.Label,
+			Timestamp: total.Timestamp,
+			Value:     math.Round(totalConsumption*10) / 10,
 		})
 	}
+	slices.SortFunc(energyChart, compareTimestamps)
 
 	return energyChart
 }
\ No newline at end of file
@@ -0,0 +1,214 @@
+package telemetry
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	DownsampleLTTB   = "lttb"   // Largest-Triangle-Three-Buckets, keeps the shape of the line
+	DownsampleMinMax = "minmax" // the lowest and highest reading of every stretch, never hides a peak
+
+	DefaultDownsamplePoints = 200
+	MaxDownsamplePoints     = 1000
+)
+
+var ErrInvalidDownsampling = errors.New("downsample must be lttb or minmax, with points between 3 and 1000")
+
+// Downsampling keeps at most Points of the readings themselves instead of averaging them per bucket
+type Downsampling struct {
+	Mode   string `json:"mode"`
+	Points int    `json:"points"`
+}
+
+// ParseDownsampling checks the mode and target of a request, points 0 takes the default
+func ParseDownsampling(mode string, points int64) (Downsampling, error) {
+	if mode != DownsampleLTTB && mode != DownsampleMinMax {
+		return Downsampling{}, ErrInvalidDownsampling
+	}
+	if points == 0 {
+		points = DefaultDownsamplePoints
+	}
+	if points < 3 || points > MaxDownsamplePoints {
+		return Downsampling{}, ErrInvalidDownsampling
+	}
+	return Downsampling{Mode: mode, Points: int(points)}, nil
+}
+
+// Sample is one numeric reading of a metric
+type Sample struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// Samples reads metric between from and to (inclusive) as numbers, oldest first. history can be in any order
+func Samples(history []models.Telemetry, metric string, from int64, to int64) []Sample {
+	samples := make([]Sample, 0)
+	for _, record := range history {
+		if record.Timestamp < from || record.Timestamp > to {
+			continue
+		}
+		if value, ok := ReadingValue(record.Payload[metric]); ok {
+			samples = append(samples, Sample{Timestamp: record.Timestamp, Value: value})
+		}
+	}
+	slices.SortStableFunc(samples, func(a, b Sample) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+	return samples
+}
+
+// Apply keeps at most Points of the samples, oldest first, as they are when there are fewer
+func (sampling Downsampling) Apply(samples []Sample) []Sample {
+	kept := make([]Sample, 0, min(len(samples), sampling.Points))
+	for _, i := range sampling.indices(samples) {
+		kept = append(kept, samples[i])
+	}
+	return kept
+}
+
+func (sampling Downsampling) indices(samples []Sample) []int {
+	if len(samples) <= sampling.Points {
+		all := make([]int, len(samples))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	if sampling.Mode == DownsampleMinMax {
+		return minMaxIndices(samples, sampling.Points)
+	}
+	return lttbIndices(samples, sampling.Points)
+}
+
+// LTTB keeps the first and last sample and, for each of target-2 even stretches in between, the
+// one making the largest triangle with the sample kept before it and the average of the next stretch
+func LTTB(samples []Sample, target int) []Sample {
+	return Downsampling{Mode: DownsampleLTTB, Points: max(target, 3)}.Apply(samples)
+}
+
+// MinMax splits the samples into target/2 even stretches and keeps the lowest and the highest of
+// each, in time order, so no spike or dip is lost
+func MinMax(samples []Sample, target int) []Sample {
+	return Downsampling{Mode: DownsampleMinMax, Points: max(target, 3)}.Apply(samples)
+}
+
+func lttbIndices(samples []Sample, target int) []int {
+	kept := make([]int, 0, target)
+	kept = append(kept, 0)
+
+	// the samples between the first and the last, in target-2 stretches
+	every := float64(len(samples)-2) / float64(target-2)
+	previous := 0
+	for bucket := 0; bucket < target-2; bucket++ {
+		start := int(float64(bucket)*every) + 1
+		end := int(float64(bucket+1)*every) + 1
+
+		// the average of the next stretch, the last sample for the last one
+		nextStart, nextEnd := end, min(int(float64(bucket+2)*every)+1, len(samples))
+		if bucket == target-3 {
+			nextStart, nextEnd = len(samples)-1, len(samples)
+		}
+		var averageX, averageY float64
+		for _, sample := range samples[nextStart:nextEnd] {
+			averageX += float64(sample.Timestamp)
+			averageY += sample.Value
+		}
+		averageX /= float64(nextEnd - nextStart)
+		averageY /= float64(nextEnd - nextStart)
+
+		pointX, pointY := float64(samples[previous].Timestamp), samples[previous].Value
+		best, bestArea := start, -1.0
+		for i := start; i < end; i++ {
+			area := math.Abs((pointX-averageX)*(samples[i].Value-pointY) - (pointX-float64(samples[i].Timestamp))*(averageY-pointY))
+			if area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+		kept = append(kept, best)
+		previous = best
+	}
+	return append(kept, len(samples)-1)
+}
+
+func minMaxIndices(samples []Sample, target int) []int {
+	buckets := target / 2
+	kept := make([]int, 0, target)
+	for bucket := 0; bucket < buckets; bucket++ {
+		start := bucket * len(samples) / buckets
+		end := (bucket + 1) * len(samples) / buckets
+
+		low, high := start, start
+		for i := start + 1; i < end; i++ {
+			if samples[i].Value < samples[low].Value {
+				low = i
+			}
+			if samples[i].Value > samples[high].Value {
+				high = i
+			}
+		}
+		switch {
+		case low == high:
+			kept = append(kept, low)
+		case low < high:
+			kept = append(kept, low, high)
+		default:
+			kept = append(kept, high, low)
+		}
+	}
+	return kept
+}
+
+// DownsampleTime charts the readings of metric over the period like FilterTime, but keeps the
+// readings picked by sampling instead of bucket averages, each labelled with its own time
+func DownsampleTime(history []models.Telemetry, metric string, period string, now int64, location *time.Location, sampling Downsampling) []ChartPoint {
+	timeFormat := GetTimeFormat(period)
+	samples := sampling.Apply(Samples(history, metric, chartStart(history, period, now), now))
+
+	chart := make([]ChartPoint, 0, len(samples))
+	for _, sample := range samples {
+		chart = append(chart, ChartPoint{
+			Label:     time.Unix(sample.Timestamp, 0).In(location).Format(timeFormat),
+			Timestamp: sample.Timestamp,
+			Value:     sample.Value,
+		})
+	}
+	return chart
+}
+
+// DownsampleReadings keeps the readings picked by sampling on metric, whole and in their order.
+// readings without a number for metric are dropped
+func DownsampleReadings(readings []models.Telemetry, metric string, sampling Downsampling) []models.Telemetry {
+	order := make([]int, 0, len(readings)) // reading of each sample
+	for i, record := range readings {
+		if _, ok := ReadingValue(record.Payload[metric]); ok {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(readings[a].Timestamp, readings[b].Timestamp)
+	})
+
+	samples := make([]Sample, len(order))
+	for i, reading := range order {
+		value, _ := ReadingValue(readings[reading].Payload[metric])
+		samples[i] = Sample{Timestamp: readings[reading].Timestamp, Value: value}
+	}
+
+	picked := make(map[int]bool)
+	for _, i := range sampling.indices(samples) {
+		picked[order[i]] = true
+	}
+	kept := make([]models.Telemetry, 0, len(picked))
+	for i, record := range readings {
+		if picked[i] {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
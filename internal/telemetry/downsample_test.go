@@ -0,0 +1,174 @@
+package telemetry
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// a slow sine with one spike up and one dip down
+func wave(n int, spikeAt int, dipAt int) []Sample {
+	samples := make([]Sample, n)
+	for i := range samples {
+		samples[i] = Sample{Timestamp: int64(1000 + 10*i), Value: 20 + math.Sin(float64(i)/50)}
+	}
+	samples[spikeAt].Value = 90
+	samples[dipAt].Value = -40
+	return samples
+}
+
+func checkIndices(t *testing.T, indices []int, n int, target int) {
+	t.Helper()
+	if len(indices) > target {
+		t.Errorf("kept %d samples, want at most %d", len(indices), target)
+	}
+	for i, index := range indices {
+		if index < 0 || index >= n {
+			t.Fatalf("index %d out of range", index)
+		}
+		if i > 0 && index <= indices[i-1] {
+			t.Fatalf("indices %v are not strictly increasing", indices)
+		}
+	}
+}
+
+func TestLTTBIndices(t *testing.T) {
+	samples := wave(1000, 321, 777)
+	indices := lttbIndices(samples, 50)
+
+	checkIndices(t, indices, len(samples), 50)
+	if len(indices) != 50 {
+		t.Errorf("kept %d samples, want exactly 50", len(indices))
+	}
+	if indices[0] != 0 || indices[len(indices)-1] != len(samples)-1 {
+		t.Errorf("first and last kept are %d and %d, want the ends", indices[0], indices[len(indices)-1])
+	}
+	if !slices.Contains(indices, 321) || !slices.Contains(indices, 777) {
+		t.Errorf("the spike or the dip was dropped: %v", indices)
+	}
+}
+
+// one stretch between the ends, the sample making the largest triangle wins
+func TestLTTBIndicesSmall(t *testing.T) {
+	samples := []Sample{{0, 0}, {1, 1}, {2, 10}, {3, 1}, {4, 0}}
+	if got := lttbIndices(samples, 3); !slices.Equal(got, []int{0, 2, 4}) {
+		t.Errorf("lttbIndices = %v, want [0 2 4]", got)
+	}
+}
+
+func TestMinMaxIndices(t *testing.T) {
+	samples := wave(1000, 321, 777)
+	indices := minMaxIndices(samples, 50)
+
+	checkIndices(t, indices, len(samples), 50)
+	if !slices.Contains(indices, 321) || !slices.Contains(indices, 777) {
+		t.Errorf("the spike or the dip was dropped: %v", indices)
+	}
+
+	// every stretch keeps its lowest and highest sample
+	buckets := 25
+	for bucket := range buckets {
+		start, end := bucket*len(samples)/buckets, (bucket+1)*len(samples)/buckets
+		low, high := start, start
+		for i := start; i < end; i++ {
+			if samples[i].Value < samples[low].Value {
+				low = i
+			}
+			if samples[i].Value > samples[high].Value {
+				high = i
+			}
+		}
+		if !slices.Contains(indices, low) || !slices.Contains(indices, high) {
+			t.Errorf("stretch %d lost its min %d or max %d", bucket, low, high)
+		}
+	}
+}
+
+// a flat stretch has its min and max on the same sample, it is kept once
+func TestMinMaxIndicesFlat(t *testing.T) {
+	samples := make([]Sample, 20)
+	for i := range samples {
+		samples[i] = Sample{Timestamp: int64(i), Value: 5}
+	}
+	if got := minMaxIndices(samples, 4); !slices.Equal(got, []int{0, 10}) {
+		t.Errorf("minMaxIndices = %v, want [0 10]", got)
+	}
+}
+
+func TestDownsamplingApply(t *testing.T) {
+	few := wave(10, 3, 6)
+	for _, mode := range []string{DownsampleLTTB, DownsampleMinMax} {
+		kept := Downsampling{Mode: mode, Points: 10}.Apply(few)
+		if !slices.Equal(kept, few) {
+			t.Errorf("%s with fewer samples than points changed them", mode)
+		}
+	}
+
+	many := wave(500, 100, 400)
+	kept := LTTB(many, 1) // raised to the minimum of 3
+	if len(kept) != 3 || kept[0] != many[0] || kept[2] != many[len(many)-1] {
+		t.Errorf("LTTB with target 1 = %v, want 3 samples with both ends", kept)
+	}
+	if kept := MinMax(many, 40); len(kept) > 40 || !slices.IsSortedFunc(kept, func(a, b Sample) int { return int(a.Timestamp - b.Timestamp) }) {
+		t.Errorf("MinMax kept %d samples, want at most 40 in time order", len(kept))
+	}
+}
+
+func TestDownsampleReadings(t *testing.T) {
+	readings := make([]models.Telemetry, 0)
+	for i := 99; i >= 0; i-- { // newest first, like the store
+		payload := map[string]interface{}{"temp": float64(i % 7), "humidity": 50.0}
+		if i == 42 {
+			payload["temp"] = 100.0
+		}
+		readings = append(readings, models.Telemetry{DeviceID: "dev-1", Timestamp: int64(1000 + i), Payload: payload})
+	}
+	readings = append(readings, models.Telemetry{DeviceID: "dev-1", Timestamp: 2000, Payload: map[string]interface{}{"temp": "broken"}})
+
+	kept := DownsampleReadings(readings, "temp", Downsampling{Mode: DownsampleMinMax, Points: 10})
+	if len(kept) == 0 || len(kept) > 10 {
+		t.Fatalf("kept %d readings, want between 1 and 10", len(kept))
+	}
+	found := false
+	for i, record := range kept {
+		if record.Timestamp == 2000 {
+			t.Error("kept a reading without a number")
+		}
+		if record.Payload["humidity"] != 50.0 {
+			t.Errorf("reading %d lost its other metrics: %v", record.Timestamp, record.Payload)
+		}
+		if i > 0 && record.Timestamp >= kept[i-1].Timestamp {
+			t.Errorf("readings are not in their original order")
+		}
+		found = found || record.Timestamp == 1042
+	}
+	if !found {
+		t.Error("the spike at 1042 was dropped")
+	}
+}
+
+func TestParseDownsampling(t *testing.T) {
+	sampling, err := ParseDownsampling(DownsampleLTTB, 0)
+	if err != nil || sampling.Points != DefaultDownsamplePoints {
+		t.Errorf("ParseDownsampling(lttb, 0) = %+v, %v, want the default points", sampling, err)
+	}
+
+	invalid := []struct {
+		mode   string
+		points int64
+	}{
+		{"average", 100},
+		{"", 100},
+		{DownsampleMinMax, 2},
+		{DownsampleMinMax, MaxDownsamplePoints + 1},
+		{DownsampleLTTB, -5},
+	}
+	for _, test := range invalid {
+		if _, err := ParseDownsampling(test.mode, test.points); !errors.Is(err, ErrInvalidDownsampling) {
+			t.Errorf("ParseDownsampling(%q, %d) = %v, want ErrInvalidDownsampling", test.mode, test.points, err)
+		}
+	}
+}
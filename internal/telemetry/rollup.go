@@ -0,0 +1,192 @@
+package telemetry
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	hourLabel  = "15:00"
+	dayLabel   = "Jan 02"
+	monthLabel = "Jan 2006"
+)
+
+// Total adds up readings the way the charts show them: numbers are averaged and
+// every "ON" report counts as 5 minutes (0.083 h) of run time
+type Total struct {
+	Sum   float64 `json:"sum"`
+	Count int     `json:"count"` // numeric readings, 0 for on/off metrics
+}
+
+// Add reports whether the value counted, off states and other strings don't
+func (total *Total) Add(value interface{}) bool {
+	switch typed := value.(type) {
+	case string:
+		if typed != "ON" {
+			return false
+		}
+		total.Sum += 0.083
+	case float64:
+		total.Sum += typed
+		total.Count++
+	case int:
+		total.Sum += float64(typed)
+		total.Count++
+	default:
+		return false
+	}
+	return true
+}
+
+func (total Total) Merge(other Total) Total {
+	return Total{Sum: total.Sum + other.Sum, Count: total.Count + other.Count}
+}
+
+func (total Total) Value() float64 {
+	value := total.Sum
+	if total.Count > 0 {
+		value = total.Sum / float64(total.Count)
+	}
+	return math.Round(value*10) / 10
+}
+
+// DayRollup is what stays of a device's day once its raw readings expire
+type DayRollup struct {
+	DeviceID  string               `json:"device_id"`
+	Date      string               `json:"date"` // YYYY-MM-DD
+	Metrics   map[string]DayMetric `json:"metrics"`
+	CreatedAt int64                `json:"created_at"`
+}
+
+type DayMetric struct {
+	Hourly []ChartPoint `json:"hourly"` // "15:00" labels
+	Total  Total        `json:"total"`
+}
+
+// MonthChart has one point per rolled up day for every metric
+type MonthChart struct {
+	DeviceID  string                  `json:"device_id"`
+	Month     string                  `json:"month"`   // YYYY-MM
+	Metrics   map[string][]ChartPoint `json:"metrics"` // "Jan 02" labels
+	Totals    map[string]Total        `json:"totals"`  // the whole month, what the year chart is built from
+	Days      []string                `json:"days"`
+	UpdatedAt int64                   `json:"updated_at"`
+}
+
+// YearChart has one point per month for every metric
+type YearChart struct {
+	DeviceID  string                  `json:"device_id"`
+	Year      string                  `json:"year"`    // YYYY
+	Metrics   map[string][]ChartPoint `json:"metrics"` // "Jan 2006" labels
+	Months    []string                `json:"months"`
+	UpdatedAt int64                   `json:"updated_at"`
+}
+
+// RollupDay groups the readings of the day starting at day per metric and hour,
+// hours are labelled in the location of day. history may hold other days
+func RollupDay(deviceID string, history []models.Telemetry, day time.Time) DayRollup {
+	start, end := day.Unix(), day.AddDate(0, 0, 1).Unix()
+	hourly := make(map[string]map[int64]Total) // metric -> hour start -> total
+	daily := make(map[string]Total)
+
+	for _, record := range history {
+		if record.Timestamp < start || record.Timestamp >= end {
+			continue
+		}
+		hour := BucketStart(record.Timestamp, time.Hour, day.Location())
+
+		for metric, value := range record.Payload {
+			var reading Total
+			if !reading.Add(value) {
+				continue
+			}
+			if hourly[metric] == nil {
+				hourly[metric] = make(map[int64]Total)
+			}
+			hourly[metric][hour] = hourly[metric][hour].Merge(reading)
+			daily[metric] = daily[metric].Merge(reading)
+		}
+	}
+
+	rollup := DayRollup{
+		DeviceID: deviceID,
+		Date:     day.Format("2006-01-02"),
+		Metrics:  make(map[string]DayMetric, len(daily)),
+	}
+	for metric, total := range daily {
+		rollup.Metrics[metric] = DayMetric{Hourly: hourlyPoints(hourly[metric], day.Location()), Total: total}
+	}
+	return rollup
+}
+
+// BuildMonthChart joins the day rollups of a month, in any order. the days start at midnight in the location of month
+func BuildMonthChart(deviceID string, month time.Time, days []DayRollup) MonthChart {
+	days = slices.Clone(days)
+	slices.SortFunc(days, func(a, b DayRollup) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+
+	chart := MonthChart{
+		DeviceID: deviceID,
+		Month:    month.Format("2006-01"),
+		Metrics:  make(map[string][]ChartPoint),
+		Totals:   make(map[string]Total),
+		Days:     make([]string, 0, len(days)),
+	}
+	for _, day := range days {
+		chart.Days = append(chart.Days, day.Date)
+		date, err := time.ParseInLocation("2006-01-02", day.Date, month.Location())
+		if err != nil {
+			continue
+		}
+		for metric, values := range day.Metrics {
+			chart.Metrics[metric] = append(chart.Metrics[metric], ChartPoint{Label: date.Format(dayLabel), Timestamp: date.Unix(), Value: values.Total.Value()})
+			chart.Totals[metric] = chart.Totals[metric].Merge(values.Total)
+		}
+	}
+	return chart
+}
+
+// BuildYearChart joins the month charts of a year, in any order. the months start in the location of year
+func BuildYearChart(deviceID string, year time.Time, months []MonthChart) YearChart {
+	months = slices.Clone(months)
+	slices.SortFunc(months, func(a, b MonthChart) int {
+		return cmp.Compare(a.Month, b.Month)
+	})
+
+	chart := YearChart{
+		DeviceID: deviceID,
+		Year:     year.Format("2006"),
+		Metrics:  make(map[string][]ChartPoint),
+		Months:   make([]string, 0, len(months)),
+	}
+	for _, month := range months {
+		chart.Months = append(chart.Months, month.Month)
+		date, err := time.ParseInLocation("2006-01", month.Month, year.Location())
+		if err != nil {
+			continue
+		}
+		for metric, total := range month.Totals {
+			chart.Metrics[metric] = append(chart.Metrics[metric], ChartPoint{Label: MonthLabel(date), Timestamp: date.Unix(), Value: total.Value()})
+		}
+	}
+	return chart
+}
+
+// MonthLabel is how a month is labelled on the year chart
+func MonthLabel(month time.Time) string {
+	return month.Format(monthLabel)
+}
+
+func hourlyPoints(totals map[int64]Total, location *time.Location) []ChartPoint {
+	points := make([]ChartPoint, 0, len(totals))
+	for start, total := range totals {
+		points = append(points, ChartPoint{Label: time.Unix(start, 0).In(location).Format(hourLabel), Timestamp: start, Value: total.Value()})
+	}
+	slices.SortFunc(points, compareTimestamps)
+	return points
+}
@@ -0,0 +1,136 @@
+package telemetry
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	SeriesNumeric = "numeric"
+	SeriesState   = "state"
+)
+
+// Series is one metric of a multi-metric chart. numeric metrics get a point per bucket of the
+// period, the same buckets for every metric so the series line up, null where nothing was
+// reported. on/off, boolean and other text metrics are a timeline of states instead
+type Series struct {
+	Metric string        `json:"metric"`
+	Kind   string        `json:"kind"` // SeriesNumeric or SeriesState
+	Points []SeriesPoint `json:"points,omitempty"`
+	States []StateSpan   `json:"states,omitempty"`
+}
+
+type SeriesPoint struct {
+	Label     string   `json:"label"`
+	Timestamp int64    `json:"timestamp"` // start of the bucket
+	Value     *float64 `json:"value"`
+}
+
+// StateSpan is a stretch of time the metric kept one value, from its first report until the next change
+type StateSpan struct {
+	Value interface{} `json:"value"`
+	From  int64       `json:"from"`
+	To    int64       `json:"to"`
+}
+
+// BuildSeries charts every metric over the window of the period from a single history, in any order.
+// with sampling the numeric series keep the picked readings instead, and no longer line up
+func BuildSeries(history []models.Telemetry, metrics []string, period string, now int64, location *time.Location, sampling *Downsampling) []Series {
+	from := chartStart(history, period, now)
+	timeFormat := GetTimeFormat(period)
+
+	series := make([]Series, 0, len(metrics))
+	for _, metric := range metrics {
+		if isStateMetric(history, metric) {
+			series = append(series, Series{Metric: metric, Kind: SeriesState, States: StateTimeline(history, metric, from, now)})
+			continue
+		}
+		if sampling != nil {
+			series = append(series, ChartSeries(metric, DownsampleTime(history, metric, period, now, location, *sampling)))
+			continue
+		}
+
+		buckets := Aggregate(history, metric, from, now, PeriodBucket(period), location)
+		points := make([]SeriesPoint, 0, len(buckets))
+		for _, bucket := range buckets {
+			point := SeriesPoint{
+				Label:     time.Unix(bucket.Timestamp, 0).In(location).Format(timeFormat),
+				Timestamp: bucket.Timestamp,
+			}
+			if bucket.Avg != nil {
+				value := math.Round(*bucket.Avg*10) / 10
+				point.Value = &value
+			}
+			points = append(points, point)
+		}
+		series = append(series, Series{Metric: metric, Kind: SeriesNumeric, Points: points})
+	}
+	return series
+}
+
+// ChartSeries wraps the points of a processed chart, where every metric is a number
+func ChartSeries(metric string, chart []ChartPoint) Series {
+	points := make([]SeriesPoint, 0, len(chart))
+	for _, point := range chart {
+		value := point.Value
+		points = append(points, SeriesPoint{Label: point.Label, Timestamp: point.Timestamp, Value: &value})
+	}
+	return Series{Metric: metric, Kind: SeriesNumeric, Points: points}
+}
+
+// StateTimeline merges the reports of metric between from and to into the spans it kept each
+// value, oldest first. the last span runs until to
+func StateTimeline(history []models.Telemetry, metric string, from int64, to int64) []StateSpan {
+	var readings []models.Telemetry
+	for _, record := range history {
+		if record.Timestamp < from || record.Timestamp > to {
+			continue
+		}
+		switch record.Payload[metric].(type) {
+		case string, bool, float64, int: // maps and lists can't be compared
+			readings = append(readings, record)
+		}
+	}
+	slices.SortStableFunc(readings, func(a, b models.Telemetry) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+
+	spans := make([]StateSpan, 0)
+	for _, record := range readings {
+		value := record.Payload[metric]
+		if last := len(spans) - 1; last >= 0 {
+			if spans[last].Value == value {
+				continue
+			}
+			spans[last].To = record.Timestamp
+		}
+		spans = append(spans, StateSpan{Value: value, From: record.Timestamp, To: to})
+	}
+	return spans
+}
+
+// whether metric is reported as a state (ON/OFF, true/false or any other text) rather than a number
+func isStateMetric(history []models.Telemetry, metric string) bool {
+	for _, record := range history {
+		switch record.Payload[metric].(type) {
+		case string, bool:
+			return true
+		}
+	}
+	return false
+}
+
+// where the chart of the period starts, the oldest reading for periods without a cutoff
+func chartStart(history []models.Telemetry, period string, now int64) int64 {
+	cutoff := PeriodCutoff(now, period)
+	if cutoff == 0 && len(history) > 0 {
+		cutoff = slices.MinFunc(history, func(a, b models.Telemetry) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		}).Timestamp
+	}
+	return cutoff
+}
@@ -3,7 +3,9 @@ package telemetry
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Fleexa-Graduation-Project/Backend/models"
@@ -18,30 +20,59 @@ import (
 const (
 	dynamoBatchLimit = 25 // DynamoDB BatchWriteItem hard limit
 	maxRetries       = 3  // Retries for unprocessed items
+	TelemetryTTL     = 7 * 24 * time.Hour // readings expire after a week, the rollup job keeps them as charts
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
 )
 
-type TelemetryStore struct {
+// TelemetryStore keeps the raw readings of every device (device_id + timestamp)
+type TelemetryStore interface {
+	SaveTelemetry(ctx context.Context, data models.Telemetry) error
+	SaveTelemetryBatch(ctx context.Context, dataList []models.Telemetry) error
+	GetTelemetryHistory(ctx context.Context, deviceID string, limit int32, since int64) ([]models.Telemetry, error)
+	DeleteDeviceTelemetry(ctx context.Context, deviceID string) (int, error)
+	QueryTelemetry(ctx context.Context, query HistoryQuery) (HistoryPage, error)
+}
+
+// HistoryQuery selects a page of a device's readings, From/To are inclusive unix seconds (0 = open).
+// with Metrics the payloads only keep those keys and the readings holding none of them are left out
+type HistoryQuery struct {
+	DeviceID  string
+	From      int64
+	To        int64
+	Metrics   []string
+	Ascending bool // oldest first, newest first by default
+	Limit     int32
+	Cursor    string
+}
+
+type HistoryPage struct {
+	Readings   []models.Telemetry
+	NextCursor string // empty on the last page, a page left empty by Metrics can still have one
+}
+
+type DynamoTelemetryStore struct {
 	Client    *dynamodb.Client
 	TableName string
 }
 
 // NewTelemetryStore initializes the store using the shared db.Client
-func NewTelemetryStore() (*TelemetryStore, error) {
+func NewTelemetryStore() (*DynamoTelemetryStore, error) {
 	tableName := os.Getenv("DYNAMODB_TABLE_NAME")
 	if tableName == "" {
 		return nil, fmt.Errorf("DYNAMODB_TABLE_NAME environment variable is not set")
 	}
 
 	// We use the global 'db.Client' we created in pkg/db/client.go
-	return &TelemetryStore{
+	return &DynamoTelemetryStore{
 		Client:    db.Client,
 		TableName: tableName,
 	}, nil
 }
 //write to db
-func (store *TelemetryStore) SaveTelemetry(ctx context.Context, data models.Telemetry) error {
+func (store *DynamoTelemetryStore) SaveTelemetry(ctx context.Context, data models.Telemetry) error {
 	if data.ExpiresAt == 0 {
-		data.ExpiresAt = time.Now().Add(7 * 24 * time.Hour).Unix()
+		data.ExpiresAt = time.Now().Add(TelemetryTTL).Unix()
 	}
 
 	item, err := attributevalue.MarshalMap(data)
@@ -64,12 +95,12 @@ func (store *TelemetryStore) SaveTelemetry(ctx context.Context, data models.Tele
 }
 
 // storing multiple telemetry records in a single DynamoDB call(max 25)
-func (store *TelemetryStore) SaveTelemetryBatch(ctx context.Context, dataList []models.Telemetry) error {
+func (store *DynamoTelemetryStore) SaveTelemetryBatch(ctx context.Context, dataList []models.Telemetry) error {
 	if len(dataList) == 0 {
 		return nil
 	}
 
-	defaultExpiry := time.Now().Add(7 * 24 * time.Hour).Unix()
+	defaultExpiry := time.Now().Add(TelemetryTTL).Unix()
 
 	for i := 0; i < len(dataList); i += dynamoBatchLimit {
 
@@ -108,7 +139,7 @@ func (store *TelemetryStore) SaveTelemetryBatch(ctx context.Context, dataList []
 	return nil
 }
 
-func (store *TelemetryStore) writeBatchWithRetry(ctx context.Context, requests []types.WriteRequest) error {
+func (store *DynamoTelemetryStore) writeBatchWithRetry(ctx context.Context, requests []types.WriteRequest) error {
 	pending := requests
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
@@ -146,7 +177,7 @@ func (store *TelemetryStore) writeBatchWithRetry(ctx context.Context, requests [
 
 
 //get recent readings for a device.
-func (store *TelemetryStore) GetTelemetryHistory(ctx context.Context, deviceID string, limit int32, since int64) ([]models.Telemetry, error) {
+func (store *DynamoTelemetryStore) GetTelemetryHistory(ctx context.Context, deviceID string, limit int32, since int64) ([]models.Telemetry, error) {
     keyCondition := "device_id = :id"
     exprAttrValues := map[string]types.AttributeValue{
         ":id": &types.AttributeValueMemberS{Value: deviceID},
@@ -169,15 +200,117 @@ func (store *TelemetryStore) GetTelemetryHistory(ctx context.Context, deviceID s
         input.Limit = aws.Int32(limit)
     }
 
-    result, err := store.Client.Query(ctx, input)
-    if err != nil {
-        return nil, fmt.Errorf("failed to query telemetry history for device %s: %w", deviceID, err)
-    }
-
+    // a query stops at 1 MB, keep reading until the window or the limit is done
     var history []models.Telemetry
-    if err = attributevalue.UnmarshalListOfMaps(result.Items, &history); err != nil {
-        return nil, fmt.Errorf("failed to unmarshal telemetry history for device %s: %w", deviceID, err)
+    for {
+        result, err := store.Client.Query(ctx, input)
+        if err != nil {
+            return nil, fmt.Errorf("failed to query telemetry history for device %s: %w", deviceID, err)
+        }
+
+        var page []models.Telemetry
+        if err = attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+            return nil, fmt.Errorf("failed to unmarshal telemetry history for device %s: %w", deviceID, err)
+        }
+        history = append(history, page...)
+
+        if len(result.LastEvaluatedKey) == 0 || (limit > 0 && len(history) >= int(limit)) {
+            break
+        }
+        input.ExclusiveStartKey = result.LastEvaluatedKey
     }
 
+    if limit > 0 && len(history) > int(limit) {
+        history = history[:limit]
+    }
     return history, nil
-}
\ No newline at end of file
+}
+
+// the table key, a page of one device
+func cursorKey(deviceID string) db.CursorKey {
+	return db.CursorKey{Partition: "device_id", Value: deviceID, Numbers: []string{"timestamp"}}
+}
+
+// one page of readings for the export and debugging screens
+func (store *DynamoTelemetryStore) QueryTelemetry(ctx context.Context, query HistoryQuery) (HistoryPage, error) {
+	query = normalizeHistoryQuery(query)
+
+	startKey, err := db.DecodeCursor(query.Cursor, cursorKey(query.DeviceID))
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(store.TableName),
+		KeyConditionExpression: aws.String("device_id = :id AND #ts BETWEEN :from AND :to"),
+		ExpressionAttributeNames: map[string]string{
+			"#ts": "timestamp",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id":   &types.AttributeValueMemberS{Value: query.DeviceID},
+			":from": &types.AttributeValueMemberN{Value: fmt.Sprint(query.From)},
+			":to":   &types.AttributeValueMemberN{Value: fmt.Sprint(query.To)},
+		},
+		ScanIndexForward:  aws.Bool(query.Ascending),
+		Limit:             aws.Int32(query.Limit),
+		ExclusiveStartKey: startKey,
+	}
+
+	if len(query.Metrics) > 0 {
+		// type is a reserved word, every metric gets a placeholder in case it is one too
+		projection := []string{"device_id", "#ts", "#type", "expires_at"}
+		input.ExpressionAttributeNames["#type"] = "type"
+		for i, metric := range query.Metrics {
+			placeholder := fmt.Sprintf("#m%d", i)
+			input.ExpressionAttributeNames[placeholder] = metric
+			projection = append(projection, "payload."+placeholder)
+		}
+		input.ProjectionExpression = aws.String(strings.Join(projection, ", "))
+	}
+
+	res, err := store.Client.Query(ctx, input)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to query telemetry for device %s: %w", query.DeviceID, err)
+	}
+
+	var readings []models.Telemetry
+	if err = attributevalue.UnmarshalListOfMaps(res.Items, &readings); err != nil {
+		return HistoryPage{}, fmt.Errorf("failed to unmarshal telemetry for device %s: %w", query.DeviceID, err)
+	}
+
+	page := HistoryPage{Readings: make([]models.Telemetry, 0, len(readings))}
+	for _, reading := range readings {
+		if len(query.Metrics) > 0 && len(reading.Payload) == 0 {
+			continue
+		}
+		page.Readings = append(page.Readings, reading)
+	}
+
+	page.NextCursor, err = db.EncodeCursor(res.LastEvaluatedKey)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+	return page, nil
+}
+
+func normalizeHistoryQuery(query HistoryQuery) HistoryQuery {
+	if query.Limit <= 0 {
+		query.Limit = defaultQueryLimit
+	}
+	if query.Limit > maxQueryLimit {
+		query.Limit = maxQueryLimit
+	}
+	if query.To <= 0 {
+		query.To = math.MaxInt64
+	}
+	return query
+}
+
+// drops every reading of a decommissioned device, the monthly charts already in s3 are kept
+func (store *DynamoTelemetryStore) DeleteDeviceTelemetry(ctx context.Context, deviceID string) (int, error) {
+	deleted, err := db.DeletePartition(ctx, store.Client, store.TableName, "device_id", "timestamp", deviceID)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete telemetry of device %s: %w", deviceID, err)
+	}
+	return deleted, nil
+}
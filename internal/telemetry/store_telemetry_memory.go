@@ -0,0 +1,165 @@
+package telemetry
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MemoryTelemetryStore is an in-process TelemetryStore used for tests and local demos
+type MemoryTelemetryStore struct {
+	mu       sync.RWMutex
+	readings map[string]map[int64]models.Telemetry // device_id -> timestamp -> reading
+}
+
+func NewMemoryTelemetryStore() *MemoryTelemetryStore {
+	return &MemoryTelemetryStore{
+		readings: make(map[string]map[int64]models.Telemetry),
+	}
+}
+
+func (store *MemoryTelemetryStore) SaveTelemetry(ctx context.Context, data models.Telemetry) error {
+	return store.SaveTelemetryBatch(ctx, []models.Telemetry{data})
+}
+
+// same key as the table (device_id + timestamp), so a repeated timestamp overwrites like PutItem
+func (store *MemoryTelemetryStore) SaveTelemetryBatch(ctx context.Context, dataList []models.Telemetry) error {
+	defaultExpiry := time.Now().Add(TelemetryTTL).Unix()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, data := range dataList {
+		if data.ExpiresAt == 0 {
+			data.ExpiresAt = defaultExpiry
+		}
+		data.Payload = maps.Clone(data.Payload)
+
+		deviceReadings, ok := store.readings[data.DeviceID]
+		if !ok {
+			deviceReadings = make(map[int64]models.Telemetry)
+			store.readings[data.DeviceID] = deviceReadings
+		}
+		deviceReadings[data.Timestamp] = data
+	}
+
+	return nil
+}
+
+// newest first, expired readings are dropped like the table TTL
+func (store *MemoryTelemetryStore) GetTelemetryHistory(ctx context.Context, deviceID string, limit int32, since int64) ([]models.Telemetry, error) {
+	now := time.Now().Unix()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	history := make([]models.Telemetry, 0)
+	for ts, record := range store.readings[deviceID] {
+		if record.ExpiresAt > 0 && record.ExpiresAt <= now {
+			delete(store.readings[deviceID], ts)
+			continue
+		}
+		if since > 0 && record.Timestamp < since {
+			continue
+		}
+		record.Payload = maps.Clone(record.Payload)
+		history = append(history, record)
+	}
+
+	slices.SortFunc(history, func(a, b models.Telemetry) int {
+		return cmp.Compare(b.Timestamp, a.Timestamp)
+	})
+
+	if limit > 0 && len(history) > int(limit) {
+		history = history[:limit]
+	}
+
+	return history, nil
+}
+
+// same order and cursor shape as the table query
+func (store *MemoryTelemetryStore) QueryTelemetry(ctx context.Context, query HistoryQuery) (HistoryPage, error) {
+	query = normalizeHistoryQuery(query)
+
+	startKey, err := db.DecodeCursor(query.Cursor, cursorKey(query.DeviceID))
+	if err != nil {
+		return HistoryPage{}, err
+	}
+	var after struct {
+		Timestamp int64 `dynamodbav:"timestamp"`
+	}
+	if startKey != nil {
+		if err := attributevalue.UnmarshalMap(startKey, &after); err != nil {
+			return HistoryPage{}, fmt.Errorf("%w: %v", db.ErrInvalidCursor, err)
+		}
+	}
+
+	history, err := store.GetTelemetryHistory(ctx, query.DeviceID, 0, query.From)
+	if err != nil {
+		return HistoryPage{}, err
+	}
+	history = slices.DeleteFunc(history, func(record models.Telemetry) bool {
+		if record.Timestamp > query.To {
+			return true
+		}
+		if startKey == nil {
+			return false
+		}
+		if query.Ascending {
+			return record.Timestamp <= after.Timestamp
+		}
+		return record.Timestamp >= after.Timestamp
+	})
+	if query.Ascending {
+		slices.Reverse(history)
+	}
+
+	page := HistoryPage{Readings: make([]models.Telemetry, 0)}
+	if len(history) > int(query.Limit) {
+		history = history[:query.Limit]
+		last := history[len(history)-1]
+		page.NextCursor, err = db.EncodeCursor(map[string]types.AttributeValue{
+			"device_id": &types.AttributeValueMemberS{Value: last.DeviceID},
+			"timestamp": &types.AttributeValueMemberN{Value: strconv.FormatInt(last.Timestamp, 10)},
+		})
+		if err != nil {
+			return HistoryPage{}, err
+		}
+	}
+
+	for _, record := range history {
+		if len(query.Metrics) > 0 {
+			projected := make(map[string]interface{})
+			for _, metric := range query.Metrics {
+				if value, exists := record.Payload[metric]; exists {
+					projected[metric] = value
+				}
+			}
+			if len(projected) == 0 {
+				continue
+			}
+			record.Payload = projected
+		}
+		page.Readings = append(page.Readings, record)
+	}
+	return page, nil
+}
+
+func (store *MemoryTelemetryStore) DeleteDeviceTelemetry(ctx context.Context, deviceID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := len(store.readings[deviceID])
+	delete(store.readings, deviceID)
+	return deleted, nil
+}
@@ -0,0 +1,115 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+	"github.com/Fleexa-Graduation-Project/Backend/pkg/db"
+)
+
+// readings every 10s from 1000 to 1240, humidity on every third one
+func seededTelemetryStore(t *testing.T) *MemoryTelemetryStore {
+	t.Helper()
+	store := NewMemoryTelemetryStore()
+	readings := make([]models.Telemetry, 0)
+	for i := range 25 {
+		payload := map[string]interface{}{"temp": float64(20 + i)}
+		if i%3 == 0 {
+			payload["humidity"] = float64(40 + i)
+		}
+		readings = append(readings, models.Telemetry{DeviceID: "dev-1", Timestamp: int64(1000 + 10*i), Payload: payload})
+	}
+	readings = append(readings, models.Telemetry{DeviceID: "dev-2", Timestamp: 1005, Payload: map[string]interface{}{"temp": 1.0}})
+	if err := store.SaveTelemetryBatch(context.Background(), readings); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// every page of the query, following the cursors
+func allPages(t *testing.T, store TelemetryStore, query HistoryQuery) ([]int64, int) {
+	t.Helper()
+	var timestamps []int64
+	pages := 0
+	for {
+		page, err := store.QueryTelemetry(context.Background(), query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, record := range page.Readings {
+			if record.DeviceID != query.DeviceID {
+				t.Fatalf("got a reading of %s", record.DeviceID)
+			}
+			timestamps = append(timestamps, record.Timestamp)
+		}
+		if page.NextCursor == "" {
+			return timestamps, pages
+		}
+		if pages > 100 {
+			t.Fatal("cursor never ends")
+		}
+		query.Cursor = page.NextCursor
+	}
+}
+
+func TestQueryTelemetryPages(t *testing.T) {
+	store := seededTelemetryStore(t)
+
+	newest, pages := allPages(t, store, HistoryQuery{DeviceID: "dev-1", Limit: 10})
+	if len(newest) != 25 || pages != 3 {
+		t.Fatalf("got %d readings in %d pages, want 25 in 3", len(newest), pages)
+	}
+	for i, ts := range newest {
+		if want := int64(1240 - 10*i); ts != want {
+			t.Fatalf("reading %d at %d, want %d newest first without gaps or repeats", i, ts, want)
+		}
+	}
+
+	oldest, _ := allPages(t, store, HistoryQuery{DeviceID: "dev-1", Limit: 7, Ascending: true, From: 1050, To: 1150})
+	if len(oldest) != 11 || oldest[0] != 1050 || oldest[len(oldest)-1] != 1150 {
+		t.Fatalf("got %v, want 1050 to 1150 oldest first", oldest)
+	}
+	for i := 1; i < len(oldest); i++ {
+		if oldest[i] != oldest[i-1]+10 {
+			t.Fatalf("got %v, want every reading once in order", oldest)
+		}
+	}
+}
+
+func TestQueryTelemetryMetrics(t *testing.T) {
+	store := seededTelemetryStore(t)
+
+	page, err := store.QueryTelemetry(context.Background(), HistoryQuery{DeviceID: "dev-1", Metrics: []string{"humidity"}, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Readings) != 9 {
+		t.Fatalf("got %d readings, want the 9 with humidity", len(page.Readings))
+	}
+	for _, record := range page.Readings {
+		if _, ok := record.Payload["temp"]; ok || len(record.Payload) != 1 {
+			t.Errorf("payload %v, want humidity only", record.Payload)
+		}
+	}
+}
+
+func TestQueryTelemetryBadCursor(t *testing.T) {
+	store := seededTelemetryStore(t)
+	_, err := store.QueryTelemetry(context.Background(), HistoryQuery{DeviceID: "dev-1", Cursor: "%%%"})
+	if !errors.Is(err, db.ErrInvalidCursor) {
+		t.Errorf("QueryTelemetry with a bad cursor = %v, want ErrInvalidCursor", err)
+	}
+
+	// a cursor of another device is refused, not read as a key of this one
+	page, err := store.QueryTelemetry(context.Background(), HistoryQuery{DeviceID: "dev-1", Limit: 2})
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("first page: %v, cursor %q", err, page.NextCursor)
+	}
+	_, err = store.QueryTelemetry(context.Background(), HistoryQuery{DeviceID: "dev-2", Cursor: page.NextCursor})
+	if !errors.Is(err, db.ErrInvalidCursor) {
+		t.Errorf("QueryTelemetry with the cursor of another device = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Fleexa-Graduation-Project/Backend/internal/commands"
 	"github.com/Fleexa-Graduation-Project/Backend/internal/devices"
 	"github.com/Fleexa-Graduation-Project/Backend/models"
 )
@@ -56,6 +57,14 @@ func ValidateMessage(event map[string]interface{}) (
 		return "", "", envelope, false, err
 	}
 
+	// command acks carry a request_id instead of a device reading
+	if messageType == "command_ack" {
+		if err := ValidateCommandAck(envelope.Payload); err != nil {
+			return "", "", envelope, false, err
+		}
+		return deviceID, messageType, envelope, false, nil
+	}
+
 	// validating payload structure
 	// If it is a batch, we SKIP deep validation here (we will do it in the loop later)
 	if !isBatch {
@@ -104,7 +113,7 @@ func validateTopic(topic string) (string, string, error) {
 		return "", "", fmt.Errorf("%w: empty device id", ErrInvalidTopic)
 	}
 	switch messageType {
-	case "telemetry", "alerts":
+	case "telemetry", "alerts", "command_ack":
 		return deviceID, messageType, nil
 	default:
 		return "", "", fmt.Errorf("%w: unsupported message type", ErrInvalidTopic)
@@ -161,4 +170,27 @@ func ValidatePayload(deviceType string, payload map[string]interface{}) error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// ack payload: {"request_id": "cmd-1", "status": "SUCCEEDED", "result": {...}, "error": "..."}
+func ValidateCommandAck(payload map[string]interface{}) error {
+	requestID, ok := payload["request_id"].(string)
+	if !ok || requestID == "" {
+		return fmt.Errorf("%w: ack missing or invalid request_id", ErrInvalidPayload)
+	}
+	status, ok := payload["status"].(string)
+	if !ok || !commands.IsAckStatus(status) {
+		return fmt.Errorf("%w: ack status must be DELIVERED, SUCCEEDED or FAILED", ErrInvalidPayload)
+	}
+	if result, exists := payload["result"]; exists {
+		if _, ok := result.(map[string]interface{}); !ok {
+			return fmt.Errorf("%w: ack result must be an object", ErrInvalidPayload)
+		}
+	}
+	if errMsg, exists := payload["error"]; exists {
+		if _, ok := errMsg.(string); !ok {
+			return fmt.Errorf("%w: ack error must be a string", ErrInvalidPayload)
+		}
+	}
+	return nil
+}
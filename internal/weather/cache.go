@@ -0,0 +1,62 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+// CachedProvider keeps every report for TTL, per location rounded to ~1 km
+// so the homes of a neighbourhood share one call
+type CachedProvider struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	report    *models.Weather
+	fetchedAt time.Time
+}
+
+func NewCachedProvider(provider Provider, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		Provider: provider,
+		TTL:      ttl,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// a failed refresh falls back to the last report for up to twice the TTL,
+// outdoor weather doesn't change fast enough to show nothing instead
+func (cache *CachedProvider) Current(ctx context.Context, location models.Location) (*models.Weather, error) {
+	key := fmt.Sprintf("%.2f,%.2f", location.Latitude, location.Longitude)
+
+	cache.mu.Lock()
+	entry, exists := cache.entries[key]
+	cache.mu.Unlock()
+
+	age := time.Since(entry.fetchedAt)
+	if exists && age < cache.TTL {
+		return entry.report, nil
+	}
+
+	// fetched without the lock, a slow provider must not hold up the other locations
+	report, err := cache.Provider.Current(ctx, location)
+	if err != nil {
+		if exists && age < 2*cache.TTL {
+			return entry.report, nil
+		}
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	cache.entries[key] = cacheEntry{report: report, fetchedAt: time.Now()}
+	cache.mu.Unlock()
+	return report, nil
+}
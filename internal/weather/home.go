@@ -0,0 +1,27 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Fleexa-Graduation-Project/Backend/internal/homes"
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+var ErrNoLocation = errors.New("home has no location")
+
+// AtHome returns the outdoor weather at the home's location, ErrNoLocation when it has none
+func AtHome(ctx context.Context, provider Provider, store homes.HomeStore, homeID string) (*models.Weather, error) {
+	home, err := store.GetHome(ctx, homeID)
+	if err != nil {
+		return nil, err
+	}
+	if home == nil {
+		return nil, fmt.Errorf("home %s not found", homeID)
+	}
+	if home.Location == nil {
+		return nil, ErrNoLocation
+	}
+	return provider.Current(ctx, *home.Location)
+}
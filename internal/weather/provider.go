@@ -0,0 +1,123 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Fleexa-Graduation-Project/Backend/models"
+)
+
+const (
+	DefaultBaseURL  = "https://api.open-meteo.com"
+	DefaultCacheTTL = 15 * time.Minute
+
+	forecastHours = 12
+)
+
+// Provider reads the outdoor weather at a location
+type Provider interface {
+	Current(ctx context.Context, location models.Location) (*models.Weather, error)
+}
+
+// HTTPProvider speaks the open-meteo forecast api, any server answering the same
+// /v1/forecast query works (cmd/weather-stub locally)
+type HTTPProvider struct {
+	Client  *http.Client
+	BaseURL string
+}
+
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		Client:  &http.Client{Timeout: 5 * time.Second},
+		BaseURL: baseURL,
+	}
+}
+
+// NewProviderFromEnv reads WEATHER_API_URL (open-meteo when unset) and WEATHER_CACHE_TTL
+// (a duration, 15m when unset), the provider is cached
+func NewProviderFromEnv() (*CachedProvider, error) {
+	baseURL := os.Getenv("WEATHER_API_URL")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	ttl := DefaultCacheTTL
+	if raw := os.Getenv("WEATHER_CACHE_TTL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("WEATHER_CACHE_TTL must be a positive duration, got %q", raw)
+		}
+		ttl = parsed
+	}
+
+	return NewCachedProvider(NewHTTPProvider(baseURL), ttl), nil
+}
+
+// the part of the open-meteo response we read, times are unix seconds (timeformat=unixtime)
+type forecastResponse struct {
+	Current struct {
+		Time        int64   `json:"time"`
+		Temperature float64 `json:"temperature_2m"`
+		Humidity    float64 `json:"relative_humidity_2m"`
+	} `json:"current"`
+	Hourly struct {
+		Time        []int64   `json:"time"`
+		Temperature []float64 `json:"temperature_2m"`
+		Humidity    []float64 `json:"relative_humidity_2m"`
+	} `json:"hourly"`
+}
+
+func (provider *HTTPProvider) Current(ctx context.Context, location models.Location) (*models.Weather, error) {
+	query := url.Values{}
+	query.Set("latitude", strconv.FormatFloat(location.Latitude, 'f', -1, 64))
+	query.Set("longitude", strconv.FormatFloat(location.Longitude, 'f', -1, 64))
+	query.Set("current", "temperature_2m,relative_humidity_2m")
+	query.Set("hourly", "temperature_2m,relative_humidity_2m")
+	query.Set("forecast_hours", strconv.Itoa(forecastHours))
+	query.Set("timeformat", "unixtime")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.BaseURL+"/v1/forecast?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build weather request: %w", err)
+	}
+
+	res, err := provider.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch weather: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("weather endpoint returned status %d", res.StatusCode)
+	}
+
+	var body forecastResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse weather: %w", err)
+	}
+
+	report := &models.Weather{
+		Temperature: body.Current.Temperature,
+		Humidity:    body.Current.Humidity,
+		ObservedAt:  body.Current.Time,
+		Forecast:    make([]models.WeatherForecast, 0, len(body.Hourly.Time)),
+	}
+	for i, timestamp := range body.Hourly.Time {
+		if i >= len(body.Hourly.Temperature) {
+			break
+		}
+		point := models.WeatherForecast{Timestamp: timestamp, Temperature: body.Hourly.Temperature[i]}
+		if i < len(body.Hourly.Humidity) {
+			point.Humidity = body.Hourly.Humidity[i]
+		}
+		report.Forecast = append(report.Forecast, point)
+	}
+	return report, nil
+}
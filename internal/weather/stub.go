@@ -0,0 +1,36 @@
+package weather
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+)
+
+// StubHandler answers /v1/forecast like open-meteo with made up weather for any location:
+// temperature around the given value, a little warmer every hour of the forecast.
+// run it with cmd/weather-stub, or behind httptest.NewServer
+func StubHandler(temperature float64, humidity float64) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/forecast", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("latitude") == "" || r.URL.Query().Get("longitude") == "" {
+			http.Error(w, `{"error":true,"reason":"latitude and longitude are required"}`, http.StatusBadRequest)
+			return
+		}
+
+		var body forecastResponse
+		now := time.Now().Truncate(time.Hour).Unix()
+		body.Current.Time = time.Now().Unix()
+		body.Current.Temperature = temperature
+		body.Current.Humidity = humidity
+		for hour := range int64(forecastHours) {
+			body.Hourly.Time = append(body.Hourly.Time, now+hour*3600)
+			body.Hourly.Temperature = append(body.Hourly.Temperature, math.Round((temperature+float64(hour)*0.5)*10)/10)
+			body.Hourly.Humidity = append(body.Hourly.Humidity, humidity)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+	return mux
+}
@@ -1,10 +1,15 @@
 package models
 
 type Alert struct {
-	DeviceID  string                 `json:"device_id" dynamodbav:"device_id"`
-	Timestamp int64                  `json:"timestamp" dynamodbav:"timestamp"`
-	Type      string                 `json:"type" dynamodbav:"type"`
-	Severity  string                 `json:"severity" dynamodbav:"severity"`
-	Payload   map[string]interface{} `json:"payload" dynamodbav:"payload"`
-	ExpiresAt int64                  `json:"expires_at" dynamodbav:"expires_at"`
+	DeviceID       string                 `json:"device_id" dynamodbav:"device_id"`
+	Timestamp      int64                  `json:"timestamp" dynamodbav:"timestamp"`
+	Type           string                 `json:"type" dynamodbav:"type"`
+	Severity       string                 `json:"severity" dynamodbav:"severity"`
+	Payload        map[string]interface{} `json:"payload" dynamodbav:"payload"`
+	Status         string                 `json:"status" dynamodbav:"status"` // OPEN - ACKNOWLEDGED - RESOLVED
+	AcknowledgedBy string                 `json:"acknowledged_by,omitempty" dynamodbav:"acknowledged_by,omitempty"`
+	AcknowledgedAt int64                  `json:"acknowledged_at,omitempty" dynamodbav:"acknowledged_at,omitempty"`
+	ResolvedBy     string                 `json:"resolved_by,omitempty" dynamodbav:"resolved_by,omitempty"` // "auto" when the device recovered by itself
+	ResolvedAt     int64                  `json:"resolved_at,omitempty" dynamodbav:"resolved_at,omitempty"`
+	ExpiresAt      int64                  `json:"expires_at" dynamodbav:"expires_at"`
 }
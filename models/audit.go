@@ -0,0 +1,17 @@
+package models
+
+// AuditEvent is one entry of the append-only audit log, it is never updated or deleted by the app
+type AuditEvent struct {
+	EventID   string      `json:"event_id" dynamodbav:"event_id"`
+	Timestamp int64       `json:"timestamp" dynamodbav:"timestamp"`
+	Action    string      `json:"action" dynamodbav:"action"`                       // COMMAND_DISPATCHED - ALERT_ACKNOWLEDGED - LOGIN...
+	ActorID   string      `json:"actor_id" dynamodbav:"actor_id"`                   // user id, or schedule:{id} / automation:{id}
+	HomeID    string      `json:"home_id,omitempty" dynamodbav:"home_id,omitempty"` // home of the device at the time
+	DeviceID  string      `json:"device_id,omitempty" dynamodbav:"device_id,omitempty"`
+	TargetID  string      `json:"target_id,omitempty" dynamodbav:"target_id,omitempty"`   // command request_id, rule_id, schedule_id...
+	RequestID string      `json:"request_id,omitempty" dynamodbav:"request_id,omitempty"` // X-Request-ID of the api call
+	Before    interface{} `json:"before,omitempty" dynamodbav:"before,omitempty"`
+	After     interface{} `json:"after,omitempty" dynamodbav:"after,omitempty"`
+	Reason    string      `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	ExpiresAt int64       `json:"expires_at" dynamodbav:"expires_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// AutomationRule sends a command to one device when another device stays in a given operational state
+type AutomationRule struct {
+	RuleID          string             `json:"rule_id" dynamodbav:"rule_id"`
+	Name            string             `json:"name" dynamodbav:"name"`
+	Enabled         bool               `json:"enabled" dynamodbav:"enabled"`
+	Trigger         AutomationTrigger  `json:"trigger" dynamodbav:"trigger"`
+	Action          AutomationAction   `json:"action" dynamodbav:"action"`
+	Weather         *AutomationWeather `json:"weather,omitempty" dynamodbav:"weather,omitempty"`       // optional, checked when the trigger state holds
+	CooldownSeconds int64              `json:"cooldown_seconds" dynamodbav:"cooldown_seconds"`         // min gap between two firings
+	HomeID          string             `json:"home_id" dynamodbav:"home_id"`                           // home of the action device when the rule was saved
+	CreatedBy       string             `json:"created_by" dynamodbav:"created_by"`                     // member whose permission the commands are sent with
+	TriggerDeviceID string             `json:"-" dynamodbav:"trigger_device_id"`                       // copy of Trigger.DeviceID for the TriggerDeviceIndex
+	ConditionSince  int64              `json:"condition_since,omitempty" dynamodbav:"condition_since"` // when the trigger state was first seen, 0 = not matching
+	LastFiredAt     int64              `json:"last_fired_at,omitempty" dynamodbav:"last_fired_at"`
+	CreatedAt       int64              `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt       int64              `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+type AutomationTrigger struct {
+	DeviceID         string `json:"device_id" dynamodbav:"device_id"`
+	OperationalState string `json:"operational_state" dynamodbav:"operational_state"` // HOT - DANGER - OPEN etc.
+	ForSeconds       int64  `json:"for_seconds" dynamodbav:"for_seconds"`             // how long the state must hold (debounce)
+}
+
+// AutomationWeather adds an outdoor condition, "cool the bedroom when it's HOT inside and above 30 outside".
+// the weather is the one at the trigger device's home, bounds left out are not checked
+type AutomationWeather struct {
+	OutsideTempAbove *float64 `json:"outside_temp_above,omitempty" dynamodbav:"outside_temp_above,omitempty"`
+	OutsideTempBelow *float64 `json:"outside_temp_below,omitempty" dynamodbav:"outside_temp_below,omitempty"`
+	HumidityAbove    *float64 `json:"humidity_above,omitempty" dynamodbav:"humidity_above,omitempty"`
+	HumidityBelow    *float64 `json:"humidity_below,omitempty" dynamodbav:"humidity_below,omitempty"`
+}
+
+type AutomationAction struct {
+	DeviceID   string                 `json:"device_id" dynamodbav:"device_id"`
+	Action     string                 `json:"action" dynamodbav:"action"`
+	Parameters map[string]interface{} `json:"parameters" dynamodbav:"parameters"`
+}
+
+// AutomationFiring is the audit trail entry written every time a rule fires
+type AutomationFiring struct {
+	RuleID          string   `json:"rule_id" dynamodbav:"rule_id"`
+	Timestamp       int64    `json:"timestamp" dynamodbav:"timestamp"`
+	TriggerDeviceID string   `json:"trigger_device_id" dynamodbav:"trigger_device_id"`
+	TriggerState    string   `json:"trigger_state" dynamodbav:"trigger_state"`
+	OutsideTemp     *float64 `json:"outside_temp,omitempty" dynamodbav:"outside_temp,omitempty"` // rules with a weather condition only
+	TargetDeviceID  string   `json:"target_device_id" dynamodbav:"target_device_id"`
+	Action          string   `json:"action" dynamodbav:"action"`
+	RequestID       string   `json:"request_id,omitempty" dynamodbav:"request_id,omitempty"`
+	Status          string   `json:"status" dynamodbav:"status"` // DISPATCHED - FAILED
+	Error           string   `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	ExpiresAt       int64    `json:"expires_at" dynamodbav:"expires_at"`
+}
@@ -1,10 +1,16 @@
 package models
 
 type Command struct {
-	RequestID string                 `json:"request_id" dynamodbav:"request_id"`
-	DeviceID  string                 `json:"device_id" dynamodbav:"device_id"`
-	Timestamp int64                  `json:"timestamp" dynamodbav:"timestamp"`
-	Action    string                 `json:"action" dynamodbav:"action"`
-	Parameters map[string]interface{} `json:"parameters" dynamodbav:"parameters"`
-	ExpiresAt int64                  `json:"expires_at" dynamodbav:"expires_at"`
+	RequestID   string                 `json:"request_id" dynamodbav:"request_id"`
+	DeviceID    string                 `json:"device_id" dynamodbav:"device_id"`
+	Timestamp   int64                  `json:"timestamp" dynamodbav:"timestamp"`
+	Action      string                 `json:"action" dynamodbav:"action"`
+	Parameters  map[string]interface{} `json:"parameters" dynamodbav:"parameters"`
+	IssuedBy    string                 `json:"issued_by,omitempty" dynamodbav:"issued_by,omitempty"` // who sent it (app user, schedule, automation)
+	Status      string                 `json:"status" dynamodbav:"status"`                           // PENDING - DELIVERED - SUCCEEDED - FAILED - TIMED_OUT
+	DeliveredAt int64                  `json:"delivered_at,omitempty" dynamodbav:"delivered_at,omitempty"`
+	CompletedAt int64                  `json:"completed_at,omitempty" dynamodbav:"completed_at,omitempty"`
+	Result      map[string]interface{} `json:"result,omitempty" dynamodbav:"result,omitempty"` // reported back by the device in the ack
+	Error       string                 `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	ExpiresAt   int64                  `json:"expires_at" dynamodbav:"expires_at"`
 }
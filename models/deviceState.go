@@ -3,10 +3,20 @@ package models
 type DeviceState struct {
 	DeviceID         string                 `json:"device_id" dynamodbav:"device_id"`
 	Type             string                 `json:"type" dynamodbav:"type"`
-	Status           string                 `json:"status" dynamodbav:"status"` // online - offline 
+	Status           string                 `json:"status" dynamodbav:"status"` // online - offline - provisioned (never seen) - decommissioned
 	OperationalState string                 `json:"operational_state" dynamodbav:"operational_state"` // based on device: LOCKED-HOT-BRIGHT-OFF etc.
 	Health           string                 `json:"health" dynamodbav:"health"`
 	Payload          map[string]interface{} `json:"payload" dynamodbav:"payload"` // Raw sensor data (temp, gas_level)
 	LastSeenAt       int64                  `json:"last_seen_at" dynamodbav:"last_seen_at"`
+	OfflineSince     int64                  `json:"offline_since,omitempty" dynamodbav:"offline_since"` // set by the offline sweeper, 0 = not reported offline
 	LastUpdated      int64                  `json:"-" dynamodbav:"updated_at"` 
+
+	// registry metadata, set from the api when the device is provisioned
+	Name             string `json:"name,omitempty" dynamodbav:"name,omitempty"`
+	RoomID           string `json:"room_id,omitempty" dynamodbav:"room_id,omitempty"` // a room of the device's home
+	Links            map[string]string `json:"links,omitempty" dynamodbav:"links,omitempty"` // relation -> device id, e.g. temp_sensor of an AC
+	Firmware         string `json:"firmware,omitempty" dynamodbav:"firmware,omitempty"`
+	InstalledAt      int64  `json:"installed_at,omitempty" dynamodbav:"installed_at,omitempty"`
+	RegisteredAt     int64  `json:"registered_at,omitempty" dynamodbav:"registered_at,omitempty"` // 0 = created by telemetry before the registry existed
+	DecommissionedAt int64  `json:"decommissioned_at,omitempty" dynamodbav:"decommissioned_at,omitempty"`
 }
\ No newline at end of file
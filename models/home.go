@@ -0,0 +1,44 @@
+package models
+
+// Home groups the devices of one household, its members can see and control them
+type Home struct {
+	HomeID    string    `json:"home_id" dynamodbav:"home_id"`
+	Name      string    `json:"name" dynamodbav:"name"`
+	OwnerID   string    `json:"owner_id" dynamodbav:"owner_id"`
+	Location  *Location `json:"location,omitempty" dynamodbav:"location,omitempty"`   // where the outdoor weather is read
+	TimeZone  string    `json:"time_zone,omitempty" dynamodbav:"time_zone,omitempty"` // IANA, charts and times are shown in it, UTC when empty
+	CreatedAt int64     `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt int64     `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude" dynamodbav:"latitude"`
+	Longitude float64 `json:"longitude" dynamodbav:"longitude"`
+}
+
+// HomeMember links a user to a home (user_id + home_id), the owner is a member too
+type HomeMember struct {
+	UserID      string   `json:"user_id" dynamodbav:"user_id"`
+	HomeID      string   `json:"home_id" dynamodbav:"home_id"`
+	Role        string   `json:"role" dynamodbav:"role"`                                 // OWNER - MEMBER - GUEST
+	Permissions []string `json:"permissions" dynamodbav:"permissions"`                   // null = role defaults, [] = view only
+	ExpiresAt   int64    `json:"expires_at,omitempty" dynamodbav:"expires_at,omitempty"` // guests only, access ends here
+	AddedAt     int64    `json:"added_at" dynamodbav:"added_at"`
+	UpdatedAt   int64    `json:"updated_at,omitempty" dynamodbav:"updated_at,omitempty"`
+}
+
+// HomeDevice places a device in a home (device_id), a device belongs to one home at a time
+type HomeDevice struct {
+	DeviceID string `json:"device_id" dynamodbav:"device_id"`
+	HomeID   string `json:"home_id" dynamodbav:"home_id"`
+	AddedAt  int64  `json:"added_at" dynamodbav:"added_at"`
+}
+
+// Room groups devices of a home by place (room_id), a device is in one room at most
+type Room struct {
+	RoomID    string `json:"room_id" dynamodbav:"room_id"`
+	HomeID    string `json:"home_id" dynamodbav:"home_id"`
+	Name      string `json:"name" dynamodbav:"name"`
+	CreatedAt int64  `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt int64  `json:"updated_at" dynamodbav:"updated_at"`
+}
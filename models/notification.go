@@ -0,0 +1,27 @@
+package models
+
+// PushToken is one phone (or webhook channel) of a user that receives alert notifications
+type PushToken struct {
+	UserID    string `json:"user_id" dynamodbav:"user_id"`
+	Token     string `json:"token" dynamodbav:"token"`
+	Provider  string `json:"provider" dynamodbav:"provider"`                     // fcm - webhook
+	Platform  string `json:"platform,omitempty" dynamodbav:"platform,omitempty"` // android - ios
+	CreatedAt int64  `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt int64  `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+// NotificationPreferences decides which alerts reach a user, and when
+type NotificationPreferences struct {
+	UserID      string      `json:"user_id" dynamodbav:"user_id"`
+	MinSeverity string      `json:"min_severity" dynamodbav:"min_severity"` // LOW - MEDIUM - CRITICAL
+	QuietHours  *QuietHours `json:"quiet_hours,omitempty" dynamodbav:"quiet_hours,omitempty"`
+	UpdatedAt   int64       `json:"updated_at" dynamodbav:"updated_at"`
+}
+
+// QuietHours silences notifications between Start and End (wall clock in TimeZone, may wrap midnight)
+type QuietHours struct {
+	Start        string `json:"start" dynamodbav:"start"` // "22:00"
+	End          string `json:"end" dynamodbav:"end"`     // "07:00"
+	TimeZone     string `json:"time_zone" dynamodbav:"time_zone"`
+	MuteCritical bool   `json:"mute_critical" dynamodbav:"mute_critical"` // CRITICAL alerts still go through unless set
+}
@@ -0,0 +1,14 @@
+package models
+
+// QuarantinedDevice sums up the messages ingestion refused from one device id
+type QuarantinedDevice struct {
+	DeviceID    string                 `json:"device_id" dynamodbav:"device_id"`
+	Reason      string                 `json:"reason" dynamodbav:"reason"` // UNREGISTERED, DECOMMISSIONED or TYPE_MISMATCH
+	MessageType string                 `json:"message_type" dynamodbav:"message_type"`
+	Type        string                 `json:"type" dynamodbav:"type"`
+	LastPayload map[string]interface{} `json:"last_payload" dynamodbav:"last_payload"`
+	Count       int64                  `json:"count" dynamodbav:"count"`
+	FirstSeenAt int64                  `json:"first_seen_at" dynamodbav:"first_seen_at"`
+	LastSeenAt  int64                  `json:"last_seen_at" dynamodbav:"last_seen_at"`
+	ExpiresAt   int64                  `json:"-" dynamodbav:"expires_at"`
+}
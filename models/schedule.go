@@ -0,0 +1,21 @@
+package models
+
+type Schedule struct {
+	ScheduleID    string                 `json:"schedule_id" dynamodbav:"schedule_id"`
+	DeviceID      string                 `json:"device_id" dynamodbav:"device_id"`
+	HomeID        string                 `json:"home_id" dynamodbav:"home_id"`       // home the device was in when the schedule was saved
+	CreatedBy     string                 `json:"created_by" dynamodbav:"created_by"` // member whose permission the commands are sent with
+	Action        string                 `json:"action" dynamodbav:"action"`
+	Parameters    map[string]interface{} `json:"parameters" dynamodbav:"parameters"`
+	RunAt         int64                  `json:"run_at,omitempty" dynamodbav:"run_at,omitempty"` // one-shot schedules
+	Cron          string                 `json:"cron,omitempty" dynamodbav:"cron,omitempty"`     // recurring: "min hour day month weekday"
+	TimeZone      string                 `json:"time_zone" dynamodbav:"time_zone"`               // IANA zone the cron is read in
+	CatchUp       string                 `json:"catch_up" dynamodbav:"catch_up"`                 // SKIP - RUN_ONCE
+	Enabled       bool                   `json:"enabled" dynamodbav:"enabled"`
+	NextRunAt     int64                  `json:"next_run_at" dynamodbav:"next_run_at"`
+	LastRunAt     int64                  `json:"last_run_at,omitempty" dynamodbav:"last_run_at,omitempty"`
+	LastRequestID string                 `json:"last_request_id,omitempty" dynamodbav:"last_request_id,omitempty"`
+	LastError     string                 `json:"last_error,omitempty" dynamodbav:"last_error,omitempty"`
+	CreatedAt     int64                  `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt     int64                  `json:"updated_at" dynamodbav:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Threshold raises an alert when a telemetry metric of a device crosses a limit
+type Threshold struct {
+	DeviceID        string  `json:"device_id" dynamodbav:"device_id"`
+	ThresholdID     string  `json:"threshold_id" dynamodbav:"threshold_id"`
+	Metric          string  `json:"metric" dynamodbav:"metric"`                     // payload key: temp - gas_level - lock_state
+	Operator        string  `json:"operator" dynamodbav:"operator"`                 // ABOVE - BELOW - EQUALS
+	Value           float64 `json:"value,omitempty" dynamodbav:"value"`             // limit for ABOVE / BELOW
+	State           string  `json:"state,omitempty" dynamodbav:"state"`             // expected reading for EQUALS (UNLOCKED, true...)
+	Hysteresis      float64 `json:"hysteresis,omitempty" dynamodbav:"hysteresis"`   // margin the value must go back by before the breach ends
+	ForSeconds      int64   `json:"for_seconds" dynamodbav:"for_seconds"`           // how long the breach must last before alerting
+	Severity        string  `json:"severity" dynamodbav:"severity"`                 // LOW - MEDIUM - CRITICAL
+	CooldownSeconds int64   `json:"cooldown_seconds" dynamodbav:"cooldown_seconds"` // min gap between two alerts of this threshold
+	Enabled         bool    `json:"enabled" dynamodbav:"enabled"`
+	BreachSince     int64   `json:"breach_since,omitempty" dynamodbav:"breach_since"` // first breaching reading, 0 = not breaching
+	LastAlertAt     int64   `json:"last_alert_at,omitempty" dynamodbav:"last_alert_at"`
+	CreatedAt       int64   `json:"created_at" dynamodbav:"created_at"`
+	UpdatedAt       int64   `json:"updated_at" dynamodbav:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Weather is the outdoor weather at a home, as the weather provider reported it
+type Weather struct {
+	Temperature float64           `json:"temperature"` // °C
+	Humidity    float64           `json:"humidity"`    // relative, %
+	Forecast    []WeatherForecast `json:"forecast"`    // hourly, oldest first
+	ObservedAt  int64             `json:"observed_at"`
+}
+
+type WeatherForecast struct {
+	Timestamp   int64   `json:"timestamp"`
+	Temperature float64 `json:"temperature"`
+	Humidity    float64 `json:"humidity"`
+}
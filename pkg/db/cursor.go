@@ -0,0 +1,96 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorKey is the LastEvaluatedKey shape of one query: the partition it reads and the other
+// key attributes. a cursor of another device, home or table doesn't match it
+type CursorKey struct {
+	Partition string   // partition attribute of the table or index, a string
+	Value     string   // the partition the query reads
+	Strings   []string // other string key attributes, the table key of an index query
+	Numbers   []string // number key attributes, the sort key
+}
+
+// turns a LastEvaluatedKey into an opaque token the app can send back
+func EncodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	var plain map[string]interface{}
+	if err := attributevalue.UnmarshalMap(key, &plain); err != nil {
+		return "", fmt.Errorf("failed to unmarshal cursor key: %w", err)
+	}
+
+	data, err := json.Marshal(plain)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// reverse of EncodeCursor, an empty token means "start from the beginning". the key must have
+// exactly the attributes of expected and its partition, anything else is ErrInvalidCursor
+func DecodeCursor(token string, expected CursorKey) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad encoding", ErrInvalidCursor)
+	}
+
+	var plain map[string]interface{}
+	if err := json.Unmarshal(data, &plain); err != nil || len(plain) == 0 {
+		return nil, fmt.Errorf("%w: bad payload", ErrInvalidCursor)
+	}
+
+	key, err := attributevalue.MarshalMap(plain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := expected.check(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (expected CursorKey) check(key map[string]types.AttributeValue) error {
+	if len(key) != 1+len(expected.Strings)+len(expected.Numbers) {
+		return fmt.Errorf("%w: unexpected key attributes", ErrInvalidCursor)
+	}
+
+	partition, ok := key[expected.Partition].(*types.AttributeValueMemberS)
+	if !ok || partition.Value != expected.Value {
+		return fmt.Errorf("%w: cursor of another query", ErrInvalidCursor)
+	}
+	for _, name := range expected.Strings {
+		if _, ok := key[name].(*types.AttributeValueMemberS); !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidCursor, name)
+		}
+	}
+	for _, name := range expected.Numbers {
+		number, ok := key[name].(*types.AttributeValueMemberN)
+		if !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidCursor, name)
+		}
+		if _, err := strconv.ParseInt(number.Value, 10, 64); err != nil {
+			return fmt.Errorf("%w: bad %s", ErrInvalidCursor, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var readingsKey = CursorKey{Partition: "device_id", Value: "dev-1", Numbers: []string{"timestamp"}}
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"device_id": &types.AttributeValueMemberS{Value: "dev-1"},
+		"timestamp": &types.AttributeValueMemberN{Value: "1767225600"},
+	}
+
+	token, err := EncodeCursor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" {
+		t.Fatal("EncodeCursor returned an empty token for a key")
+	}
+
+	decoded, err := DecodeCursor(token, readingsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceID, ok := decoded["device_id"].(*types.AttributeValueMemberS)
+	if !ok || deviceID.Value != "dev-1" {
+		t.Errorf("device_id = %#v, want S dev-1", decoded["device_id"])
+	}
+	timestamp, ok := decoded["timestamp"].(*types.AttributeValueMemberN)
+	if !ok || timestamp.Value != "1767225600" {
+		t.Errorf("timestamp = %#v, want N 1767225600", decoded["timestamp"])
+	}
+	if len(decoded) != len(key) {
+		t.Errorf("decoded %d attributes, want %d", len(decoded), len(key))
+	}
+}
+
+// no LastEvaluatedKey is the last page, no cursor starts from the beginning
+func TestCursorEmpty(t *testing.T) {
+	token, err := EncodeCursor(nil)
+	if err != nil || token != "" {
+		t.Errorf("EncodeCursor(nil) = %q, %v, want an empty token", token, err)
+	}
+	key, err := DecodeCursor("", readingsKey)
+	if err != nil || key != nil {
+		t.Errorf("DecodeCursor(\"\") = %v, %v, want no key", key, err)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tokens := []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`["device_id"]`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"device_id":"dev-2","timestamp":1767225600}`)),                // another device
+		base64.RawURLEncoding.EncodeToString([]byte(`{"device_id":"dev-1"}`)),                                       // no sort key
+		base64.RawURLEncoding.EncodeToString([]byte(`{"device_id":"dev-1","timestamp":"1767225600"}`)),              // sort key as a string
+		base64.RawURLEncoding.EncodeToString([]byte(`{"device_id":"dev-1","timestamp":1.5}`)),                       // not a unix time
+		base64.RawURLEncoding.EncodeToString([]byte(`{"device_id":"dev-1","timestamp":1767225600,"event_id":"x"}`)), // another table
+	}
+	for _, token := range tokens {
+		if _, err := DecodeCursor(token, readingsKey); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
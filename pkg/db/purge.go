@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	batchWriteLimit   = 25 // DynamoDB BatchWriteItem hard limit
+	purgeRetries      = 3
+	purgeRetryBackoff = 100 * time.Millisecond
+)
+
+// DeletePartition removes every item under one partition key (all readings of a device...),
+// it pages through the keys only and deletes them 25 at a time. returns how many were deleted
+func DeletePartition(ctx context.Context, client *dynamodb.Client, tableName string, partitionKey string, sortKey string, value string) (int, error) {
+	return deleteQueried(ctx, client, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("#pk = :value"),
+		ProjectionExpression:   aws.String("#pk, #sk"),
+		ExpressionAttributeNames: map[string]string{
+			"#pk": partitionKey,
+			"#sk": sortKey,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":value": &types.AttributeValueMemberS{Value: value},
+		},
+	})
+}
+
+// DeleteIndexed removes every item a GSI lists under one key (all commands of a device...) for a
+// table keyed by tableKey alone, the index must project it
+func DeleteIndexed(ctx context.Context, client *dynamodb.Client, tableName string, indexName string, indexKey string, tableKey string, value string) (int, error) {
+	return deleteQueried(ctx, client, &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		IndexName:              aws.String(indexName),
+		KeyConditionExpression: aws.String("#ik = :value"),
+		ProjectionExpression:   aws.String("#pk"),
+		ExpressionAttributeNames: map[string]string{
+			"#ik": indexKey,
+			"#pk": tableKey,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":value": &types.AttributeValueMemberS{Value: value},
+		},
+	})
+}
+
+// pages through the query, whose projection must be the table key, and deletes what it returns
+func deleteQueried(ctx context.Context, client *dynamodb.Client, input *dynamodb.QueryInput) (int, error) {
+	tableName := aws.ToString(input.TableName)
+	deleted := 0
+
+	for {
+		res, err := client.Query(ctx, input)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to query %s keys: %w", tableName, err)
+		}
+
+		for i := 0; i < len(res.Items); i += batchWriteLimit {
+			end := min(i+batchWriteLimit, len(res.Items))
+
+			requests := make([]types.WriteRequest, 0, end-i)
+			for _, key := range res.Items[i:end] {
+				requests = append(requests, types.WriteRequest{
+					DeleteRequest: &types.DeleteRequest{Key: key},
+				})
+			}
+
+			if err := deleteBatch(ctx, client, tableName, requests); err != nil {
+				return deleted, err
+			}
+			deleted += len(requests)
+		}
+
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+		if input.ExclusiveStartKey == nil {
+			return deleted, nil
+		}
+	}
+}
+
+// retries the unprocessed deletes with a growing backoff
+func deleteBatch(ctx context.Context, client *dynamodb.Client, tableName string, requests []types.WriteRequest) error {
+	pending := requests
+
+	for attempt := 0; attempt <= purgeRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<uint(attempt-1)) * purgeRetryBackoff):
+			}
+		}
+
+		output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{tableName: pending},
+		})
+		if err != nil {
+			return fmt.Errorf("batch delete attempt %d failed: %w", attempt+1, err)
+		}
+
+		pending = output.UnprocessedItems[tableName]
+		if len(pending) == 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("batch delete: %d items still unprocessed after %d retries", len(pending), purgeRetries)
+}